@@ -0,0 +1,91 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/matrixorigin/matrixone/pkg/common/moerr"
+)
+
+// parseAccounts splits the comma separated names of -accounts
+func parseAccounts(ctx context.Context, spec string) ([]string, error) {
+	var accounts []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || strings.ContainsAny(name, "#:") {
+			return nil, moerr.NewInvalidInput(ctx, "accounts must be comma separated account names, got %s", spec)
+		}
+		if seen[name] {
+			return nil, moerr.NewInvalidInput(ctx, "account %s is given twice", name)
+		}
+		seen[name] = true
+		accounts = append(accounts, name)
+	}
+	return accounts, nil
+}
+
+// accountUser returns the login name of the user in the account. MatrixOne
+// takes the account from the login name, account#user, there is no way to
+// switch the account of a session.
+func accountUser(account, user string) string {
+	return account + "#" + user
+}
+
+// dumpAccounts dumps the databases of each account of -accounts through a
+// login of the same user and password to that account. The output of an
+// account starts with a comment naming it, the restore has to log in to
+// the account for its part.
+func (opt *Options) dumpAccounts(ctx context.Context) error {
+	user := opt.username
+	requested := opt.dbs
+	defer func() {
+		opt.username = user
+		opt.dbs = requested
+	}()
+	for _, account := range opt.accounts {
+		if opt.truncated {
+			break
+		}
+		err := opt.endTableOutput()
+		if err != nil {
+			return err
+		}
+		opt.username = accountUser(account, user)
+		conn, err = opt.openDBConnection(ctx, "")
+		if err != nil {
+			return moerr.NewInternalError(ctx, "can not log in to account %s: %v", account, err)
+		}
+		opt.dbs = requested
+		if opt.expandsDatabases() {
+			opt.dbs, err = opt.expandDatabases(ctx, requested)
+		}
+		if err == nil {
+			fmt.Fprintf(opt.stdout(), "/* MODUMP ACCOUNT: %s */\n", account)
+			err = opt.dumpData(ctx)
+		}
+		if e := conn.Close(); err == nil {
+			err = e
+		}
+		conn = nil
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAccounts(t *testing.T) {
+	ctx := context.Background()
+	accounts, err := parseAccounts(ctx, "acc1, acc2")
+	require.NoError(t, err)
+	require.Equal(t, []string{"acc1", "acc2"}, accounts)
+
+	for _, spec := range []string{"", "acc1,", "acc1,acc1", "acc1#admin", "acc1:admin"} {
+		_, err = parseAccounts(ctx, spec)
+		require.Error(t, err, spec)
+	}
+	require.Equal(t, "acc1#dump", accountUser("acc1", "dump"))
+}
+
+func TestDumpAccounts(t *testing.T) {
+	ctx := context.Background()
+	opt := Options{
+		username:        "dump",
+		password:        "111",
+		host:            "h1",
+		port:            6001,
+		database:        "db1",
+		dbs:             []string{"db1"},
+		netBufferLength: defaultNetBufferLength,
+		format:          formatSQL,
+		consistency:     consistencyNone,
+		accounts:        []string{"acc1", "acc2"},
+	}
+	mocks := make(map[string]sqlmock.Sqlmock)
+	for i, account := range opt.accounts {
+		o := opt
+		o.username = accountUser(account, opt.username)
+		dsn, err := o.dsn(ctx, "h1", "")
+		require.NoError(t, err)
+		_, mock, err := sqlmock.NewWithDSN(dsn)
+		require.NoError(t, err)
+		mocks[account] = mock
+
+		mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables").
+			WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).AddRow("t1", "r"))
+		mock.ExpectQuery("show create table").
+			WillReturnRows(sqlmock.NewRows([]string{"Table", "Create Table"}).AddRow("t1", "CREATE TABLE `t1` (`a` INT)"))
+		mock.ExpectQuery("select \\* from `db1`.`t1`").
+			WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow(i + 1))
+		// the login of the account ends before the next one
+		mock.ExpectClose()
+	}
+	var opened []string
+	sqlOpen = func(_ string, dsn string) (*sql.DB, error) {
+		opened = append(opened, dsn)
+		return sql.Open("sqlmock", dsn)
+	}
+	defer func() { sqlOpen = sql.Open }()
+	conn = nil
+
+	var err error
+	out := captureStdout(t, func() {
+		err = opt.dumpAccounts(ctx)
+	})
+	require.NoError(t, err)
+	require.Len(t, opened, 2)
+	require.Contains(t, opened[0], "acc1#dump:111@")
+	require.Contains(t, opened[1], "acc2#dump:111@")
+	require.Equal(t, "dump", opt.username)
+	require.Nil(t, conn)
+	for _, mock := range mocks {
+		require.NoError(t, mock.ExpectationsWereMet())
+	}
+	require.Equal(t, "/* MODUMP ACCOUNT: acc1 */\n"+
+		"DROP TABLE IF EXISTS `t1`;\nCREATE TABLE `t1` (`a` INT);\nINSERT INTO `t1` VALUES (1);\n\n\n\n"+
+		"/* MODUMP ACCOUNT: acc2 */\n"+
+		"DROP TABLE IF EXISTS `t1`;\nCREATE TABLE `t1` (`a` INT);\nINSERT INTO `t1` VALUES (2);\n\n\n\n", out)
+}
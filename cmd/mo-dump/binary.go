@@ -0,0 +1,56 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/hex"
+	"strings"
+)
+
+// hexLiteral writes binary data as a hex literal, which keeps every byte
+// as it is
+func hexLiteral(v []byte) string {
+	return "x'" + hex.EncodeToString(v) + "'"
+}
+
+func hasBinaryColumn(cols []*Column) bool {
+	for _, col := range cols {
+		if isBinaryType(col.Type) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadColumns returns the column list of a LOAD DATA statement. Binary
+// columns are hex encoded in the csv file, they are read into a variable
+// and decoded by the SET clause.
+func loadColumns(cols []*Column) string {
+	names := make([]string, len(cols))
+	var sets []string
+	for i, col := range cols {
+		if !isBinaryType(col.Type) {
+			names[i] = quoteIdent(col.Name)
+			continue
+		}
+		names[i] = "@" + quoteIdent(col.Name)
+		sets = append(sets, quoteIdent(col.Name)+"=unhex(@"+quoteIdent(col.Name)+")")
+	}
+	list := "(" + strings.Join(names, ",") + ")"
+	if len(sets) > 0 {
+		list += " SET " + strings.Join(sets, ",")
+	}
+	return list
+}
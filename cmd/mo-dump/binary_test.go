@@ -0,0 +1,118 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/hex"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+// allBytes holds every byte value once
+func allBytes() []byte {
+	b := make([]byte, 256)
+	for i := range b {
+		b[i] = byte(i)
+	}
+	return b
+}
+
+func TestConvertValueBinary(t *testing.T) {
+	for _, typ := range []string{"binary", "VARBINARY", "blob"} {
+		s := convertValue(makeValue(string(allBytes())), typ)
+		require.True(t, strings.HasPrefix(s, "x'") && strings.HasSuffix(s, "'"), typ)
+		v, err := hex.DecodeString(s[2 : len(s)-1])
+		require.NoError(t, err)
+		require.Equal(t, allBytes(), v, typ)
+
+		require.Equal(t, "x''", convertValue(makeValue(""), typ))
+		var null sql.RawBytes
+		require.Equal(t, "NULL", convertValue(&null, typ))
+	}
+}
+
+func TestShowInsertBinary(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("select").WillReturnRows(sqlmock.NewRows([]string{"id", "b"}).
+		AddRow(1, allBytes()).
+		AddRow(2, []byte("it's\\")))
+	r, err := db.Query("select")
+	require.NoError(t, err)
+	defer r.Close()
+
+	cols := []*Column{{Name: "id", Type: "INT"}, {Name: "b", Type: "VARBINARY"}}
+	var id, b sql.RawBytes
+	bufPool := &sync.Pool{
+		New: func() any {
+			return &bytes.Buffer{}
+		},
+	}
+	out := captureStdout(t, func() {
+		err = showInsert(r, os.Stdout, []any{&id, &b}, cols, "t", bufPool, 1<<20, 0, 0, "", false, "", -1)
+		require.NoError(t, err)
+	})
+	require.Equal(t, "INSERT INTO `t` VALUES (1,x'"+hex.EncodeToString(allBytes())+"'),(2,x'"+hex.EncodeToString([]byte("it's\\"))+"');\n", out)
+}
+
+func TestToCsvBinary(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("select").WillReturnRows(sqlmock.NewRows([]string{"id", "b"}).
+		AddRow(1, allBytes()).
+		AddRow(2, []byte{}).
+		AddRow(3, nil))
+	r, err := db.Query("select")
+	require.NoError(t, err)
+	defer r.Close()
+
+	cols := []*Column{{Name: "id", Type: "INT"}, {Name: "b", Type: "BINARY"}}
+	var id, b sql.RawBytes
+	var out bytes.Buffer
+	err = toCsv(r, &out, "t", []any{&id, &b}, cols, &csvConfig{enable: true, fieldDelimiter: '\t'})
+	require.NoError(t, err)
+
+	cr := csv.NewReader(&out)
+	cr.Comma = '\t'
+	records, err := cr.ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	v, err := hex.DecodeString(records[0][1])
+	require.NoError(t, err)
+	require.Equal(t, allBytes(), v)
+	require.Equal(t, "", records[1][1])
+	require.Equal(t, "\\N", records[2][1])
+}
+
+func TestLoadDataStmtBinary(t *testing.T) {
+	cols := []*Column{{Name: "id", Type: "INT"}, {Name: "b", Type: "VARBINARY"}, {Name: "c", Type: "BLOB"}}
+	require.True(t, hasBinaryColumn(cols))
+	require.False(t, hasBinaryColumn(cols[:1]))
+	require.Equal(t,
+		"LOAD DATA INFILE '/tmp/db1_t1.csv' INTO TABLE `t1` FIELDS TERMINATED BY '\\t' ENCLOSED BY '\"' LINES TERMINATED BY '\\n'"+
+			" (`id`,@`b`,@`c`) SET `b`=unhex(@`b`),`c`=unhex(@`c`) PARALLEL 'FALSE';",
+		loadDataStmt("/tmp/db1_t1.csv", "t1", false, &csvConfig{enable: true, fieldDelimiter: '\t'}, cols))
+}
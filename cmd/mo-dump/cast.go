@@ -0,0 +1,70 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/matrixorigin/matrixone/pkg/common/moerr"
+)
+
+// castTypes are the column types accepted by -cast
+var castTypes = map[string]bool{
+	"bool": true, "boolean": true,
+	"tinyint": true, "smallint": true, "int": true, "bigint": true,
+	"unsigned tinyint": true, "unsigned smallint": true, "unsigned int": true, "unsigned bigint": true,
+	"float": true, "double": true, "decimal": true,
+	"char": true, "varchar": true, "text": true, "uuid": true, "json": true,
+	"date": true, "time": true, "datetime": true, "timestamp": true,
+	"binary": true, "varbinary": true, "blob": true,
+	"vecf32": true, "vecf64": true,
+}
+
+// parseCasts parses tbl.col:type;tbl.col:type into the type overrides of
+// each table, keyed by table and column name
+func parseCasts(ctx context.Context, spec string) (map[string]map[string]string, error) {
+	casts := make(map[string]map[string]string)
+	for _, item := range strings.Split(spec, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		target, typ, ok := strings.Cut(item, ":")
+		dot := strings.LastIndex(target, ".")
+		if !ok || dot <= 0 || dot == len(target)-1 {
+			return nil, moerr.NewInvalidInput(ctx, "cast must be in the format tbl.col:type, got %s", item)
+		}
+		typ = strings.ToLower(strings.TrimSpace(typ))
+		if !castTypes[typ] {
+			return nil, moerr.NewInvalidInput(ctx, "unsupported cast type %s", typ)
+		}
+		tbl, col := target[:dot], target[dot+1:]
+		if casts[tbl] == nil {
+			casts[tbl] = make(map[string]string)
+		}
+		casts[tbl][col] = typ
+	}
+	return casts, nil
+}
+
+// applyCasts replaces the driver reported type of the overridden columns
+func applyCasts(cols []*Column, casts map[string]string) {
+	for _, col := range cols {
+		if typ, ok := casts[col.Name]; ok {
+			col.Type = typ
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCasts(t *testing.T) {
+	ctx := context.Background()
+	casts, err := parseCasts(ctx, "t1.id:uuid; t1.flag:BOOL;t2.c:json;")
+	require.NoError(t, err)
+	require.Equal(t, map[string]map[string]string{
+		"t1": {"id": "uuid", "flag": "bool"},
+		"t2": {"c": "json"},
+	}, casts)
+
+	for _, spec := range []string{"t1:uuid", "t1.id", ".id:uuid", "t1.:uuid", "t1.id:uuuid"} {
+		_, err = parseCasts(ctx, spec)
+		require.Error(t, err, spec)
+	}
+}
+
+func TestGenOutputCast(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	bufPool := &sync.Pool{
+		New: func() any {
+			return &bytes.Buffer{}
+		},
+	}
+	newRows := func() *sqlmock.Rows {
+		// the driver reports an empty type for both columns
+		return sqlmock.NewRows([]string{"id", "flag"}).
+			AddRow("00123", "true")
+	}
+
+	opt := Options{netBufferLength: defaultNetBufferLength, format: formatSQL}
+	mock.ExpectQuery("select").WillReturnRows(newRows())
+	out := captureStdout(t, func() {
+		err = opt.genOutput(context.Background(), []string{"select * from `db1`.`t1`"}, "db1", "t1", bufPool)
+	})
+	require.NoError(t, err)
+	require.Equal(t, "INSERT INTO `t1` VALUES (00123,true);\n", out)
+
+	opt.casts = map[string]map[string]string{"t1": {"id": "varchar", "flag": "varchar"}}
+	mock.ExpectQuery("select").WillReturnRows(newRows())
+	out = captureStdout(t, func() {
+		err = opt.genOutput(context.Background(), []string{"select * from `db1`.`t1`"}, "db1", "t1", bufPool)
+	})
+	require.NoError(t, err)
+	require.Equal(t, "INSERT INTO `t1` VALUES ('00123','true');\n", out)
+
+	// overrides of other tables do not apply
+	mock.ExpectQuery("select").WillReturnRows(newRows())
+	out = captureStdout(t, func() {
+		err = opt.genOutput(context.Background(), []string{"select * from `db1`.`t2`"}, "db1", "t2", bufPool)
+	})
+	require.NoError(t, err)
+	require.Equal(t, "INSERT INTO `t2` VALUES (00123,true);\n", out)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,135 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/matrixorigin/matrixone/pkg/common/moerr"
+)
+
+var (
+	charsetIdent  = regexp.MustCompile(`^\w+$`)
+	charsetClause = regexp.MustCompile(`(?i)\b((?:DEFAULT\s+)?(?:CHARACTER\s+SET|CHARSET)(?:\s*=\s*|\s+))\w+`)
+	collateClause = regexp.MustCompile(`(?i)\b((?:DEFAULT\s+)?COLLATE(?:\s*=\s*|\s+))\w+`)
+	// a collation of the old charset is invalid with the forced one
+	collateDrop = regexp.MustCompile(`(?i)\s*\b(?:DEFAULT\s+)?COLLATE(?:\s*=\s*|\s+)\w+`)
+)
+
+// charsetOverride replaces the charset and collation clauses of the dumped
+// DDL, at database, table and column level
+type charsetOverride struct {
+	charset   string
+	collation string
+}
+
+func (c *charsetOverride) check(ctx context.Context) error {
+	if c.charset != "" && !charsetIdent.MatchString(c.charset) {
+		return moerr.NewInvalidInput(ctx, "invalid force-charset %s", c.charset)
+	}
+	if c.collation != "" && !charsetIdent.MatchString(c.collation) {
+		return moerr.NewInvalidInput(ctx, "invalid force-collation %s", c.collation)
+	}
+	return nil
+}
+
+func (c *charsetOverride) enabled() bool {
+	return c.charset != "" || c.collation != ""
+}
+
+// rewrite replaces the clauses outside of quoted strings and identifiers.
+// If only the charset is forced, the collation clauses are removed so that
+// the default collation of the charset applies.
+func (c *charsetOverride) rewrite(ddl string) string {
+	if !c.enabled() {
+		return ddl
+	}
+	return mapUnquoted(ddl, func(s string) string {
+		if c.charset != "" {
+			s = charsetClause.ReplaceAllString(s, "${1}"+c.charset)
+			if c.collation == "" {
+				s = collateDrop.ReplaceAllString(s, "")
+			}
+		}
+		if c.collation != "" {
+			s = collateClause.ReplaceAllString(s, "${1}"+c.collation)
+		}
+		return s
+	})
+}
+
+// rewriteDB rewrites the CREATE DATABASE statement. A forced value is
+// appended if the statement has no such clause, since the tables without
+// their own clause inherit it.
+func (c *charsetOverride) rewriteDB(ddl string) string {
+	if !c.enabled() {
+		return ddl
+	}
+	var hasCharset, hasCollation bool
+	mapUnquoted(ddl, func(s string) string {
+		hasCharset = hasCharset || charsetClause.MatchString(s)
+		hasCollation = hasCollation || collateClause.MatchString(s)
+		return s
+	})
+	ret := c.rewrite(ddl)
+	if c.charset != "" && !hasCharset {
+		ret += " DEFAULT CHARACTER SET " + c.charset
+	}
+	if c.collation != "" && !hasCollation {
+		ret += " DEFAULT COLLATE " + c.collation
+	}
+	return ret
+}
+
+// mapUnquoted applies f to the parts of s outside of '...', "..." and `...`
+func mapUnquoted(s string, f func(string) string) string {
+	var (
+		sb    strings.Builder
+		start int
+		quote byte
+	)
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if quote == 0 {
+			if ch == '\'' || ch == '"' || ch == '`' {
+				sb.WriteString(f(s[start:i]))
+				start = i
+				quote = ch
+			}
+			continue
+		}
+		switch {
+		case ch == '\\' && quote != '`':
+			i++
+		case ch == quote:
+			if i+1 < len(s) && s[i+1] == quote {
+				// doubled quote
+				i++
+				continue
+			}
+			sb.WriteString(s[start : i+1])
+			start = i + 1
+			quote = 0
+		}
+	}
+	if quote == 0 {
+		sb.WriteString(f(s[start:]))
+	} else {
+		sb.WriteString(s[start:])
+	}
+	return sb.String()
+}
@@ -0,0 +1,79 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCharsetOverrideCheck(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, (&charsetOverride{}).check(ctx))
+	require.NoError(t, (&charsetOverride{"utf8mb4", "utf8mb4_general_ci"}).check(ctx))
+	require.Error(t, (&charsetOverride{charset: "utf8; drop"}).check(ctx))
+	require.Error(t, (&charsetOverride{collation: "a b"}).check(ctx))
+}
+
+func TestCharsetOverrideRewrite(t *testing.T) {
+	both := &charsetOverride{"utf8mb4", "utf8mb4_general_ci"}
+	charsetOnly := &charsetOverride{charset: "utf8mb4"}
+	collationOnly := &charsetOverride{collation: "utf8mb4_bin"}
+
+	// database level
+	db := "CREATE DATABASE `db1` /*!40100 DEFAULT CHARACTER SET latin1 COLLATE latin1_swedish_ci */"
+	require.Equal(t, "CREATE DATABASE `db1` /*!40100 DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci */", both.rewriteDB(db))
+	require.Equal(t, "CREATE DATABASE `db1` /*!40100 DEFAULT CHARACTER SET utf8mb4 */", charsetOnly.rewriteDB(db))
+	require.Equal(t, "CREATE DATABASE `db1` DEFAULT CHARACTER SET utf8mb4 DEFAULT COLLATE utf8mb4_general_ci", both.rewriteDB("CREATE DATABASE `db1`"))
+	require.Equal(t, "CREATE DATABASE `db1`", (&charsetOverride{}).rewriteDB("CREATE DATABASE `db1`"))
+
+	// table and column level
+	tbl := "CREATE TABLE `t1` (\n" +
+		"`a` varchar(10) CHARACTER SET latin1 COLLATE latin1_bin DEFAULT 'charset latin1',\n" +
+		"`charset` text CHARSET gbk,\n" +
+		"`c` int COMMENT 'it''s COLLATE x'\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=latin1 COLLATE=latin1_swedish_ci"
+	require.Equal(t, "CREATE TABLE `t1` (\n"+
+		"`a` varchar(10) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci DEFAULT 'charset latin1',\n"+
+		"`charset` text CHARSET utf8mb4,\n"+
+		"`c` int COMMENT 'it''s COLLATE x'\n"+
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci", both.rewrite(tbl))
+	require.Equal(t, "CREATE TABLE `t1` (\n"+
+		"`a` varchar(10) CHARACTER SET utf8mb4 DEFAULT 'charset latin1',\n"+
+		"`charset` text CHARSET utf8mb4,\n"+
+		"`c` int COMMENT 'it''s COLLATE x'\n"+
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4", charsetOnly.rewrite(tbl))
+	require.Equal(t, "CREATE TABLE `t1` (\n"+
+		"`a` varchar(10) CHARACTER SET latin1 COLLATE utf8mb4_bin DEFAULT 'charset latin1',\n"+
+		"`charset` text CHARSET gbk,\n"+
+		"`c` int COMMENT 'it''s COLLATE x'\n"+
+		") ENGINE=InnoDB DEFAULT CHARSET=latin1 COLLATE=utf8mb4_bin", collationOnly.rewrite(tbl))
+	require.Equal(t, tbl, (&charsetOverride{}).rewrite(tbl))
+}
+
+func TestMapUnquoted(t *testing.T) {
+	upper := func(s string) string {
+		b := []byte(s)
+		for i := range b {
+			if b[i] >= 'a' && b[i] <= 'z' {
+				b[i] -= 'a' - 'A'
+			}
+		}
+		return string(b)
+	}
+	require.Equal(t, "A 'b' C \"d\\\"e\" F `g` H 'i''j' K", mapUnquoted("a 'b' c \"d\\\"e\" f `g` h 'i''j' k", upper))
+	require.Equal(t, "A 'unterminated", mapUnquoted("a 'unterminated", upper))
+}
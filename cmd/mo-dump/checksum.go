@@ -0,0 +1,156 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/matrixorigin/matrixone/pkg/common/moerr"
+)
+
+const (
+	checksumCRC32  = "crc32"
+	checksumSHA256 = "sha256"
+	checksumXXHash = "xxhash"
+)
+
+func checkChecksum(ctx context.Context, algorithm string, rowChecksums bool) error {
+	switch algorithm {
+	case "":
+		if rowChecksums {
+			return moerr.NewInvalidInput(ctx, "row-checksums requires checksum-algorithm")
+		}
+	case checksumCRC32, checksumSHA256, checksumXXHash:
+	default:
+		return moerr.NewInvalidInput(ctx, "checksum-algorithm must be one of %s, %s, %s, got %s", checksumCRC32, checksumSHA256, checksumXXHash, algorithm)
+	}
+	return nil
+}
+
+// rowChecksum hashes the values of a row as fetched from mo, before they are
+// converted for the output. Each value is encoded as its uint32 length and
+// its bytes, NULL as the length math.MaxUint32, so that different rows do
+// not collide by their concatenation. sha256 is cut to its first 8 bytes.
+func rowChecksum(algorithm string, buf *bytes.Buffer, values []any) uint64 {
+	buf.Reset()
+	var n [4]byte
+	for _, v := range values {
+		val := *(v.(*sql.RawBytes))
+		if val == nil {
+			binary.BigEndian.PutUint32(n[:], math.MaxUint32)
+			buf.Write(n[:])
+			continue
+		}
+		binary.BigEndian.PutUint32(n[:], uint32(len(val)))
+		buf.Write(n[:])
+		buf.Write(val)
+	}
+	switch algorithm {
+	case checksumCRC32:
+		return uint64(crc32.ChecksumIEEE(buf.Bytes()))
+	case checksumSHA256:
+		sum := sha256.Sum256(buf.Bytes())
+		return binary.BigEndian.Uint64(sum[:8])
+	default:
+		return xxhash.Sum64(buf.Bytes())
+	}
+}
+
+// tableChecksum combines the row checksums of a table by a wrapping sum,
+// which does not depend on the row order and, unlike xor, does not cancel
+// duplicate rows
+type tableChecksum struct {
+	sum  uint64
+	rows int64
+}
+
+func (c *tableChecksum) add(o tableChecksum) {
+	c.sum += o.sum
+	c.rows += o.rows
+}
+
+func (c *tableChecksum) show(w io.Writer, tbl, algorithm string) {
+	fmt.Fprintf(w, "/* CHECKSUM `%s` %s: %016x, %d rows */\n", tbl, algorithm, c.sum, c.rows)
+}
+
+// checksumRows computes the checksum of each row scanned from the wrapped
+// rows. The row checksums are written to the sidecar file in row order if
+// it is set.
+type checksumRows struct {
+	rowIterator
+	tableChecksum
+	algorithm string
+	file      *limitedFile
+	sidecar   *bufio.Writer
+	buf       bytes.Buffer
+}
+
+// newChecksumRows wraps r, the row checksums go to db_tbl.rowsums if
+// rowChecksums is set
+func newChecksumRows(r rowIterator, algorithm string, rowChecksums bool, dir, db, tbl string) (*checksumRows, error) {
+	c := &checksumRows{rowIterator: r, algorithm: algorithm}
+	if rowChecksums {
+		f, err := openFiles.create(dataFile(dir, fmt.Sprintf("%s_%s.%s", db, tbl, "rowsums")))
+		if err != nil {
+			return nil, err
+		}
+		c.file = f
+		c.sidecar = bufio.NewWriter(f)
+	}
+	return c, nil
+}
+
+// finish writes the table checksum and returns the sidecar file name, if any
+func (c *checksumRows) finish(w io.Writer, tbl string) (string, error) {
+	c.show(w, tbl, c.algorithm)
+	if c.file == nil {
+		return "", nil
+	}
+	err := c.sidecar.Flush()
+	if err != nil {
+		return "", err
+	}
+	return c.file.Name(), nil
+}
+
+func (c *checksumRows) close() {
+	if c.file != nil {
+		c.file.Close()
+	}
+}
+
+func (c *checksumRows) Scan(dest ...any) error {
+	err := c.rowIterator.Scan(dest...)
+	if err != nil {
+		return err
+	}
+	h := rowChecksum(c.algorithm, &c.buf, dest)
+	c.sum += h
+	c.rows++
+	if c.sidecar != nil {
+		_, err = fmt.Fprintf(c.sidecar, "%016x\n", h)
+	}
+	return err
+}
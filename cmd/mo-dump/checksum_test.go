@@ -0,0 +1,140 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckChecksum(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, checkChecksum(ctx, "", false))
+	require.NoError(t, checkChecksum(ctx, checksumCRC32, true))
+	require.NoError(t, checkChecksum(ctx, checksumSHA256, false))
+	require.NoError(t, checkChecksum(ctx, checksumXXHash, false))
+	require.Error(t, checkChecksum(ctx, "md5", false))
+	require.Error(t, checkChecksum(ctx, "", true))
+}
+
+func TestRowChecksum(t *testing.T) {
+	row := func(vals ...any) []any {
+		ret := make([]any, len(vals))
+		for i, v := range vals {
+			var b sql.RawBytes
+			if v != nil {
+				b = sql.RawBytes(v.(string))
+			}
+			ret[i] = &b
+		}
+		return ret
+	}
+	var buf bytes.Buffer
+	for _, alg := range []string{checksumCRC32, checksumSHA256, checksumXXHash} {
+		a := rowChecksum(alg, &buf, row("1", "ab"))
+		require.Equal(t, a, rowChecksum(alg, &buf, row("1", "ab")), alg)
+		// the values are not simply concatenated
+		require.NotEqual(t, a, rowChecksum(alg, &buf, row("1a", "b")), alg)
+		// NULL differs from the empty string
+		require.NotEqual(t, rowChecksum(alg, &buf, row("1", nil)), rowChecksum(alg, &buf, row("1", "")), alg)
+	}
+}
+
+func TestGenOutputChecksumOrderIndependent(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	defer os.Chdir(wd)
+
+	bufPool := &sync.Pool{
+		New: func() any {
+			return &bytes.Buffer{}
+		},
+	}
+	checksum := regexp.MustCompile("/\\* CHECKSUM `t1` \\w+: [0-9a-f]{16}, 3 rows \\*/\n")
+	for _, alg := range []string{checksumCRC32, checksumSHA256, checksumXXHash} {
+		opt := Options{netBufferLength: defaultNetBufferLength, checksumAlgorithm: alg, rowChecksums: true}
+		mock.ExpectQuery("select").WillReturnRows(sqlmock.NewRows([]string{"a", "b"}).
+			AddRow("1", "x").AddRow("2", nil).AddRow("3", "z"))
+		out1 := captureStdout(t, func() {
+			err = opt.genOutput(context.Background(), []string{"select * from `db1`.`t1`"}, "db1", "t1", bufPool)
+		})
+		require.NoError(t, err)
+		sums1, err := os.ReadFile(filepath.Join(dir, "db1_t1.rowsums"))
+		require.NoError(t, err)
+
+		// the same data in another order
+		mock.ExpectQuery("select").WillReturnRows(sqlmock.NewRows([]string{"a", "b"}).
+			AddRow("3", "z").AddRow("1", "x").AddRow("2", nil))
+		out2 := captureStdout(t, func() {
+			err = opt.genOutput(context.Background(), []string{"select * from `db1`.`t1`"}, "db1", "t1", bufPool)
+		})
+		require.NoError(t, err)
+		sums2, err := os.ReadFile(filepath.Join(dir, "db1_t1.rowsums"))
+		require.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+
+		sum1 := checksum.FindString(out1)
+		require.NotEmpty(t, sum1, alg)
+		require.Contains(t, sum1, " "+alg+": ")
+		require.Equal(t, sum1, checksum.FindString(out2), alg)
+		require.True(t, strings.HasPrefix(out1, "INSERT INTO `t1` VALUES (1,'x'),(2,NULL),(3,'z');\n"), alg)
+
+		// the row checksums follow the rows
+		lines1 := strings.Split(strings.TrimSpace(string(sums1)), "\n")
+		lines2 := strings.Split(strings.TrimSpace(string(sums2)), "\n")
+		require.Len(t, lines1, 3)
+		require.Equal(t, []string{lines1[2], lines1[0], lines1[1]}, lines2)
+	}
+
+	// a changed row changes the table checksum
+	opt := Options{netBufferLength: defaultNetBufferLength, checksumAlgorithm: checksumXXHash}
+	mock.ExpectQuery("select").WillReturnRows(sqlmock.NewRows([]string{"a", "b"}).
+		AddRow("1", "x").AddRow("2", nil).AddRow("3", "z"))
+	out1 := captureStdout(t, func() {
+		err = opt.genOutput(context.Background(), []string{"select * from `db1`.`t1`"}, "db1", "t1", bufPool)
+	})
+	require.NoError(t, err)
+	mock.ExpectQuery("select").WillReturnRows(sqlmock.NewRows([]string{"a", "b"}).
+		AddRow("1", "x").AddRow("2", "").AddRow("3", "z"))
+	out2 := captureStdout(t, func() {
+		err = opt.genOutput(context.Background(), []string{"select * from `db1`.`t1`"}, "db1", "t1", bufPool)
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.NotEqual(t, checksum.FindString(out1), checksum.FindString(out2))
+}
+
+func TestTableChecksumAdd(t *testing.T) {
+	var c tableChecksum
+	c.add(tableChecksum{sum: ^uint64(0), rows: 1})
+	c.add(tableChecksum{sum: 2, rows: 2})
+	require.Equal(t, tableChecksum{sum: 1, rows: 3}, c)
+}
@@ -0,0 +1,250 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/matrixorigin/matrixone/pkg/common/moerr"
+)
+
+// maxTableChunks limits the parallel queries of a chunked table
+const maxTableChunks = 256
+
+// chunkTable splits the rows of table into chunks of its integer primary
+// key column, which are read in parallel
+type chunkTable struct {
+	table  string
+	column string
+	chunks int
+}
+
+// parseChunkTable parses tbl:pk:N
+func parseChunkTable(ctx context.Context, spec string) (*chunkTable, error) {
+	parts := strings.Split(spec, ":")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" {
+		return nil, moerr.NewInvalidInput(ctx, "chunk-table must be in the format tbl:pk:N, got %s", spec)
+	}
+	n, err := strconv.Atoi(parts[2])
+	if err != nil || n < 1 || n > maxTableChunks {
+		return nil, moerr.NewInvalidInput(ctx, "number of chunks must be between 1 and %d, got %s", maxTableChunks, parts[2])
+	}
+	return &chunkTable{
+		table:  parts[0],
+		column: parts[1],
+		chunks: n,
+	}, nil
+}
+
+// splitKeys returns the keys which split [min, max] into at most n ranges
+// of about the same size, in ascending order
+func splitKeys(min, max int64, n int) []int64 {
+	span := uint64(max-min) + 1
+	if span == 0 {
+		// the whole int64 range, the last range takes the missing key
+		span = math.MaxUint64
+	}
+	chunks := uint64(n)
+	if chunks > span {
+		chunks = span
+	}
+	if chunks <= 1 {
+		return nil
+	}
+	step, rem := span/chunks, span%chunks
+	splits := make([]int64, 0, chunks-1)
+	lo := min
+	for i := uint64(0); i < chunks-1; i++ {
+		size := step
+		if i < rem {
+			size++
+		}
+		lo = int64(uint64(lo) + size)
+		splits = append(splits, lo)
+	}
+	return splits
+}
+
+// predicates returns the conditions of the chunks of [min, max]. The first
+// chunk has no lower bound and the last one no upper bound, so every key
+// falls into exactly one chunk, also keys written after the bounds were
+// read. There is no predicate if the range is not split.
+func (c *chunkTable) predicates(min, max int64) []string {
+	splits := splitKeys(min, max, c.chunks)
+	if len(splits) == 0 {
+		return nil
+	}
+	col := quoteIdent(c.column)
+	preds := make([]string, 0, len(splits)+1)
+	preds = append(preds, fmt.Sprintf("%s < %d", col, splits[0]))
+	for i := 1; i < len(splits); i++ {
+		preds = append(preds, fmt.Sprintf("%s >= %d AND %s < %d", col, splits[i-1], col, splits[i]))
+	}
+	preds = append(preds, fmt.Sprintf("%s >= %d", col, splits[len(splits)-1]))
+	return preds
+}
+
+// isPrimaryKey checks if the column is the primary key of the table
+func isPrimaryKey(ctx context.Context, db, tbl, col string) (bool, error) {
+	var cnt int
+	query := "select count(*) from mo_catalog.mo_columns where att_database = '" + escapeString(db) +
+		"' and att_relname = '" + escapeString(tbl) + "' and attname = '" + escapeString(col) + "' and att_constraint_type = 'p'"
+	err := connRetry.queryRow(ctx, "read the primary key of "+quoteIdent(db)+"."+quoteIdent(tbl), query, &cnt)
+	if err != nil {
+		return false, err
+	}
+	return cnt > 0, nil
+}
+
+// dumpTableChunks reads the chunks of the table in parallel, each one into
+// a temporary file, and writes the files in key order
+func (opt *Options) dumpTableChunks(ctx context.Context, db, tbl string, bufPool *sync.Pool) error {
+	c := opt.chunkTable
+	ok, err := isPrimaryKey(ctx, db, tbl, c.column)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return moerr.NewInvalidInput(ctx, "column %s is not the primary key of table `%s`.`%s`", c.column, db, tbl)
+	}
+	var min, max sql.NullInt64
+	query := "select min(" + quoteIdent(c.column) + "), max(" + quoteIdent(c.column) + ") from " + quoteIdent(db) + "." + quoteIdent(tbl)
+	err = connRetry.queryRow(ctx, query, query, &min, &max)
+	if err != nil {
+		return moerr.NewNotSupported(ctx, "chunk-table requires an integer primary key, `%s`.`%s`: %v", db, tbl, err)
+	}
+	var preds []string
+	if min.Valid {
+		preds = c.predicates(min.Int64, max.Int64)
+	}
+	if len(preds) == 0 {
+		// empty table or a single chunk
+		queries, err := opt.selectQueries(ctx, db, tbl)
+		if err != nil {
+			return err
+		}
+		return opt.genOutput(ctx, queries, db, tbl, bufPool)
+	}
+	chunkQueries := make([][]string, len(preds))
+	var allQueries []string
+	for i, pred := range preds {
+		chunkQueries[i], err = opt.selectQueries(ctx, db, tbl, pred)
+		if err != nil {
+			return err
+		}
+		allQueries = append(allQueries, chunkQueries[i]...)
+	}
+	err = opt.showRowCount(ctx, allQueries, db, tbl)
+	if err != nil {
+		return err
+	}
+	p, err := opt.startTableProgress(ctx, allQueries, db, tbl)
+	if err != nil {
+		return err
+	}
+	defer p.finish()
+	// the files are closed once written and reopened in key order, at most
+	// max-open-files of them are open at once
+	names := make([]string, len(preds))
+	defer func() {
+		for _, name := range names {
+			if name != "" {
+				os.Remove(name)
+			}
+		}
+	}()
+	errs := make([]error, len(preds))
+	sums := make([]tableChecksum, len(preds))
+	var wg sync.WaitGroup
+	for i := range chunkQueries {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			f, err := openFiles.createTemp("mo-dump-chunk-*")
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			names[i] = f.Name()
+			sums[i], errs[i] = opt.dumpChunk(ctx, chunkQueries[i], tbl, f.File, bufPool, p)
+			if err = f.Close(); errs[i] == nil {
+				errs[i] = err
+			}
+		}(i)
+	}
+	wg.Wait()
+	for _, err = range errs {
+		if err != nil {
+			return err
+		}
+	}
+	for _, name := range names {
+		err = copyFile(opt.stdout(), name)
+		if err != nil {
+			return err
+		}
+	}
+	if opt.checksumAlgorithm != "" {
+		// the sum does not depend on the order of the chunks
+		var sum tableChecksum
+		for _, s := range sums {
+			sum.add(s)
+		}
+		sum.show(opt.stdout(), tbl, opt.checksumAlgorithm)
+	}
+	return nil
+}
+
+// dumpChunk writes the INSERTs of a chunk to f and returns the checksum of
+// its rows if checksum-algorithm is set
+func (opt *Options) dumpChunk(ctx context.Context, queries []string, tbl string, f *os.File, bufPool *sync.Pool, p *progress) (tableChecksum, error) {
+	rows, cols, rowResults, err := opt.openRows(ctx, queries, tbl)
+	if err != nil {
+		return tableChecksum{}, err
+	}
+	defer rows.Close()
+	var r rowIterator = p.wrap(rows)
+	cr := &checksumRows{rowIterator: r, algorithm: opt.checksumAlgorithm}
+	if opt.checksumAlgorithm != "" {
+		r = cr
+	}
+	r = opt.wrapJSONRows(r, cols, tbl)
+	w := bufio.NewWriter(f)
+	err = showInsert(r, w, rowResults, cols, tbl, bufPool, opt.netBufferLength, opt.insertBatchRows, opt.maxRowSize, opt.validateUTF8, opt.completeInsert(), opt.insertMode, -1)
+	if err != nil {
+		return tableChecksum{}, err
+	}
+	return cr.tableChecksum, w.Flush()
+}
+
+// copyFile writes the content of the file to w
+func copyFile(w io.Writer, name string) error {
+	f, err := openFiles.openFile(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
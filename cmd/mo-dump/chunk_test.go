@@ -0,0 +1,138 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"sync"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseChunkTable(t *testing.T) {
+	ctx := context.Background()
+	c, err := parseChunkTable(ctx, "big:id:16")
+	require.NoError(t, err)
+	require.Equal(t, &chunkTable{"big", "id", 16}, c)
+
+	for _, spec := range []string{"big:id", "big:id:0", "big:id:x", ":id:4", "big::4", "big:id:4:1", "big:id:1000"} {
+		_, err = parseChunkTable(ctx, spec)
+		require.Error(t, err, spec)
+	}
+}
+
+func TestChunkPredicates(t *testing.T) {
+	c := &chunkTable{"big", "id", 4}
+	require.Equal(t, []string{
+		"`id` < 26",
+		"`id` >= 26 AND `id` < 51",
+		"`id` >= 51 AND `id` < 76",
+		"`id` >= 76",
+	}, c.predicates(1, 100))
+	require.Equal(t, []string{"`id` < -5", "`id` >= -5 AND `id` < 0", "`id` >= 0 AND `id` < 5", "`id` >= 5"}, c.predicates(-10, 9))
+	// fewer keys than chunks
+	require.Equal(t, []string{"`id` < 2", "`id` >= 2 AND `id` < 3", "`id` >= 3"}, c.predicates(1, 3))
+	require.Nil(t, c.predicates(5, 5))
+	c.chunks = 1
+	require.Nil(t, c.predicates(1, 100))
+}
+
+func TestSplitKeysCoverage(t *testing.T) {
+	kases := []struct {
+		min, max int64
+		n        int
+	}{
+		{1, 100, 4},
+		{1, 101, 4},
+		{0, 9, 16},
+		{-1000, 1000, 7},
+		{1, 1 << 40, 256},
+		{math.MinInt64, math.MaxInt64, 16},
+		{math.MaxInt64 - 10, math.MaxInt64, 3},
+	}
+	for _, k := range kases {
+		splits := splitKeys(k.min, k.max, k.n)
+		require.LessOrEqual(t, len(splits), k.n-1)
+		// the chunks [min, s0), [s0, s1) ... [sn, max] are not empty, do
+		// not overlap and together hold every key
+		lo := k.min
+		var sizes []uint64
+		for _, s := range splits {
+			require.Greater(t, s, lo)
+			require.LessOrEqual(t, s, k.max)
+			sizes = append(sizes, uint64(s-lo))
+			lo = s
+		}
+		sizes = append(sizes, uint64(k.max-lo)+1)
+		var total uint64
+		for _, size := range sizes {
+			total += size
+			// about the same size
+			require.LessOrEqual(t, size-sizes[len(sizes)-1], uint64(1))
+		}
+		require.Equal(t, uint64(k.max-k.min)+1, total)
+	}
+}
+
+func TestDumpTableChunks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+	mock.MatchExpectationsInOrder(false)
+
+	ctx := context.Background()
+	bufPool := &sync.Pool{
+		New: func() any {
+			return &bytes.Buffer{}
+		},
+	}
+	opt := Options{
+		netBufferLength: defaultNetBufferLength,
+		format:          formatSQL,
+		where:           "a > 0",
+		chunkTable:      &chunkTable{"big", "id", 3},
+	}
+	mock.ExpectQuery("att_constraint_type = 'p'").WillReturnRows(sqlmock.NewRows([]string{"cnt"}).AddRow(1))
+	mock.ExpectQuery("select min\\(`id`\\), max\\(`id`\\) from `db1`.`big`").
+		WillReturnRows(sqlmock.NewRows([]string{"min", "max"}).AddRow(1, 9))
+	mock.ExpectQuery("where \\(a > 0\\) AND `id` < 4$").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("1").AddRow("3"))
+	mock.ExpectQuery("where \\(a > 0\\) AND `id` >= 4 AND `id` < 7$").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("where \\(a > 0\\) AND `id` >= 7$").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("7").AddRow("9"))
+	out := captureStdout(t, func() {
+		err = opt.dumpTableData(ctx, "db1", "big", bufPool)
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Equal(t, "INSERT INTO `big` VALUES (1),(3);\nINSERT INTO `big` VALUES (7),(9);\n\n\n\n", out)
+
+	// the column must be the primary key
+	mock.ExpectQuery("att_constraint_type = 'p'").WillReturnRows(sqlmock.NewRows([]string{"cnt"}).AddRow(0))
+	err = opt.dumpTableChunks(ctx, "db1", "big", bufPool)
+	require.Error(t, err)
+
+	// and an integer
+	mock.ExpectQuery("att_constraint_type = 'p'").WillReturnRows(sqlmock.NewRows([]string{"cnt"}).AddRow(1))
+	mock.ExpectQuery("select min").WillReturnRows(sqlmock.NewRows([]string{"min", "max"}).AddRow("a", "z"))
+	err = opt.dumpTableChunks(ctx, "db1", "big", bufPool)
+	require.Error(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
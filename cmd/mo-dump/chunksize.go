@@ -0,0 +1,169 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/matrixorigin/matrixone/pkg/common/moerr"
+)
+
+// csvChunk is the size at which the csv file of a table rolls over to the
+// next file for chunk-size, in bytes or in rows. Zero keeps one file.
+type csvChunk struct {
+	bytes int64
+	rows  int64
+}
+
+func (c csvChunk) enabled() bool {
+	return c.bytes > 0 || c.rows > 0
+}
+
+// full reports whether a file holding the given bytes and rows is complete
+func (c csvChunk) full(bytes, rows int64) bool {
+	return c.bytes > 0 && bytes >= c.bytes || c.rows > 0 && rows >= c.rows
+}
+
+// parseChunkSize parses a number of bytes with an optional K, M or G
+// suffix, or a number of rows such as 100000rows
+func parseChunkSize(ctx context.Context, spec string) (csvChunk, error) {
+	s := strings.ToLower(strings.TrimSpace(spec))
+	var (
+		c    csvChunk
+		unit int64 = 1
+	)
+	rows := strings.HasSuffix(s, "rows")
+	if rows {
+		s = strings.TrimSuffix(s, "rows")
+	} else if s != "" {
+		switch s[len(s)-1] {
+		case 'k':
+			unit = 1 << 10
+		case 'm':
+			unit = 1 << 20
+		case 'g':
+			unit = 1 << 30
+		}
+		if unit > 1 {
+			s = s[:len(s)-1]
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 1 || n > (1<<62)/unit {
+		return c, moerr.NewInvalidInput(ctx, "chunk-size must be a positive number of bytes with an optional K, M or G suffix, or of rows such as 100000rows, got %s", spec)
+	}
+	if rows {
+		c.rows = n
+	} else {
+		c.bytes = n * unit
+	}
+	return c, nil
+}
+
+// rowEnder is told about the end of each row written to it, so files can
+// roll over between rows
+type rowEnder interface {
+	endRow() error
+}
+
+// csvFiles writes the csv rows of a table to db_tbl.csv, or with chunk-size
+// to db_tbl.0001.csv, db_tbl.0002.csv and so on. The next file is opened by
+// the first write after the current one is full, so a table never ends with
+// an empty file. The size of a file is counted before compression.
+type csvFiles struct {
+	base  string
+	ext   string
+	conf  *csvConfig
+	f     *limitedFile
+	gw    *gzip.Writer
+	w     io.Writer
+	bytes int64
+	rows  int64
+	names []string
+}
+
+func newCsvFiles(db, tbl string, csvConf *csvConfig) *csvFiles {
+	ext := ".csv"
+	if csvConf.tsv {
+		ext = ".tsv"
+	}
+	if csvConf.compress == csvCompressGzip {
+		ext += ".gz"
+	}
+	return &csvFiles{
+		base: dataFile(csvConf.dir, fmt.Sprintf("%s_%s", db, tbl)),
+		ext:  ext,
+		conf: csvConf,
+	}
+}
+
+func (c *csvFiles) open() error {
+	name := c.base + c.ext
+	if c.conf.chunk.enabled() {
+		name = fmt.Sprintf("%s.%04d%s", c.base, len(c.names)+1, c.ext)
+	}
+	f, err := openFiles.create(name)
+	if err != nil {
+		return err
+	}
+	c.f, c.w = f, f
+	if c.conf.compress == csvCompressGzip {
+		c.gw = gzip.NewWriter(f)
+		c.w = c.gw
+	}
+	c.names = append(c.names, name)
+	return nil
+}
+
+func (c *csvFiles) Write(p []byte) (int, error) {
+	if c.f == nil {
+		if err := c.open(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := c.w.Write(p)
+	c.bytes += int64(n)
+	return n, err
+}
+
+func (c *csvFiles) endRow() error {
+	c.rows++
+	if c.conf.chunk.enabled() && c.conf.chunk.full(c.bytes, c.rows) {
+		return c.closeFile()
+	}
+	return nil
+}
+
+// closeFile completes the current file, if any
+func (c *csvFiles) closeFile() error {
+	if c.f == nil {
+		return nil
+	}
+	var err error
+	if c.gw != nil {
+		err = c.gw.Close()
+	}
+	if e := c.f.Close(); err == nil {
+		err = e
+	}
+	c.f, c.gw, c.w = nil, nil, nil
+	c.bytes, c.rows = 0, 0
+	return err
+}
@@ -0,0 +1,122 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseChunkSize(t *testing.T) {
+	ctx := context.Background()
+	for spec, want := range map[string]csvChunk{
+		"4096":       {bytes: 4096},
+		"64K":        {bytes: 64 << 10},
+		"100m":       {bytes: 100 << 20},
+		"2G":         {bytes: 2 << 30},
+		"100000rows": {rows: 100000},
+		"10ROWS":     {rows: 10},
+	} {
+		c, err := parseChunkSize(ctx, spec)
+		require.NoError(t, err, spec)
+		require.Equal(t, want, c, spec)
+	}
+	for _, spec := range []string{"", "0", "-1", "K", "rows", "1.5M", "10T", "1 rows", "9999999999G"} {
+		_, err := parseChunkSize(ctx, spec)
+		require.Error(t, err, spec)
+	}
+}
+
+func TestGenOutputChunkSize(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	dir := t.TempDir()
+	ctx := context.Background()
+	opt := Options{
+		netBufferLength: defaultNetBufferLength,
+		toCsv:           true,
+		csvConf:         csvConfig{enable: true, fieldDelimiter: defaultFieldDelimiter, dir: dir, chunk: csvChunk{rows: 2}},
+	}
+	rows := sqlmock.NewRows([]string{"a"})
+	for _, v := range []string{"1", "2", "3", "4", "5"} {
+		rows.AddRow(v)
+	}
+	mock.ExpectQuery("select \\* from `db1`.`t1`").WillReturnRows(rows)
+	out := captureStdout(t, func() {
+		err = opt.genOutput(ctx, []string{"select * from `db1`.`t1`"}, "db1", "t1", &sync.Pool{})
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	var want string
+	for i, data := range []string{"1\n2\n", "3\n4\n", "5\n"} {
+		name := filepath.Join(dir, "db1_t1.000"+string(rune('1'+i))+".csv")
+		got, err := os.ReadFile(name)
+		require.NoError(t, err)
+		require.Equal(t, data, string(got))
+		want += loadDataStmt(name, "t1", false, &opt.csvConf, nil) + "\n"
+	}
+	require.Equal(t, want, out)
+	_, err = os.Stat(filepath.Join(dir, "db1_t1.0004.csv"))
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestShowLoadChunkBytes(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	dir := t.TempDir()
+	rows := sqlmock.NewRows([]string{"a"})
+	for i := 0; i < 10; i++ {
+		rows.AddRow(strings.Repeat("x", 9))
+	}
+	mock.ExpectQuery("select").WillReturnRows(rows)
+	r, err := db.Query("select")
+	require.NoError(t, err)
+	defer r.Close()
+
+	// a file is complete once it holds 25 bytes before compression, which
+	// takes three rows of ten bytes
+	csvConf := &csvConfig{enable: true, fieldDelimiter: ',', compress: csvCompressGzip, dir: dir, chunk: csvChunk{bytes: 25}}
+	fnames, err := showLoad(r, []any{new(sql.RawBytes)}, []*Column{{Name: "a", Type: "VARCHAR"}}, "db1", "t1", csvConf)
+	require.NoError(t, err)
+	require.Len(t, fnames, 4)
+	var total int
+	for i, name := range fnames {
+		require.Equal(t, filepath.Join(dir, "db1_t1.000"+string(rune('1'+i))+".csv.gz"), name)
+		f, err := os.Open(name)
+		require.NoError(t, err)
+		gr, err := gzip.NewReader(f)
+		require.NoError(t, err)
+		data, err := io.ReadAll(gr)
+		require.NoError(t, err)
+		f.Close()
+		total += strings.Count(string(data), "\n")
+	}
+	require.Equal(t, 10, total)
+}
@@ -0,0 +1,96 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// connRetry retries the queries which fail on the connection, set by
+// -retries and -retry-interval
+var connRetry retryPolicy
+
+type retryPolicy struct {
+	retries  int
+	interval time.Duration
+}
+
+// do runs f until it succeeds, fails with an error of the statement rather
+// than of the connection, or the retries are used up. The wait doubles
+// after every retry.
+func (p retryPolicy) do(ctx context.Context, what string, f func() error) error {
+	wait := p.interval
+	for i := 1; ; i++ {
+		err := f()
+		if err == nil || i > p.retries || !isConnError(err) {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "%s failed: %v, retry %d/%d in %v\n", what, err, i, p.retries, wait)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+// query runs a query with the retries of the policy
+func (p retryPolicy) query(ctx context.Context, what, query string) (*sql.Rows, error) {
+	var r *sql.Rows
+	err := p.do(ctx, what, func() (err error) {
+		r, err = session(ctx).QueryContext(ctx, query)
+		return err
+	})
+	return r, err
+}
+
+// queryRow runs a query of one row and scans it with the retries of the
+// policy, each within query-timeout
+func (p retryPolicy) queryRow(ctx context.Context, what, query string, dest ...any) error {
+	return p.do(ctx, what, func() error {
+		qctx, cancel := queryContext(ctx)
+		defer cancel()
+		err := session(ctx).QueryRowContext(qctx, query).Scan(dest...)
+		return queryTimeoutError(ctx, qctx, err, what)
+	})
+}
+
+// isConnError reports if err is a lost or timed out connection, which a new
+// connection may not run into. The end of the context is not retried, nor
+// any error the server returns for the statement.
+func isConnError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
@@ -0,0 +1,120 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"syscall"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/require"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestIsConnError(t *testing.T) {
+	for _, err := range []error{
+		driver.ErrBadConn,
+		mysql.ErrInvalidConn,
+		io.ErrUnexpectedEOF,
+		fmt.Errorf("read: %w", syscall.ECONNRESET),
+		timeoutError{},
+	} {
+		require.True(t, isConnError(err), err.Error())
+	}
+	for _, err := range []error{
+		&mysql.MySQLError{Number: 1146, Message: "table not found"},
+		context.Canceled,
+		context.DeadlineExceeded,
+		fmt.Errorf("syntax error"),
+	} {
+		require.False(t, isConnError(err), err.Error())
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	ctx := context.Background()
+	p := retryPolicy{retries: 2, interval: time.Millisecond}
+
+	var calls int
+	stderr := captureStderr(t, func() {
+		require.NoError(t, p.do(ctx, "show databases", func() error {
+			calls++
+			if calls < 3 {
+				return mysql.ErrInvalidConn
+			}
+			return nil
+		}))
+	})
+	require.Equal(t, 3, calls)
+	require.Equal(t, "show databases failed: invalid connection, retry 1/2 in 1ms\n"+
+		"show databases failed: invalid connection, retry 2/2 in 2ms\n", stderr)
+
+	// the retries are used up
+	calls = 0
+	captureStderr(t, func() {
+		require.ErrorIs(t, p.do(ctx, "q", func() error {
+			calls++
+			return mysql.ErrInvalidConn
+		}), mysql.ErrInvalidConn)
+	})
+	require.Equal(t, 3, calls)
+
+	// the statement fails the same on any connection
+	calls = 0
+	require.Error(t, p.do(ctx, "q", func() error {
+		calls++
+		return &mysql.MySQLError{Number: 1146, Message: "table not found"}
+	}))
+	require.Equal(t, 1, calls)
+
+	// no retries by default
+	calls = 0
+	require.Error(t, retryPolicy{}.do(ctx, "q", func() error {
+		calls++
+		return mysql.ErrInvalidConn
+	}))
+	require.Equal(t, 1, calls)
+}
+
+func TestGetCreateTableRetry(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+	defer func(p retryPolicy) { connRetry = p }(connRetry)
+	connRetry = retryPolicy{retries: 1}
+
+	mock.ExpectQuery("show create table `db1`.`t1`").WillReturnError(mysql.ErrInvalidConn)
+	mock.ExpectQuery("show create table `db1`.`t1`").
+		WillReturnRows(sqlmock.NewRows([]string{"Table", "Create Table"}).AddRow("t1", "create table t1 (a int)"))
+	var create string
+	stderr := captureStderr(t, func() {
+		create, err = getCreateTable(context.Background(), "db1", "t1")
+	})
+	require.NoError(t, err)
+	require.Equal(t, "create table t1 (a int)", create)
+	require.Contains(t, stderr, "retry 1/1")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,331 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/matrixorigin/matrixone/pkg/catalog"
+	"github.com/matrixorigin/matrixone/pkg/common/moerr"
+)
+
+const (
+	// consistencyNone reads every table without any coordination
+	consistencyNone = "none"
+	// consistencySnapshot reads all data inside one transaction
+	consistencySnapshot = "snapshot"
+	// consistencyLock holds LOCK TABLES ... READ on each database while it is dumped
+	consistencyLock = "lock"
+	// consistencyFlush holds FLUSH TABLES WITH READ LOCK during the whole dump
+	consistencyFlush = "flush"
+)
+
+// consistencyFallback maps each mode to the next best one, tried when
+// the server does not support the mode
+var consistencyFallback = map[string]string{
+	consistencyFlush:    consistencyLock,
+	consistencyLock:     consistencySnapshot,
+	consistencySnapshot: consistencyNone,
+}
+
+// consistencyCapability names the statement each mode relies on
+var consistencyCapability = map[string]string{
+	consistencySnapshot: "START TRANSACTION",
+	consistencyLock:     "LOCK TABLES",
+	consistencyFlush:    "FLUSH TABLES WITH READ LOCK",
+}
+
+// queryer is the part of *sql.DB and *sql.Conn the dump runs its
+// statements through
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// pinned is the connection held by every mode but none. The transaction
+// and the locks of the mode belong to its session, so all statements of
+// the dump have to run on it. A lost connection fails the next statement
+// instead of reading on without the snapshot or the locks.
+var pinned *pinnedConn
+
+// pinnedConn keeps track of the statements run on the pinned connection, so
+// the keepalive pings it only while it is idle. A ping between two rows of
+// an open result would break the protocol of the session.
+type pinnedConn struct {
+	*sql.Conn
+	mu   sync.Mutex
+	used bool
+	rows []*sql.Rows
+}
+
+func (c *pinnedConn) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.used = true
+	r, err := c.Conn.QueryContext(ctx, query, args...)
+	if err == nil {
+		c.rows = append(c.liveRows(), r)
+	}
+	return r, err
+}
+
+func (c *pinnedConn) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.used = true
+	return c.Conn.QueryRowContext(ctx, query, args...)
+}
+
+func (c *pinnedConn) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.used = true
+	return c.Conn.ExecContext(ctx, query, args...)
+}
+
+// pingIdle pings the connection if no statement was run on it since the
+// last call and all its results are closed
+func (c *pinnedConn) pingIdle(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rows = c.liveRows()
+	used := c.used
+	c.used = false
+	if used || len(c.rows) > 0 {
+		return nil
+	}
+	return c.Conn.PingContext(ctx)
+}
+
+// liveRows returns the results of the connection which are not closed yet
+func (c *pinnedConn) liveRows() []*sql.Rows {
+	open := c.rows[:0]
+	for _, r := range c.rows {
+		// Columns fails once the rows are closed
+		if _, err := r.Columns(); err == nil {
+			open = append(open, r)
+		}
+	}
+	return open
+}
+
+// sessionKey is the context key of the connection of a worker of -parallel
+type sessionKey struct{}
+
+// withSession runs the statements under ctx on the connection q of a worker
+func withSession(ctx context.Context, q queryer) context.Context {
+	return context.WithValue(ctx, sessionKey{}, q)
+}
+
+// session returns the connection of the worker the statements under ctx
+// run for, else the pinned connection if the dump holds one, else the pool
+func session(ctx context.Context) queryer {
+	if q, ok := ctx.Value(sessionKey{}).(queryer); ok {
+		return q
+	}
+	if pinned != nil {
+		return pinned
+	}
+	return conn
+}
+
+func checkConsistency(ctx context.Context, mode string) error {
+	switch mode {
+	case consistencyNone, consistencySnapshot, consistencyLock, consistencyFlush:
+		return nil
+	default:
+		return moerr.NewInvalidInput(ctx, "unsupported consistency %s", mode)
+	}
+}
+
+// checkLockOptions turns -lock-tables into consistency lock and -no-lock
+// into consistency none. Both name a mode, so they exclude each other,
+// single-transaction and a different -consistency.
+func (opt *Options) checkLockOptions(ctx context.Context) error {
+	if !opt.lockTables && !opt.noLock {
+		return nil
+	}
+	if opt.lockTables && opt.noLock {
+		return moerr.NewInvalidInput(ctx, "lock-tables and no-lock can not be used together")
+	}
+	name, mode := "lock-tables", consistencyLock
+	if opt.noLock {
+		name, mode = "no-lock", consistencyNone
+	}
+	if opt.singleTransaction {
+		return moerr.NewInvalidInput(ctx, "%s can not be used with single-transaction", name)
+	}
+	if flagSet("consistency") && opt.consistency != mode {
+		return moerr.NewInvalidInput(ctx, "%s reads with consistency %s, it can not be used with consistency %s", name, mode, opt.consistency)
+	}
+	opt.consistency = mode
+	return nil
+}
+
+// checkSessionOptions rejects the options which read on more than one
+// connection, unless the consistency is none. The other modes hold the
+// transaction or the locks of the dump in the session of one connection.
+func (opt *Options) checkSessionOptions(ctx context.Context) error {
+	if opt.consistency == consistencyNone {
+		return nil
+	}
+	switch {
+	case opt.parallel > 1:
+		return moerr.NewInvalidInput(ctx, "parallel requires consistency %s, the other modes read through one session", consistencyNone)
+	case opt.retries > 0:
+		return moerr.NewInvalidInput(ctx, "retries requires consistency %s, a new connection has neither the transaction nor the locks of the dump", consistencyNone)
+	case opt.parallelSchemaFetch > 1:
+		return moerr.NewInvalidInput(ctx, "parallel-schema-fetch requires consistency %s, the other modes read through one session", consistencyNone)
+	case opt.chunkTable != nil:
+		return moerr.NewInvalidInput(ctx, "chunk-table requires consistency %s, the other modes read through one session", consistencyNone)
+	}
+	return nil
+}
+
+// probeConsistency checks once if the server supports the statement of the
+// mode. The probe runs on a dedicated connection and releases whatever it
+// acquires, so it has no effect on the data.
+func probeConsistency(ctx context.Context, mode string) error {
+	if mode == consistencyNone {
+		return nil
+	}
+	c, err := conn.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	switch mode {
+	case consistencySnapshot:
+		tx, err := c.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		return tx.Rollback()
+	case consistencyLock:
+		_, err = c.ExecContext(ctx, "LOCK TABLES mo_catalog.mo_database READ")
+		if err != nil {
+			return err
+		}
+		_, err = c.ExecContext(ctx, "UNLOCK TABLES")
+		return err
+	case consistencyFlush:
+		_, err = c.ExecContext(ctx, "FLUSH TABLES WITH READ LOCK")
+		if err != nil {
+			return err
+		}
+		_, err = c.ExecContext(ctx, "UNLOCK TABLES")
+		return err
+	}
+	return nil
+}
+
+// resolveConsistency returns the strongest supported mode starting from the
+// requested one. Without fallback, an unsupported mode is reported as error.
+func resolveConsistency(ctx context.Context, mode string, fallback bool) (string, error) {
+	for {
+		err := probeConsistency(ctx, mode)
+		if err == nil {
+			return mode, nil
+		}
+		if !fallback {
+			return "", moerr.NewNotSupported(ctx, "consistency %s requires %s which the server rejects: %v", mode, consistencyCapability[mode], err)
+		}
+		next := consistencyFallback[mode]
+		fmt.Fprintf(os.Stderr, "server does not support %s, fall back to consistency %s\n", consistencyCapability[mode], next)
+		mode = next
+	}
+}
+
+// beginConsistency pins the connection of the dump and starts the dump
+// wide part of the mode on it
+func beginConsistency(ctx context.Context, mode string) error {
+	if mode == consistencyNone {
+		return nil
+	}
+	c, err := conn.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	pinned = &pinnedConn{Conn: c}
+	switch mode {
+	case consistencySnapshot:
+		_, err = pinned.ExecContext(ctx, "START TRANSACTION")
+	case consistencyFlush:
+		_, err = pinned.ExecContext(ctx, "FLUSH TABLES WITH READ LOCK")
+	}
+	if err != nil {
+		pinned.Close()
+		pinned = nil
+	}
+	return err
+}
+
+// endConsistency ends the mode and gives the pinned connection back to the pool
+func endConsistency(ctx context.Context, mode string) error {
+	if pinned == nil {
+		return nil
+	}
+	var err error
+	switch mode {
+	case consistencySnapshot:
+		_, err = pinned.ExecContext(ctx, "COMMIT")
+	case consistencyFlush:
+		_, err = pinned.ExecContext(ctx, "UNLOCK TABLES")
+	}
+	if e := pinned.Close(); e != nil && err == nil {
+		err = e
+	}
+	pinned = nil
+	return err
+}
+
+// lockTables takes read locks on the ordinary tables of the database
+func lockTables(ctx context.Context, db string, tables Tables) error {
+	var locks []string
+	for _, tbl := range tables {
+		if tbl.Kind == catalog.SystemOrdinaryRel {
+			locks = append(locks, quoteIdent(db)+"."+quoteIdent(tbl.Name)+" READ")
+		}
+	}
+	if len(locks) == 0 {
+		return nil
+	}
+	_, err := session(ctx).ExecContext(ctx, "LOCK TABLES "+strings.Join(locks, ", "))
+	return err
+}
+
+func unlockTables(ctx context.Context) error {
+	_, err := session(ctx).ExecContext(ctx, "UNLOCK TABLES")
+	return err
+}
+
+// capturePosition returns the logical timestamp of the cluster, which a CDC
+// consumer can start from to continue right after the dumped snapshot
+func capturePosition(ctx context.Context) (string, error) {
+	var pos string
+	query := "select mo_ctl('cn', 'GetSnapshot', '')"
+	err := connRetry.queryRow(ctx, query, query, &pos)
+	if err != nil {
+		return "", err
+	}
+	return pos, nil
+}
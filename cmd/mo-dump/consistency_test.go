@@ -0,0 +1,257 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckConsistency(t *testing.T) {
+	ctx := context.Background()
+	for _, mode := range []string{consistencyNone, consistencySnapshot, consistencyLock, consistencyFlush} {
+		require.NoError(t, checkConsistency(ctx, mode))
+	}
+	require.Error(t, checkConsistency(ctx, "serializable"))
+}
+
+func TestResolveConsistency(t *testing.T) {
+	ctx := context.Background()
+	unsupported := fmt.Errorf("syntax error")
+
+	t.Run("supported", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		conn = db
+
+		mock.ExpectExec("FLUSH TABLES WITH READ LOCK").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("UNLOCK TABLES").WillReturnResult(sqlmock.NewResult(0, 0))
+		mode, err := resolveConsistency(ctx, consistencyFlush, true)
+		require.NoError(t, err)
+		require.Equal(t, consistencyFlush, mode)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("lock", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		conn = db
+
+		mock.ExpectExec("LOCK TABLES mo_catalog.mo_database READ").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("UNLOCK TABLES").WillReturnResult(sqlmock.NewResult(0, 0))
+		mode, err := resolveConsistency(ctx, consistencyLock, false)
+		require.NoError(t, err)
+		require.Equal(t, consistencyLock, mode)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		conn = db
+
+		mock.ExpectExec("FLUSH TABLES WITH READ LOCK").WillReturnError(unsupported)
+		mock.ExpectExec("LOCK TABLES mo_catalog.mo_database READ").WillReturnError(unsupported)
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+		mode, err := resolveConsistency(ctx, consistencyFlush, true)
+		require.NoError(t, err)
+		require.Equal(t, consistencySnapshot, mode)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("fallback to none", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		conn = db
+
+		mock.ExpectBegin().WillReturnError(unsupported)
+		mode, err := resolveConsistency(ctx, consistencySnapshot, true)
+		require.NoError(t, err)
+		require.Equal(t, consistencyNone, mode)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("no fallback", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		conn = db
+
+		mock.ExpectExec("LOCK TABLES mo_catalog.mo_database READ").WillReturnError(unsupported)
+		_, err = resolveConsistency(ctx, consistencyLock, false)
+		require.Error(t, err)
+		require.True(t, strings.Contains(err.Error(), "LOCK TABLES"))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestBeginConsistencyPinsConnection(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	ctx := context.Background()
+	mock.ExpectExec("START TRANSACTION").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("COMMIT").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	require.NoError(t, beginConsistency(ctx, consistencySnapshot))
+	require.Equal(t, queryer(pinned), session(ctx))
+	require.Equal(t, 1, db.Stats().InUse)
+
+	require.NoError(t, endConsistency(ctx, consistencySnapshot))
+	require.Nil(t, pinned)
+	require.Equal(t, queryer(db), session(ctx))
+	require.Equal(t, 0, db.Stats().InUse)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPinnedConnPingIdle(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	ctx := context.Background()
+	mock.ExpectExec("START TRANSACTION").WillReturnResult(sqlmock.NewResult(0, 0))
+	require.NoError(t, beginConsistency(ctx, consistencySnapshot))
+	ping := keepAlivePing()
+
+	mock.ExpectQuery("select").WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow(1).AddRow(2))
+	r, err := session(ctx).QueryContext(ctx, "select a from t")
+	require.NoError(t, err)
+	// the connection was used since the last ping
+	require.NoError(t, ping(ctx))
+	// the rows are still being read
+	require.True(t, r.Next())
+	require.NoError(t, ping(ctx))
+	require.NoError(t, r.Close())
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// idle now, the pinned connection is pinged
+	mock.ExpectPing()
+	require.NoError(t, ping(ctx))
+	require.Empty(t, pinned.rows)
+
+	mock.ExpectExec("COMMIT").WillReturnResult(sqlmock.NewResult(0, 0))
+	require.NoError(t, endConsistency(ctx, consistencySnapshot))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCheckLockOptions(t *testing.T) {
+	ctx := context.Background()
+	opt := Options{consistency: consistencySnapshot}
+	require.NoError(t, opt.checkLockOptions(ctx))
+	require.Equal(t, consistencySnapshot, opt.consistency)
+
+	opt = Options{consistency: consistencyNone, lockTables: true}
+	require.NoError(t, opt.checkLockOptions(ctx))
+	require.Equal(t, consistencyLock, opt.consistency)
+
+	opt = Options{consistency: consistencySnapshot, noLock: true}
+	require.NoError(t, opt.checkLockOptions(ctx))
+	require.Equal(t, consistencyNone, opt.consistency)
+
+	for _, opt := range []Options{
+		{lockTables: true, noLock: true},
+		{lockTables: true, singleTransaction: true},
+		{noLock: true, singleTransaction: true},
+	} {
+		require.Error(t, opt.checkLockOptions(ctx))
+	}
+}
+
+func TestLockTables(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	ctx := context.Background()
+	tables := Tables{{"t1", "r"}, {"v1", "v"}, {"t2", "r"}}
+	mock.ExpectExec("LOCK TABLES `db1`.`t1` READ, `db1`.`t2` READ").WillReturnResult(sqlmock.NewResult(0, 0))
+	require.NoError(t, lockTables(ctx, "db1", tables))
+	require.NoError(t, lockTables(ctx, "db1", Tables{{"v1", "v"}}))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCheckSessionOptions(t *testing.T) {
+	ctx := context.Background()
+	opt := Options{consistency: consistencyNone, parallel: 4, parallelSchemaFetch: 4, retries: 3, chunkTable: &chunkTable{table: "t1", column: "id", chunks: 4}}
+	require.NoError(t, opt.checkSessionOptions(ctx))
+
+	opt = Options{consistency: consistencySnapshot, parallel: 1, parallelSchemaFetch: 1}
+	require.NoError(t, opt.checkSessionOptions(ctx))
+
+	opt.parallel = 4
+	require.ErrorContains(t, opt.checkSessionOptions(ctx), "parallel requires consistency none")
+	opt.parallel = 1
+	opt.parallelSchemaFetch = 4
+	require.ErrorContains(t, opt.checkSessionOptions(ctx), "parallel-schema-fetch requires consistency none")
+	opt.parallelSchemaFetch = 1
+	opt.retries = 3
+	require.ErrorContains(t, opt.checkSessionOptions(ctx), "retries requires consistency none")
+	opt.retries = 0
+	opt.chunkTable = &chunkTable{table: "t1", column: "id", chunks: 4}
+	opt.consistency = consistencyLock
+	require.ErrorContains(t, opt.checkSessionOptions(ctx), "chunk-table requires consistency none")
+}
+
+func TestCapturePosition(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	ctx := context.Background()
+	opt := Options{
+		dbs:             []string{"db1"},
+		tables:          Tables{{"t1", ""}},
+		netBufferLength: defaultNetBufferLength,
+		format:          formatSQL,
+		consistency:     consistencySnapshot,
+		capturePosition: true,
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+	mock.ExpectExec("START TRANSACTION").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("mo_ctl").WillReturnRows(sqlmock.NewRows([]string{"pos"}).AddRow("1697328000000000000-1"))
+	mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables").
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).AddRow("t1", "r"))
+	mock.ExpectQuery("show create table").
+		WillReturnRows(sqlmock.NewRows([]string{"Table", "Create"}).AddRow("t1", "create table t1 (a int)"))
+	mock.ExpectQuery("select \\* from `db1`.`t1`").
+		WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("1"))
+	mock.ExpectExec("COMMIT").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	out := captureStdout(t, func() {
+		err = opt.dumpData(ctx)
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Equal(t, 1, strings.Count(out, "MODUMP POSITION"))
+	require.True(t, strings.HasPrefix(out, "/* MODUMP POSITION: 1697328000000000000-1 */\n"))
+	require.Less(t, strings.Index(out, "MODUMP POSITION"), strings.Index(out, "INSERT INTO"))
+}
@@ -0,0 +1,94 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// csvRecordWriter is implemented by csv.Writer and quoteAllWriter
+type csvRecordWriter interface {
+	Write(record []string) error
+	Flush()
+	Error() error
+}
+
+// nullRecordWriter is implemented by the writers telling NULL from the
+// string \N. nulls marks the NULL fields of record, which are written as
+// \N, while a string \N is escaped. nulls may be nil for a record without
+// NULL.
+type nullRecordWriter interface {
+	writeRow(record []string, nulls []bool) error
+}
+
+// quoteAllEscaper escapes the backslashes of a field with the backslash of
+// the default ESCAPED BY of LOAD DATA, and doubles its double quotes
+var quoteAllEscaper = strings.NewReplacer(`\`, `\\`, `"`, `""`)
+
+// quoteAllWriter writes records like csv.Writer, but encloses every field
+// in double quotes instead of only the fields which need it. Leading and
+// trailing spaces and empty strings are kept by any reader this way.
+// NULL is written as \N without quotes. Every backslash in a field is
+// escaped like tsvEscaper does, so a string \N is written as "\\N" and
+// differs from NULL, and a string \\N as "\\\\N".
+type quoteAllWriter struct {
+	w     *bufio.Writer
+	comma rune
+	err   error
+}
+
+func newQuoteAllWriter(w io.Writer, comma rune) *quoteAllWriter {
+	return &quoteAllWriter{
+		w:     bufio.NewWriter(w),
+		comma: comma,
+	}
+}
+
+func (w *quoteAllWriter) Write(record []string) error {
+	return w.writeRow(record, nil)
+}
+
+func (w *quoteAllWriter) writeRow(record []string, nulls []bool) error {
+	if w.err != nil {
+		return w.err
+	}
+	for i, field := range record {
+		if i > 0 {
+			w.w.WriteRune(w.comma)
+		}
+		if nulls != nil && nulls[i] {
+			w.w.Write(nullBytes)
+			continue
+		}
+		w.w.WriteByte('"')
+		quoteAllEscaper.WriteString(w.w, field)
+		w.w.WriteByte('"')
+	}
+	// bufio.Writer keeps the first error, the last write returns it
+	w.err = w.w.WriteByte('\n')
+	return w.err
+}
+
+func (w *quoteAllWriter) Flush() {
+	if w.err == nil {
+		w.err = w.w.Flush()
+	}
+}
+
+func (w *quoteAllWriter) Error() error {
+	return w.err
+}
@@ -0,0 +1,176 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"encoding/csv"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuoteAllWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := newQuoteAllWriter(&buf, ',')
+	require.NoError(t, w.writeRow([]string{" a ", "", "\\N", "x\"y", "1"}, []bool{false, false, true, false, false}))
+	require.NoError(t, w.Write([]string{"\tb", "c,d"}))
+	require.NoError(t, w.Write([]string{"\\N", "\\\\N", "a\\b"}))
+	w.Flush()
+	require.NoError(t, w.Error())
+	require.Equal(t, "\" a \",\"\",\\N,\"x\"\"y\",\"1\"\n\"\tb\",\"c,d\"\n\"\\\\N\",\"\\\\\\\\N\",\"a\\\\b\"\n", buf.String())
+
+	// the output reads back with the same fields and NULLs
+	r := csv.NewReader(&buf)
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	require.NoError(t, err)
+	var fields [][]string
+	var nulls [][]bool
+	for _, record := range records {
+		f, n := unescapeQuoteAll(record)
+		fields = append(fields, f)
+		nulls = append(nulls, n)
+	}
+	require.Equal(t, [][]string{{" a ", "", "", "x\"y", "1"}, {"\tb", "c,d"}, {"\\N", "\\\\N", "a\\b"}}, fields)
+	require.Equal(t, [][]bool{{false, false, true, false, false}, {false, false}, {false, false, false}}, nulls)
+}
+
+// unescapeQuoteAll reads the fields of a record of quoteAllWriter back the
+// way LOAD DATA does with the default ESCAPED BY
+func unescapeQuoteAll(record []string) ([]string, []bool) {
+	fields := make([]string, len(record))
+	nulls := make([]bool, len(record))
+	for i, field := range record {
+		if field == string(nullBytes) {
+			nulls[i] = true
+			continue
+		}
+		fields[i] = strings.ReplaceAll(field, "\\\\", "\\")
+	}
+	return fields, nulls
+}
+
+func TestToCsvQuoteAll(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "name", "note"}).
+		AddRow("1", "  leading", "trailing  ").
+		AddRow("2", "", nil).
+		AddRow("3", "\\N", "").
+		AddRow("4", "\\\\N", "a\\b")
+	mock.ExpectQuery("select").WillReturnRows(rows)
+	r, err := db.Query("select")
+	require.NoError(t, err)
+	defer r.Close()
+
+	cols := []*Column{
+		{Name: "id", Type: "INT"},
+		{Name: "name", Type: "VARCHAR"},
+		{Name: "note", Type: "VARCHAR"},
+	}
+	rowResults := make([]any, 0, len(cols))
+	for range cols {
+		var v sql.RawBytes
+		rowResults = append(rowResults, &v)
+	}
+	var out bytes.Buffer
+	err = toCsv(r, &out, "t", rowResults, cols, &csvConfig{enable: true, fieldDelimiter: '\t', quoteAll: true})
+	require.NoError(t, err)
+	// every backslash is escaped, the string \N does not load as NULL and
+	// differs from the string \\N
+	require.Equal(t, "\"1\"\t\"  leading\"\t\"trailing  \"\n\"2\"\t\"\"\t\\N\n\"3\"\t\"\\\\N\"\t\"\"\n\"4\"\t\"\\\\\\\\N\"\t\"a\\\\b\"\n", out.String())
+
+	cr := csv.NewReader(&out)
+	cr.Comma = '\t'
+	records, err := cr.ReadAll()
+	require.NoError(t, err)
+	var names, notes []string
+	for _, record := range records {
+		fields, nulls := unescapeQuoteAll(record)
+		require.False(t, nulls[1])
+		names = append(names, fields[1])
+		if nulls[2] {
+			notes = append(notes, "NULL")
+		} else {
+			notes = append(notes, fields[2])
+		}
+	}
+	require.Equal(t, []string{"  leading", "", "\\N", "\\\\N"}, names)
+	require.Equal(t, []string{"trailing  ", "NULL", "", "a\\b"}, notes)
+}
+
+func TestLoadDataStmt(t *testing.T) {
+	conf := &csvConfig{enable: true, fieldDelimiter: ','}
+	require.Equal(t, "LOAD DATA LOCAL INFILE '/tmp/db1_t1.csv' INTO TABLE `t1` FIELDS TERMINATED BY '\\t' ENCLOSED BY '\"' LINES TERMINATED BY '\\n' PARALLEL 'FALSE';",
+		loadDataStmt("/tmp/db1_t1.csv", "t1", true, conf, nil))
+	require.Equal(t, "LOAD DATA INFILE '/tmp/db1_t1.csv' INTO TABLE `t1` FIELDS TERMINATED BY '\\t' ENCLOSED BY '\"' LINES TERMINATED BY '\\n' PARALLEL 'FALSE';",
+		loadDataStmt("/tmp/db1_t1.csv", "t1", false, conf, nil))
+
+	conf.compress = csvCompressGzip
+	require.Equal(t, "LOAD DATA LOCAL INFILE {'filepath'='/tmp/db1_t1.csv.gz', 'compression'='gzip'} INTO TABLE `t1` FIELDS TERMINATED BY '\\t' ENCLOSED BY '\"' LINES TERMINATED BY '\\n' PARALLEL 'FALSE';",
+		loadDataStmt("/tmp/db1_t1.csv.gz", "t1", true, conf, nil))
+	require.Equal(t, "LOAD DATA INFILE {'filepath'='/tmp/db1_t1.csv.gz', 'compression'='gzip'} INTO TABLE `t1` FIELDS TERMINATED BY '\\t' ENCLOSED BY '\"' LINES TERMINATED BY '\\n' PARALLEL 'FALSE';",
+		loadDataStmt("/tmp/db1_t1.csv.gz", "t1", false, conf, nil))
+
+	// the path of names with quotes or backslashes stays one literal
+	require.Equal(t, "LOAD DATA INFILE {'filepath'='/tmp/it\\'s_a\\\\b.csv.gz', 'compression'='gzip'} INTO TABLE `a\\b` FIELDS TERMINATED BY '\\t' ENCLOSED BY '\"' LINES TERMINATED BY '\\n' PARALLEL 'FALSE';",
+		loadDataStmt("/tmp/it's_a\\b.csv.gz", "a\\b", false, conf, nil))
+	conf.compress = ""
+	require.Equal(t, "LOAD DATA LOCAL INFILE '/tmp/it\\'s_a\\\\b.csv' INTO TABLE `a\\b` FIELDS TERMINATED BY '\\t' ENCLOSED BY '\"' LINES TERMINATED BY '\\n' PARALLEL 'FALSE';",
+		loadDataStmt("/tmp/it's_a\\b.csv", "a\\b", true, conf, nil))
+}
+
+func TestShowLoadGzip(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	defer os.Chdir(wd)
+
+	mock.ExpectQuery("select").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow("1", "a").AddRow("2", "b"))
+	r, err := db.Query("select")
+	require.NoError(t, err)
+	defer r.Close()
+
+	cols := []*Column{{Name: "id", Type: "INT"}, {Name: "name", Type: "VARCHAR"}}
+	rowResults := []any{new(sql.RawBytes), new(sql.RawBytes)}
+	fnames, err := showLoad(r, rowResults, cols, "db1", "t1", &csvConfig{enable: true, fieldDelimiter: ',', compress: csvCompressGzip})
+	require.NoError(t, err)
+	require.Equal(t, []string{"db1_t1.csv.gz"}, fnames)
+
+	_, err = os.Stat(filepath.Join(dir, "db1_t1.csv"))
+	require.True(t, os.IsNotExist(err))
+	f, err := os.Open(filepath.Join(dir, "db1_t1.csv.gz"))
+	require.NoError(t, err)
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	data, err := io.ReadAll(gr)
+	require.NoError(t, err)
+	require.Equal(t, "1,a\n2,b\n", string(data))
+}
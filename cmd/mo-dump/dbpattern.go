@@ -0,0 +1,137 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	"github.com/matrixorigin/matrixone/pkg/common/moerr"
+)
+
+// isPattern reports if name is a shell style glob rather than a plain name.
+// A backslash escapes the next character, so t\[1\] is a glob matching the
+// name t[1] only.
+func isPattern(name string) bool {
+	return strings.ContainsAny(name, "*?[\\")
+}
+
+// exactPatterns splits the patterns into the globs and the ones which are
+// the very name of one of names. Such a pattern selects that name alone, so
+// a table or a database named like t[1] can be given without escapes.
+func exactPatterns(patterns []string, names []string) ([]string, map[string]bool) {
+	existing := make(map[string]bool, len(names))
+	for _, name := range names {
+		existing[name] = true
+	}
+	var globs []string
+	exact := make(map[string]bool)
+	for _, p := range patterns {
+		if existing[p] {
+			exact[p] = true
+		} else {
+			globs = append(globs, p)
+		}
+	}
+	return globs, exact
+}
+
+// parsePatterns splits the comma separated globs of flag and checks their
+// syntax, so a bad pattern fails before the connection is opened
+func parsePatterns(ctx context.Context, flag, spec string) ([]string, error) {
+	var patterns []string
+	for _, p := range strings.Split(spec, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, moerr.NewInvalidInput(ctx, "invalid %s pattern %s: %v", flag, p, err)
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns, nil
+}
+
+// matchAny reports if name matches one of the patterns
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// expandsDatabases reports if the databases of -db are only known once the
+// connection is open: -db all, a glob or -exclude-database
+func (opt *Options) expandsDatabases() bool {
+	if opt.excludeDatabases != nil {
+		return true
+	}
+	for _, db := range opt.dbs {
+		if db == "all" || isPattern(db) {
+			return true
+		}
+	}
+	return false
+}
+
+// expandDatabases replaces the globs of requested by the databases of the
+// server they match, in the order of show databases, and drops the ones
+// matching -exclude-database. all is an alias for *. Plain names, and globs
+// which are the name of a database, are kept as given, a missing database
+// fails when it is dumped.
+func (opt *Options) expandDatabases(ctx context.Context, requested []string) ([]string, error) {
+	var all []string
+	for _, db := range requested {
+		if db == "all" || isPattern(db) {
+			var err error
+			all, err = getDatabases(ctx)
+			if err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	seen := make(map[string]bool)
+	var dbs []string
+	add := func(db string) {
+		if !seen[db] && !matchAny(opt.excludeDatabases, db) {
+			seen[db] = true
+			dbs = append(dbs, db)
+		}
+	}
+	_, exact := exactPatterns(requested, all)
+	for _, p := range requested {
+		if p == "all" {
+			p = "*"
+		}
+		if !isPattern(p) || exact[p] {
+			add(p)
+			continue
+		}
+		for _, db := range all {
+			if ok, _ := path.Match(p, db); ok {
+				add(db)
+			}
+		}
+	}
+	if len(dbs) == 0 {
+		return nil, moerr.NewInvalidInput(ctx, "no database matches -db %s", strings.Join(requested, ","))
+	}
+	return dbs, nil
+}
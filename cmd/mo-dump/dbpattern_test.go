@@ -0,0 +1,90 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePatterns(t *testing.T) {
+	ctx := context.Background()
+	patterns, err := parsePatterns(ctx, "exclude-database", "app_test*, tmp_?,")
+	require.NoError(t, err)
+	require.Equal(t, []string{"app_test*", "tmp_?"}, patterns)
+
+	_, err = parsePatterns(ctx, "exclude-database", "app_[")
+	require.ErrorContains(t, err, "invalid exclude-database pattern app_[")
+}
+
+func TestExpandsDatabases(t *testing.T) {
+	require.False(t, (&Options{dbs: []string{"db1", "db2"}}).expandsDatabases())
+	require.True(t, (&Options{dbs: []string{"all"}}).expandsDatabases())
+	require.True(t, (&Options{dbs: []string{"db1", "app_*"}}).expandsDatabases())
+	require.True(t, (&Options{dbs: []string{`db\[1\]`}}).expandsDatabases())
+	require.True(t, (&Options{dbs: []string{"db1"}, excludeDatabases: []string{"x"}}).expandsDatabases())
+}
+
+func TestExpandDatabases(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+	ctx := context.Background()
+	databases := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"Database"}).
+			AddRow("app_a").AddRow("app_test1").AddRow("app_b").AddRow("mo_catalog").AddRow("other")
+	}
+
+	opt := Options{excludeDatabases: []string{"app_test*"}}
+	mock.ExpectQuery("show databases").WillReturnRows(databases())
+	dbs, err := opt.expandDatabases(ctx, []string{"other", "app_*", "app_b"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"other", "app_a", "app_b"}, dbs)
+
+	// all is an alias for *
+	opt = Options{excludeDatabases: []string{"mo_*"}}
+	mock.ExpectQuery("show databases").WillReturnRows(databases())
+	dbs, err = opt.expandDatabases(ctx, []string{"all"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"app_a", "app_test1", "app_b", "other"}, dbs)
+
+	// plain names do not list the databases
+	opt = Options{excludeDatabases: []string{"db2"}}
+	dbs, err = opt.expandDatabases(ctx, []string{"db1", "db2"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"db1"}, dbs)
+
+	// a glob which is the name of a database selects it alone, an escaped
+	// one matches the name
+	named := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"Database"}).AddRow("db1").AddRow("db[1]").AddRow("db2")
+	}
+	mock.ExpectQuery("show databases").WillReturnRows(named())
+	dbs, err = (&Options{}).expandDatabases(ctx, []string{"db[1]"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"db[1]"}, dbs)
+	mock.ExpectQuery("show databases").WillReturnRows(named())
+	dbs, err = (&Options{}).expandDatabases(ctx, []string{`db\[1\]`})
+	require.NoError(t, err)
+	require.Equal(t, []string{"db[1]"}, dbs)
+
+	mock.ExpectQuery("show databases").WillReturnRows(databases())
+	_, err = (&Options{}).expandDatabases(ctx, []string{"nope_*"})
+	require.ErrorContains(t, err, "no database matches -db nope_*")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
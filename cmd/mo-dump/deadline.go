@@ -0,0 +1,48 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// withDeadline returns the context of the data queries, which is done once
+// the deadline of the dump is exceeded
+func (opt *Options) withDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if opt.deadline <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, opt.deadline)
+}
+
+// deadlineExceeded reports if the deadline of the dump has passed. The
+// first time, the end of the dump is marked after the last finished table.
+func (opt *Options) deadlineExceeded(ctx context.Context) bool {
+	if opt.deadline <= 0 || ctx.Err() == nil {
+		return false
+	}
+	if !opt.truncated {
+		opt.truncated = true
+		last := "<none>"
+		if opt.lastTable != "" {
+			last = opt.lastTable
+		}
+		fmt.Fprintf(opt.stdout(), "/* DUMP TRUNCATED: deadline exceeded after table %s */\n", last)
+		fmt.Fprintf(os.Stderr, "deadline %v exceeded, the dump is incomplete after table %s\n", opt.deadline, last)
+	}
+	return true
+}
@@ -0,0 +1,96 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpDataDeadline(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	ctx := context.Background()
+	opt := Options{
+		dbs:             []string{"db1", "db2"},
+		emptyTables:     true,
+		truncate:        true,
+		netBufferLength: defaultNetBufferLength,
+		format:          formatSQL,
+		consistency:     consistencyNone,
+		deadline:        100 * time.Millisecond,
+		stamp:           stamp{table: "dumps", version: "v1"},
+	}
+	mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables").
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).
+			AddRow("t1", "r").
+			AddRow("t2", "r").
+			AddRow("t3", "r"))
+	for _, tbl := range []string{"t1", "t2", "t3"} {
+		mock.ExpectQuery("show create table").
+			WillReturnRows(sqlmock.NewRows([]string{"Table", "Create"}).AddRow(tbl, "create table "+tbl+" (a int)"))
+	}
+	mock.ExpectQuery("select \\* from `db1`.`t1`").
+		WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("1"))
+	// the slow query is canceled at the deadline
+	mock.ExpectQuery("select \\* from `db1`.`t2`").
+		WillDelayFor(time.Minute).
+		WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("2"))
+	start := time.Now()
+	out := captureStdout(t, func() {
+		err = opt.dumpData(ctx)
+	})
+	require.NoError(t, err)
+	require.Less(t, time.Since(start), 10*time.Second)
+	require.True(t, opt.truncated)
+	require.Equal(t, "USE `db1`;\n\n\n"+
+		"TRUNCATE TABLE `t1`;\nINSERT INTO `t1` VALUES (1);\n\n\n\n"+
+		"TRUNCATE TABLE `t2`;\n"+
+		"/* DUMP TRUNCATED: deadline exceeded after table `db1`.`t1` */\n", out)
+	require.Equal(t, 1, opt.dumpedObjects)
+	// neither the next table nor the next database is dumped
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// a dump within the deadline is complete
+	opt = Options{
+		dbs:             []string{"db1"},
+		tables:          Tables{{"t1", ""}},
+		truncate:        true,
+		netBufferLength: defaultNetBufferLength,
+		format:          formatSQL,
+		consistency:     consistencyNone,
+		deadline:        time.Minute,
+	}
+	mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables").
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).AddRow("t1", "r"))
+	mock.ExpectQuery("show create table").
+		WillReturnRows(sqlmock.NewRows([]string{"Table", "Create"}).AddRow("t1", "create table t1 (a int)"))
+	mock.ExpectQuery("select \\* from `db1`.`t1`").
+		WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("1"))
+	out = captureStdout(t, func() {
+		err = opt.dumpData(ctx)
+	})
+	require.NoError(t, err)
+	require.False(t, opt.truncated)
+	require.NotContains(t, out, "TRUNCATED")
+	require.Equal(t, "`db1`.`t1`", opt.lastTable)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,31 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"database/sql"
+)
+
+// isEmptyTable reports if the table has no rows. Reading one row is cheap
+// for any size of table, unlike counting them.
+func isEmptyTable(ctx context.Context, db, tbl string) (bool, error) {
+	var one int
+	err := session(ctx).QueryRowContext(ctx, "select 1 from "+quoteIdent(db)+"."+quoteIdent(tbl)+" limit 1").Scan(&one)
+	if err == sql.ErrNoRows {
+		return true, nil
+	}
+	return false, err
+}
@@ -0,0 +1,86 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpDataSkipEmpty(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	ctx := context.Background()
+	expect := func() {
+		mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables").
+			WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).AddRow("t1", "r").AddRow("t2", "r"))
+		for _, tbl := range []string{"t1", "t2"} {
+			mock.ExpectQuery("show create table").
+				WillReturnRows(sqlmock.NewRows([]string{"Table", "Create"}).AddRow(tbl, "create table "+tbl+" (a int)"))
+		}
+		mock.ExpectQuery("select 1 from `db1`.`t1` limit 1").
+			WillReturnRows(sqlmock.NewRows([]string{"1"}))
+		mock.ExpectQuery("select 1 from `db1`.`t2` limit 1").
+			WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow("1"))
+	}
+
+	// the empty table is skipped entirely
+	opt := Options{
+		dbs:             []string{"db1"},
+		netBufferLength: defaultNetBufferLength,
+		format:          formatSQL,
+		consistency:     consistencyNone,
+		skipEmptyTables: true,
+	}
+	expect()
+	mock.ExpectQuery("select \\* from `db1`.`t2`").
+		WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("2"))
+	var out string
+	warn := captureStderr(t, func() {
+		out = captureStdout(t, func() {
+			err = opt.dumpData(ctx)
+		})
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Equal(t, "DROP TABLE IF EXISTS `t2`;\ncreate table t2 (a int);\nINSERT INTO `t2` VALUES (2);\n\n\n\n", out)
+	require.Equal(t, "skip empty table `db1`.`t1`\n", warn)
+	require.Equal(t, 1, opt.dumpedObjects)
+
+	// the DDL of the empty table is kept without its data
+	opt = Options{
+		dbs:             []string{"db1"},
+		netBufferLength: defaultNetBufferLength,
+		format:          formatSQL,
+		consistency:     consistencyNone,
+		skipEmptyData:   true,
+	}
+	expect()
+	mock.ExpectQuery("select \\* from `db1`.`t2`").
+		WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("2"))
+	out = captureStdout(t, func() {
+		err = opt.dumpData(ctx)
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Equal(t, "DROP TABLE IF EXISTS `t1`;\ncreate table t1 (a int);\n\n\n"+
+		"DROP TABLE IF EXISTS `t2`;\ncreate table t2 (a int);\nINSERT INTO `t2` VALUES (2);\n\n\n\n", out)
+	require.Equal(t, 2, opt.dumpedObjects)
+}
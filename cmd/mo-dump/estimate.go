@@ -0,0 +1,162 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/matrixorigin/matrixone/pkg/catalog"
+)
+
+// estimateSampleRows is how many rows -estimate-size writes of each table
+const estimateSampleRows = 100
+
+// sizeCounter counts the bytes and the lines written to it
+type sizeCounter struct {
+	bytes int64
+	lines int64
+}
+
+func (c *sizeCounter) Write(p []byte) (int, error) {
+	c.bytes += int64(len(p))
+	c.lines += int64(bytes.Count(p, []byte("\n")))
+	return len(p), nil
+}
+
+// countedRows counts the rows read from a rowIterator
+type countedRows struct {
+	rowIterator
+	rows int64
+}
+
+func (r *countedRows) Next() bool {
+	if r.rowIterator.Next() {
+		r.rows++
+		return true
+	}
+	return false
+}
+
+// showEstimate estimates the size of the data of every dumped table,
+// without dumping anything. A sample of each table is written as INSERT
+// statements or csv, as the dump would, and the average size of its rows
+// is scaled to the row count of the table metadata. Row filters such as
+// -where are not applied.
+func (opt *Options) showEstimate(ctx context.Context, w io.Writer) (err error) {
+	if conn == nil {
+		conn, err = opt.openDBConnection(ctx, opt.dbs[0])
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "DATABASE\tTABLE\tROWS\tROW_BYTES\tBYTES\n")
+	var totalRows, totalSize int64
+	requested := opt.tables
+	for _, db := range opt.dbs {
+		tables := append(Tables(nil), requested...)
+		tables, err = getTables(ctx, db, tables, opt.ignoreTables, opt.skipMissingTables, opt.allowEmptyMatch, opt.includeTemporary)
+		if err != nil {
+			return err
+		}
+		tables, err = opt.filterTableKinds(ctx, db, tables)
+		if err != nil {
+			return err
+		}
+		var counts map[string]tableCount
+		counts, err = getTableCounts(ctx, db)
+		if err != nil {
+			return err
+		}
+		for _, tbl := range tables {
+			if tbl.Kind != catalog.SystemOrdinaryRel {
+				continue
+			}
+			rows := counts[tbl.Name].rows
+			var rowSize float64
+			var size int64
+			rows, rowSize, size, err = opt.estimateTable(ctx, db, tbl.Name, rows)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%.1f\t%d\n", db, tbl.Name, rows, rowSize, size)
+			totalRows += rows
+			totalSize += size
+		}
+	}
+	fmt.Fprintf(tw, "TOTAL\t\t%d\t\t%d\n", totalRows, totalSize)
+	return tw.Flush()
+}
+
+// estimateTable writes a sample of the table to a counter and returns the
+// row count, which is at least the sampled rows, the average size of a row
+// and the estimated size of the data
+func (opt *Options) estimateTable(ctx context.Context, db, tbl string, rows int64) (int64, float64, int64, error) {
+	list, err := opt.selectList(ctx, db, tbl)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	query := fmt.Sprintf("select %s from %s.%s limit %d", list, quoteIdent(db), quoteIdent(tbl), estimateSampleRows)
+	r, cols, rowResults, err := opt.openRows(ctx, []string{query}, tbl)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	defer r.Close()
+	sample := &countedRows{rowIterator: r}
+	var c sizeCounter
+	if opt.csvConf.enable {
+		err = toCsv(sample, &c, tbl, rowResults, cols, &opt.csvConf)
+	} else {
+		bufPool := &sync.Pool{
+			New: func() any {
+				return &bytes.Buffer{}
+			},
+		}
+		err = showInsert(sample, &c, rowResults, cols, tbl, bufPool, opt.netBufferLength, opt.insertBatchRows, opt.maxRowSize, opt.validateUTF8, opt.completeInsert(), opt.insertMode, -1)
+	}
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if sample.rows == 0 {
+		return rows, 0, 0, nil
+	}
+	// the metadata may lag behind the rows just written
+	if rows < sample.rows {
+		rows = sample.rows
+	}
+	if opt.csvConf.enable {
+		rowSize := float64(c.bytes) / float64(sample.rows)
+		return rows, rowSize, int64(rowSize * float64(rows)), nil
+	}
+	// every INSERT statement is one line, its head and end are written once
+	// per statement instead of once per row
+	overhead := int64(len(insertHead(tbl, cols, opt.completeInsert(), opt.insertMode)) + len(";\n"))
+	rowSize := float64(c.bytes-c.lines*overhead) / float64(sample.rows)
+	data := rowSize * float64(rows)
+	statements := int64(data)/int64(opt.netBufferLength) + 1
+	if opt.insertBatchRows > 0 {
+		if n := (rows + int64(opt.insertBatchRows) - 1) / int64(opt.insertBatchRows); n > statements {
+			statements = n
+		}
+	}
+	return rows, rowSize, int64(data) + statements*overhead, nil
+}
@@ -0,0 +1,105 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func estimateRows(n int) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"id", "name"})
+	for i := 0; i < n; i++ {
+		rows.AddRow(fmt.Sprint(i+1), strings.Repeat("x", i%20)+"-name")
+	}
+	return rows
+}
+
+func TestShowEstimate(t *testing.T) {
+	const total = 5000
+	// the size of the INSERT statements of all rows
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectQuery("select").WillReturnRows(estimateRows(total))
+	r, err := db.Query("select")
+	require.NoError(t, err)
+	cols := []*Column{{Name: "id"}, {Name: "name"}}
+	rowResults := []any{&sql.RawBytes{}, &sql.RawBytes{}}
+	bufPool := &sync.Pool{New: func() any { return &bytes.Buffer{} }}
+	var actual sizeCounter
+	require.NoError(t, showInsert(r, &actual, rowResults, cols, "t1", bufPool, 16*1024, 0, 0, "", false, "", -1))
+	r.Close()
+
+	conn = db
+	opt := Options{
+		dbs:             []string{"db1"},
+		netBufferLength: 16 * 1024,
+		format:          formatSQL,
+		estimateSize:    true,
+	}
+	mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables").
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).AddRow("t1", "r").AddRow("v1", "v"))
+	mock.ExpectQuery("select relname, mo_table_rows").
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "rows", "size"}).AddRow("t1", total, 1<<20))
+	mock.ExpectQuery("select \\* from `db1`.`t1` limit 100").WillReturnRows(estimateRows(estimateSampleRows))
+	var out bytes.Buffer
+	require.NoError(t, opt.showEstimate(context.Background(), &out))
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 3)
+	require.Equal(t, []string{"DATABASE", "TABLE", "ROWS", "ROW_BYTES", "BYTES"}, strings.Fields(lines[0]))
+	fields := strings.Fields(lines[1])
+	require.Equal(t, []string{"db1", "t1", fmt.Sprint(total)}, fields[:3])
+	var estimate int64
+	_, err = fmt.Sscan(fields[4], &estimate)
+	require.NoError(t, err)
+	ratio := float64(estimate) / float64(actual.bytes)
+	require.True(t, ratio > 0.8 && ratio < 1.25, "estimate %d, actual %d", estimate, actual.bytes)
+	require.Equal(t, []string{"TOTAL", fmt.Sprint(total), fields[4]}, strings.Fields(lines[2]))
+}
+
+func TestEstimateTableCsv(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	opt := Options{csvConf: csvConfig{enable: true, fieldDelimiter: ','}}
+	mock.ExpectQuery("select \\* from `db1`.`t1` limit 100").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow("1", "ab").AddRow("2", "cd"))
+	rows, rowSize, size, err := opt.estimateTable(context.Background(), "db1", "t1", 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(10), rows)
+	require.Equal(t, 5.0, rowSize)
+	require.Equal(t, int64(50), size)
+
+	// the metadata lags behind, the sampled rows are counted
+	mock.ExpectQuery("select \\* from `db1`.`t1` limit 100").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow("1", "ab"))
+	rows, _, size, err = opt.estimateTable(context.Background(), "db1", "t1", 0)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), rows)
+	require.Equal(t, int64(5), size)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,53 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/matrixorigin/matrixone/pkg/common/moerr"
+)
+
+// selectList returns the columns to select from the table, * unless
+// exclude-columns-regexp is set
+func (opt *Options) selectList(ctx context.Context, db, tbl string) (string, error) {
+	if opt.excludeColumns == nil {
+		return "*", nil
+	}
+	names, err := getColumnNames(ctx, db, tbl)
+	if err != nil {
+		return "", err
+	}
+	kept := make([]string, 0, len(names))
+	for _, name := range names {
+		if !opt.excludeColumns.MatchString(name) {
+			kept = append(kept, quoteIdent(name))
+		}
+	}
+	if len(kept) == 0 {
+		return "", moerr.NewInvalidInput(ctx, "all columns of table `%s`.`%s` are excluded by exclude-columns-regexp", db, tbl)
+	}
+	return strings.Join(kept, ","), nil
+}
+
+// columnList returns the column list of an INSERT or LOAD DATA statement
+func columnList(cols []*Column) string {
+	names := make([]string, len(cols))
+	for i, col := range cols {
+		names[i] = quoteIdent(col.Name)
+	}
+	return "(" + strings.Join(names, ",") + ")"
+}
@@ -0,0 +1,91 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpDataExcludeColumns(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	ctx := context.Background()
+	newOpt := func() Options {
+		return Options{
+			dbs:             []string{"db1"},
+			netBufferLength: defaultNetBufferLength,
+			format:          formatSQL,
+			consistency:     consistencyNone,
+			excludeColumns:  regexp.MustCompile(`^(created_at|updated_at|_internal_.*)$`),
+		}
+	}
+	columns := func(names ...string) *sqlmock.Rows {
+		rows := sqlmock.NewRows([]string{"attname"})
+		for _, name := range names {
+			rows.AddRow(name)
+		}
+		return rows
+	}
+	opt := newOpt()
+	mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables").
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).
+			AddRow("t1", "r").
+			AddRow("t2", "r"))
+	mock.ExpectQuery("show create table").
+		WillReturnRows(sqlmock.NewRows([]string{"Table", "Create"}).AddRow("t1", "create table t1 (id int, name varchar(10), created_at datetime, updated_at datetime)"))
+	mock.ExpectQuery("show create table").
+		WillReturnRows(sqlmock.NewRows([]string{"Table", "Create"}).AddRow("t2", "create table t2 (id int, created_at datetime, _internal_flag int)"))
+	mock.ExpectQuery("att_relname = 't1'").WillReturnRows(columns("id", "name", "created_at", "updated_at"))
+	mock.ExpectQuery("select `id`,`name` from `db1`.`t1`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow("1", "a"))
+	mock.ExpectQuery("att_relname = 't2'").WillReturnRows(columns("id", "created_at", "_internal_flag"))
+	mock.ExpectQuery("select `id` from `db1`.`t2`").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("2"))
+	out := captureStdout(t, func() {
+		err = opt.dumpData(ctx)
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Contains(t, out, "INSERT INTO `t1` (`id`,`name`) VALUES (1,'a');\n")
+	require.Contains(t, out, "INSERT INTO `t2` (`id`) VALUES (2);\n")
+
+	// a table without any column left fails
+	opt = newOpt()
+	opt.tables = Tables{{"t3", ""}}
+	mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables").
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).AddRow("t3", "r"))
+	mock.ExpectQuery("show create table").
+		WillReturnRows(sqlmock.NewRows([]string{"Table", "Create"}).AddRow("t3", "create table t3 (created_at datetime, updated_at datetime)"))
+	mock.ExpectQuery("att_relname = 't3'").WillReturnRows(columns("created_at", "updated_at"))
+	captureStdout(t, func() {
+		err = opt.dumpData(ctx)
+	})
+	require.ErrorContains(t, err, "all columns of table `db1`.`t3` are excluded")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLoadDataStmtColumns(t *testing.T) {
+	conf := &csvConfig{enable: true, fieldDelimiter: ','}
+	cols := []*Column{{Name: "id"}, {Name: "name"}}
+	require.Equal(t, "LOAD DATA INFILE '/tmp/db1_t1.csv' INTO TABLE `t1` FIELDS TERMINATED BY '\\t' ENCLOSED BY '\"' LINES TERMINATED BY '\\n' (`id`,`name`) PARALLEL 'FALSE';",
+		loadDataStmt("/tmp/db1_t1.csv", "t1", false, conf, cols))
+}
@@ -0,0 +1,132 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// foreignKey is a reference from a table of the dumped database to a table
+type foreignKey struct {
+	table      string
+	referDB    string
+	referTable string
+}
+
+// selfReferencing reports if the key references its own table, like
+// employees.manager_id -> employees.id. Such a key orders the rows of the
+// table, not the table among the others, so it is no cycle of the tables.
+func (fk foreignKey) selfReferencing(db string) bool {
+	return fk.referDB == db && fk.referTable == fk.table
+}
+
+// getForeignKeys returns the foreign keys of the tables of the database,
+// one for each pair of tables however many columns the key has
+func getForeignKeys(ctx context.Context, db string) ([]foreignKey, error) {
+	qctx, cancel := queryContext(ctx)
+	defer cancel()
+	what := "read foreign keys of " + quoteIdent(db)
+	r, err := connRetry.query(qctx, what, "select distinct table_name, refer_db_name, refer_table_name from mo_catalog.mo_foreign_keys where db_name = '"+escapeString(db)+"'")
+	if err != nil {
+		return nil, queryTimeoutError(ctx, qctx, err, what)
+	}
+	defer r.Close()
+
+	var fks []foreignKey
+	for r.Next() {
+		var fk foreignKey
+		err = r.Scan(&fk.table, &fk.referDB, &fk.referTable)
+		if err != nil {
+			return nil, err
+		}
+		fks = append(fks, fk)
+	}
+	if err = r.Err(); err != nil {
+		return nil, queryTimeoutError(ctx, qctx, err, what)
+	}
+	return fks, nil
+}
+
+// selfReferencingTables returns the tables of db with a foreign key to
+// themselves. Their rows only load with the foreign key checks off, or in
+// the order of the hierarchy, see tableForeignKeyChecks.
+func selfReferencingTables(db string, fks []foreignKey) map[string]bool {
+	tables := make(map[string]bool)
+	for _, fk := range fks {
+		if fk.selfReferencing(db) {
+			tables[fk.table] = true
+		}
+	}
+	return tables
+}
+
+// orderByForeignKeys orders the tables of db for -fk-order, so that every
+// table is created and loaded after the tables its foreign keys reference.
+// Keys to the table itself or to another database do not order the tables.
+// On a cycle the order is kept, the restore relies on the foreign key checks
+// being off as without -fk-order. The self-referencing tables are returned.
+func orderByForeignKeys(ctx context.Context, db string, tables Tables) (map[string]bool, error) {
+	fks, err := getForeignKeys(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	selfReferencing := selfReferencingTables(db, fks)
+	refs := make(map[string]map[string]bool)
+	for _, fk := range fks {
+		if fk.referDB != db || fk.selfReferencing(db) {
+			continue
+		}
+		if refs[fk.table] == nil {
+			refs[fk.table] = make(map[string]bool)
+		}
+		refs[fk.table][fk.referTable] = true
+	}
+	order, cycle := dependencyOrder(len(tables), func(i, j int) bool {
+		return refs[tables[i].Name][tables[j].Name]
+	})
+	if len(cycle) > 0 {
+		fmt.Fprintf(os.Stderr, "foreign keys of tables %s of database `%s` form a cycle, the tables are dumped in their current order and need the foreign key checks off to restore\n", quotedNames(tables, cycle), db)
+		return selfReferencing, nil
+	}
+	ordered := make(Tables, len(tables))
+	for i, k := range order {
+		ordered[i] = tables[k]
+	}
+	copy(tables, ordered)
+	return selfReferencing, nil
+}
+
+// tableForeignKeyChecks toggles the foreign key checks around the data of
+// a self-referencing table under -fk-order, as its rows may reference rows
+// loaded after them. The toggle goes with the data statements, to the load
+// script for -load-script. It is left out when the checks are off for the
+// whole dump already, which -no-fk-toggle turns off.
+func (opt *Options) tableForeignKeyChecks(db string, on bool) {
+	if (opt.splitSchema() || opt.loadScript != nil) && !opt.noFKToggle {
+		return
+	}
+	v := 0
+	if on {
+		v = 1
+	}
+	stmt := fmt.Sprintf("SET FOREIGN_KEY_CHECKS = %d;", v)
+	if opt.loadScript != nil {
+		opt.loadScript.add(db, stmt)
+		return
+	}
+	fmt.Fprintln(opt.stdout(), stmt)
+}
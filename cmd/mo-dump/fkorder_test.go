@@ -0,0 +1,134 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetForeignKeysSelfReferencing(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	mock.ExpectQuery("select distinct table_name, refer_db_name, refer_table_name from mo_catalog.mo_foreign_keys where db_name = 'hr'").
+		WillReturnRows(sqlmock.NewRows([]string{"table_name", "refer_db_name", "refer_table_name"}).
+			AddRow("employees", "hr", "employees").
+			AddRow("employees", "hr", "departments").
+			AddRow("audit", "other", "audit"))
+	fks, err := getForeignKeys(context.Background(), "hr")
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Equal(t, []foreignKey{
+		{"employees", "hr", "employees"},
+		{"employees", "hr", "departments"},
+		{"audit", "other", "audit"},
+	}, fks)
+	require.True(t, fks[0].selfReferencing("hr"))
+	require.False(t, fks[1].selfReferencing("hr"))
+	// a table of the same name in another database is another table
+	require.False(t, fks[2].selfReferencing("hr"))
+	require.Equal(t, map[string]bool{"employees": true}, selfReferencingTables("hr", fks))
+}
+
+func TestOrderByForeignKeys(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	ctx := context.Background()
+	fkQuery := "select distinct table_name, refer_db_name, refer_table_name from mo_catalog.mo_foreign_keys where db_name = 'shop'"
+	mock.ExpectQuery(fkQuery).
+		WillReturnRows(sqlmock.NewRows([]string{"table_name", "refer_db_name", "refer_table_name"}).
+			AddRow("items", "shop", "orders").
+			AddRow("items", "shop", "products").
+			AddRow("orders", "shop", "customers").
+			AddRow("customers", "shop", "customers").
+			AddRow("products", "other", "vendors"))
+	tables := Tables{{"items", "r"}, {"orders", "r"}, {"customers", "r"}, {"products", "r"}, {"v1", "v"}}
+	selfReferencing, err := orderByForeignKeys(ctx, "shop", tables)
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{"customers": true}, selfReferencing)
+	// the self reference of customers and the key to another database do
+	// not order the tables
+	require.Equal(t, []string{"customers", "products", "v1", "orders", "items"}, tableNames(tables))
+
+	// a cycle keeps the order
+	mock.ExpectQuery(fkQuery).
+		WillReturnRows(sqlmock.NewRows([]string{"table_name", "refer_db_name", "refer_table_name"}).
+			AddRow("a", "shop", "b").
+			AddRow("b", "shop", "a").
+			AddRow("c", "shop", "d"))
+	tables = Tables{{"a", "r"}, {"b", "r"}, {"c", "r"}, {"d", "r"}}
+	stderr := captureStderr(t, func() {
+		_, err := orderByForeignKeys(ctx, "shop", tables)
+		require.NoError(t, err)
+	})
+	require.Equal(t, []string{"a", "b", "c", "d"}, tableNames(tables))
+	require.Equal(t, "foreign keys of tables `a`, `b` of database `shop` form a cycle, the tables are dumped in their current order and need the foreign key checks off to restore\n", stderr)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDumpDataSelfReferencing(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	var out bytes.Buffer
+	opt := Options{
+		dbs:             []string{"hr"},
+		emptyTables:     true,
+		netBufferLength: defaultNetBufferLength,
+		format:          formatSQL,
+		consistency:     consistencyNone,
+		fkOrder:         true,
+		noFKToggle:      true,
+		out:             &out,
+	}
+	mock.ExpectQuery("show create database").
+		WillReturnRows(sqlmock.NewRows([]string{"Database", "Create"}).AddRow("hr", "create database hr"))
+	mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables").
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).AddRow("employees", "r").AddRow("departments", "r"))
+	mock.ExpectQuery("select distinct table_name, refer_db_name, refer_table_name from mo_catalog.mo_foreign_keys").
+		WillReturnRows(sqlmock.NewRows([]string{"table_name", "refer_db_name", "refer_table_name"}).
+			AddRow("employees", "hr", "employees").
+			AddRow("employees", "hr", "departments"))
+	mock.ExpectQuery("show create table").
+		WillReturnRows(sqlmock.NewRows([]string{"Table", "Create"}).AddRow("departments", "create table departments (id int)"))
+	mock.ExpectQuery("show create table").
+		WillReturnRows(sqlmock.NewRows([]string{"Table", "Create"}).AddRow("employees", "create table employees (id int, manager_id int)"))
+	mock.ExpectQuery("select \\* from `hr`.`departments`").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("1"))
+	mock.ExpectQuery("select \\* from `hr`.`employees`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "manager_id"}).AddRow("2", "3").AddRow("3", nil))
+	require.NoError(t, opt.dumpData(context.Background()))
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// only the data of the self-referencing table loads with the checks off
+	require.Equal(t, "DROP DATABASE IF EXISTS `hr`;\ncreate database hr ;\nUSE `hr`;\n\n\n"+
+		"DROP TABLE IF EXISTS `departments`;\ncreate table departments (id int);\n"+
+		"INSERT INTO `departments` VALUES (1);\n\n\n\n"+
+		"DROP TABLE IF EXISTS `employees`;\ncreate table employees (id int, manager_id int);\n"+
+		"SET FOREIGN_KEY_CHECKS = 0;\n"+
+		"INSERT INTO `employees` VALUES (2,3),(3,NULL);\n\n\n\n"+
+		"SET FOREIGN_KEY_CHECKS = 1;\n", out.String())
+}
@@ -0,0 +1,163 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Each frame of the framed format is a type byte, the big endian uint32
+// length of the payload and the payload. A table is one header frame, one
+// row frame per row and one footer frame.
+const (
+	frameHeader byte = 'H'
+	frameRow    byte = 'R'
+	frameFooter byte = 'F'
+)
+
+// nullFieldLen is the field length of a NULL value in a row frame
+const nullFieldLen = math.MaxUint32
+
+type framedColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// framedHeader is the json payload of the header frame
+type framedHeader struct {
+	Database    string         `json:"database"`
+	Table       string         `json:"table"`
+	Fingerprint string         `json:"fingerprint"`
+	Columns     []framedColumn `json:"columns"`
+}
+
+// framedFooter is the json payload of the footer frame
+type framedFooter struct {
+	Rows int64 `json:"rows"`
+}
+
+// schemaFingerprint hashes the DDL of the table and its result columns, so
+// that a reader can check it applies the rows to the same schema
+func schemaFingerprint(create string, cols []*Column) string {
+	h := sha256.New()
+	io.WriteString(h, create)
+	for _, col := range cols {
+		fmt.Fprintf(h, "\n%s %s", col.Name, col.Type)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// showFramed writes the rows of the table to db_tbl.frames. create is the
+// DDL of the table the fingerprint is computed from.
+func showFramed(r rowIterator, out io.Writer, rowResults []any, cols []*Column, dir string, db string, tbl string, create string) (string, error) {
+	fname := dataFile(dir, fmt.Sprintf("%s_%s.%s", db, tbl, "frames"))
+	f, err := openFiles.create(fname)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	header := framedHeader{
+		Database:    db,
+		Table:       tbl,
+		Fingerprint: schemaFingerprint(create, cols),
+		Columns:     make([]framedColumn, len(cols)),
+	}
+	for i, col := range cols {
+		header.Columns[i] = framedColumn{Name: col.Name, Type: col.Type}
+	}
+	w := bufio.NewWriter(f)
+	err = toFramed(r, w, rowResults, header)
+	if err != nil {
+		return "", err
+	}
+	err = w.Flush()
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(out, "/*!FRAMED '%s' %s */\n", refPath(fname), header.Fingerprint)
+	return fname, nil
+}
+
+// toFramed writes the header, the rows and the footer frames. The fields of
+// a row frame are the uint32 length and the text of each value, NULL has
+// the length nullFieldLen and no text.
+func toFramed(r rowIterator, output io.Writer, rowResults []any, header framedHeader) error {
+	payload, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	err = writeFrame(output, frameHeader, payload)
+	if err != nil {
+		return err
+	}
+	var (
+		buf  bytes.Buffer
+		rows int64
+		n    [4]byte
+	)
+	for r.Next() {
+		err = r.Scan(rowResults...)
+		if err != nil {
+			return err
+		}
+		buf.Reset()
+		for _, v := range rowResults {
+			val := *(v.(*sql.RawBytes))
+			if val == nil {
+				binary.BigEndian.PutUint32(n[:], nullFieldLen)
+				buf.Write(n[:])
+				continue
+			}
+			binary.BigEndian.PutUint32(n[:], uint32(len(val)))
+			buf.Write(n[:])
+			buf.Write(val)
+		}
+		err = writeFrame(output, frameRow, buf.Bytes())
+		if err != nil {
+			return err
+		}
+		rows++
+	}
+	if err = r.Err(); err != nil {
+		return err
+	}
+	payload, err = json.Marshal(framedFooter{Rows: rows})
+	if err != nil {
+		return err
+	}
+	return writeFrame(output, frameFooter, payload)
+}
+
+func writeFrame(w io.Writer, typ byte, payload []byte) error {
+	var head [5]byte
+	head[0] = typ
+	binary.BigEndian.PutUint32(head[1:], uint32(len(payload)))
+	_, err := w.Write(head[:])
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(payload)
+	return err
+}
@@ -0,0 +1,143 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+type frame struct {
+	typ     byte
+	payload []byte
+}
+
+func readFrames(t *testing.T, data []byte) []frame {
+	var frames []frame
+	r := bytes.NewReader(data)
+	for {
+		var head [5]byte
+		_, err := io.ReadFull(r, head[:])
+		if err == io.EOF {
+			return frames
+		}
+		require.NoError(t, err)
+		payload := make([]byte, binary.BigEndian.Uint32(head[1:]))
+		_, err = io.ReadFull(r, payload)
+		require.NoError(t, err)
+		frames = append(frames, frame{head[0], payload})
+	}
+}
+
+func TestSchemaFingerprint(t *testing.T) {
+	cols := []*Column{{Name: "id", Type: "INT"}, {Name: "name", Type: "VARCHAR"}}
+	fp := schemaFingerprint("create table t1 (id int, name varchar(10))", cols)
+	require.Len(t, fp, 64)
+	require.Equal(t, fp, schemaFingerprint("create table t1 (id int, name varchar(10))", cols))
+	require.NotEqual(t, fp, schemaFingerprint("create table t1 (id int, name varchar(20))", cols))
+	require.NotEqual(t, fp, schemaFingerprint("create table t1 (id int, name varchar(10))", cols[:1]))
+}
+
+func TestToFramed(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "name"}).
+		AddRow("1", []byte{'a', 0xff}).
+		AddRow("2", nil).
+		AddRow("3", "")
+	mock.ExpectQuery("select").WillReturnRows(rows)
+	r, err := db.Query("select")
+	require.NoError(t, err)
+	defer r.Close()
+
+	rowResults := []any{new(sql.RawBytes), new(sql.RawBytes)}
+	header := framedHeader{
+		Database:    "db1",
+		Table:       "t1",
+		Fingerprint: "fp",
+		Columns:     []framedColumn{{"id", "INT"}, {"name", "VARCHAR"}},
+	}
+	var out bytes.Buffer
+	require.NoError(t, toFramed(r, &out, rowResults, header))
+
+	frames := readFrames(t, out.Bytes())
+	require.Len(t, frames, 5)
+	require.Equal(t, frameHeader, frames[0].typ)
+	var h framedHeader
+	require.NoError(t, json.Unmarshal(frames[0].payload, &h))
+	require.Equal(t, header, h)
+
+	require.Equal(t, frameRow, frames[1].typ)
+	require.Equal(t, []byte{0, 0, 0, 1, '1', 0, 0, 0, 2, 'a', 0xff}, frames[1].payload)
+	require.Equal(t, []byte{0, 0, 0, 1, '2', 0xff, 0xff, 0xff, 0xff}, frames[2].payload)
+	require.Equal(t, []byte{0, 0, 0, 1, '3', 0, 0, 0, 0}, frames[3].payload)
+
+	require.Equal(t, frameFooter, frames[4].typ)
+	var f framedFooter
+	require.NoError(t, json.Unmarshal(frames[4].payload, &f))
+	require.Equal(t, int64(3), f.Rows)
+}
+
+func TestGenOutputFramed(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	defer os.Chdir(wd)
+
+	bufPool := &sync.Pool{
+		New: func() any {
+			return &bytes.Buffer{}
+		},
+	}
+	create := "create table t1 (a int)"
+	opt := Options{netBufferLength: defaultNetBufferLength, format: formatFramed}
+	mock.ExpectQuery("show create table `db1`.`t1`").
+		WillReturnRows(sqlmock.NewRows([]string{"Table", "Create"}).AddRow("t1", create))
+	rows := mock.NewRowsWithColumnDefinition(sqlmock.NewColumn("a").OfType("INT", int64(0))).AddRow("1")
+	mock.ExpectQuery("select \\* from `db1`.`t1`").WillReturnRows(rows)
+	out := captureStdout(t, func() {
+		err = opt.genOutput(context.Background(), []string{"select * from `db1`.`t1`"}, "db1", "t1", bufPool)
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	fp := schemaFingerprint(create, []*Column{{Name: "a", Type: "INT"}})
+	require.Contains(t, out, "/db1_t1.frames' "+fp+" */\n")
+	data, err := os.ReadFile(filepath.Join(dir, "db1_t1.frames"))
+	require.NoError(t, err)
+	frames := readFrames(t, data)
+	require.Len(t, frames, 3)
+	var h framedHeader
+	require.NoError(t, json.Unmarshal(frames[0].payload, &h))
+	require.Equal(t, fp, h.Fingerprint)
+}
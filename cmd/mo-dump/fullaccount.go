@@ -0,0 +1,284 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/matrixorigin/matrixone/pkg/common/moerr"
+)
+
+// systemDatabases are created with every account, -full-account leaves
+// them out
+var systemDatabases = map[string]bool{
+	"information_schema": true,
+	"mo_catalog":         true,
+	"mo_debug":           true,
+	"mo_task":            true,
+	"mysql":              true,
+	"system":             true,
+	"system_metrics":     true,
+}
+
+const (
+	// the roles moadmin, public and accountadmin are created with the
+	// account, their ids are 0, 1 and 2
+	lastBuiltinRoleID = 2
+	publicRoleID      = 1
+	// adminUserCond leaves out the administrators, whose default role is
+	// moadmin or accountadmin. They are created with the account.
+	adminUserCond = "(u.default_role is null or u.default_role not in (0, 2))"
+)
+
+// subscription is a database created from a publication of another
+// account. It holds no data of its own.
+type subscription struct {
+	db     string
+	create string
+}
+
+// checkAccount makes sure the connection belongs to the account given to
+// -account-id, as the roles, users and grants can only be read from a
+// session of their own account
+func (opt *Options) checkAccount(ctx context.Context) error {
+	if opt.accountID < 0 {
+		return nil
+	}
+	var id int64
+	err := session(ctx).QueryRowContext(ctx, "select current_account_id()").Scan(&id)
+	if err != nil {
+		return err
+	}
+	if id != opt.accountID {
+		return moerr.NewInvalidInput(ctx, "full-account dumps the account of the connection %d, connect as a user of account %d", id, opt.accountID)
+	}
+	return nil
+}
+
+// getAccountDatabases returns the databases of the account without the
+// system databases, and its subscriptions apart
+func getAccountDatabases(ctx context.Context) ([]string, []subscription, error) {
+	all, err := getDatabases(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := session(ctx).QueryContext(ctx, "select datname, dat_createsql from mo_catalog.mo_database where dat_type = 'subscription' order by datname")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer r.Close()
+	var subs []subscription
+	subscribed := map[string]bool{}
+	for r.Next() {
+		var s subscription
+		if err = r.Scan(&s.db, &s.create); err != nil {
+			return nil, nil, err
+		}
+		subs = append(subs, s)
+		subscribed[s.db] = true
+	}
+	if err = r.Err(); err != nil {
+		return nil, nil, err
+	}
+	dbs := make([]string, 0, len(all))
+	for _, db := range all {
+		if !systemDatabases[db] && !subscribed[db] {
+			dbs = append(dbs, db)
+		}
+	}
+	return dbs, subs, nil
+}
+
+// dumpFullAccount dumps the account in the order it is restored: the roles
+// and users, the databases with their tables, views and routines, the
+// publications and subscriptions, then the grants which refer to all of them
+func (opt *Options) dumpFullAccount(ctx context.Context) error {
+	w := opt.schema()
+	fmt.Fprintf(w, "/* MODUMP FULL ACCOUNT: ROLES AND USERS */\n")
+	err := showRoles(ctx, w)
+	if err != nil {
+		return err
+	}
+	err = showUsers(ctx, w)
+	if err != nil {
+		return err
+	}
+	err = opt.dumpData(ctx)
+	if err != nil {
+		return err
+	}
+	if opt.truncated {
+		return nil
+	}
+	w = opt.schema()
+	fmt.Fprintf(w, "/* MODUMP FULL ACCOUNT: PUBLICATIONS AND SUBSCRIPTIONS */\n")
+	err = showPublications(ctx, w)
+	if err != nil {
+		return err
+	}
+	for _, s := range opt.subscriptions {
+		showCreateTable(w, s.create, true)
+	}
+	fmt.Fprintf(w, "/* MODUMP FULL ACCOUNT: GRANTS */\n")
+	return showGrants(ctx, w)
+}
+
+// queryStrings runs a query of string columns and calls fn with the values
+// of each row
+func queryStrings(ctx context.Context, query string, n int, fn func(values []string) error) error {
+	r, err := session(ctx).QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	values := make([]string, n)
+	dest := make([]any, n)
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	for r.Next() {
+		if err = r.Scan(dest...); err != nil {
+			return err
+		}
+		if err = fn(values); err != nil {
+			return err
+		}
+	}
+	return r.Err()
+}
+
+func showRoles(ctx context.Context, w io.Writer) error {
+	return queryStrings(ctx, fmt.Sprintf("select role_name from mo_catalog.mo_role where role_id > %d order by role_id", lastBuiltinRoleID), 1, func(v []string) error {
+		_, err := fmt.Fprintf(w, "CREATE ROLE IF NOT EXISTS %s;\n", quoteIdent(v[0]))
+		return err
+	})
+}
+
+// showUsers writes the users with a random password, since the password
+// hash can not be restored. The passwords are set again with ALTER USER.
+func showUsers(ctx context.Context, w io.Writer) error {
+	query := "select u.user_name, ifnull(u.status, ''), ifnull(r.role_name, '') from mo_catalog.mo_user u " +
+		fmt.Sprintf("left join mo_catalog.mo_role r on u.default_role = r.role_id and r.role_id <> %d ", publicRoleID) +
+		"where " + adminUserCond + " order by u.user_id"
+	first := true
+	return queryStrings(ctx, query, 3, func(v []string) error {
+		if first {
+			fmt.Fprintf(w, "/* the passwords are not dumped, set them with ALTER USER */\n")
+			first = false
+		}
+		stmt := fmt.Sprintf("CREATE USER IF NOT EXISTS %s IDENTIFIED BY RANDOM PASSWORD", quoteIdent(v[0]))
+		if v[2] != "" {
+			stmt += fmt.Sprintf(" DEFAULT ROLE %s", quoteIdent(v[2]))
+		}
+		if v[1] == "lock" {
+			stmt += " LOCK"
+		}
+		_, err := fmt.Fprintf(w, "%s;\n", stmt)
+		return err
+	})
+}
+
+func showPublications(ctx context.Context, w io.Writer) error {
+	return queryStrings(ctx, "select pub_name, database_name, ifnull(account_list, ''), ifnull(comment, '') from mo_catalog.mo_pubs order by pub_name", 4, func(v []string) error {
+		stmt := fmt.Sprintf("CREATE PUBLICATION IF NOT EXISTS %s DATABASE %s", quoteIdent(v[0]), quoteIdent(v[1]))
+		switch {
+		case strings.EqualFold(v[2], "all"):
+			stmt += " ACCOUNT ALL"
+		case v[2] != "":
+			stmt += " ACCOUNT " + v[2]
+		}
+		if v[3] != "" {
+			stmt += " COMMENT '" + escapeString(v[3]) + "'"
+		}
+		_, err := fmt.Fprintf(w, "%s;\n", stmt)
+		return err
+	})
+}
+
+// showGrants writes the grants of the roles to the roles and users, then
+// the privileges of the roles. The objects of the privileges are named, as
+// their ids change in the restore.
+func showGrants(ctx context.Context, w io.Writer) error {
+	err := queryStrings(ctx, "select g.role_name, e.role_name, if(rg.with_grant_option, 'true', 'false') from mo_catalog.mo_role_grant rg "+
+		"join mo_catalog.mo_role g on rg.granted_id = g.role_id join mo_catalog.mo_role e on rg.grantee_id = e.role_id "+
+		"order by g.role_name, e.role_name", 3, func(v []string) error {
+		_, err := fmt.Fprintf(w, "GRANT %s TO %s%s;\n", quoteIdent(v[0]), quoteIdent(v[1]), grantOption(v[2]))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	err = queryStrings(ctx, "select r.role_name, u.user_name, if(ug.with_grant_option, 'true', 'false') from mo_catalog.mo_user_grant ug "+
+		"join mo_catalog.mo_role r on ug.role_id = r.role_id join mo_catalog.mo_user u on ug.user_id = u.user_id "+
+		fmt.Sprintf("where ug.role_id <> %d and ", publicRoleID)+adminUserCond+" order by r.role_name, u.user_name", 3, func(v []string) error {
+		_, err := fmt.Fprintf(w, "GRANT %s TO %s%s;\n", quoteIdent(v[0]), quoteIdent(v[1]), grantOption(v[2]))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	query := "select p.role_name, p.privilege_name, p.obj_type, p.privilege_level, if(p.with_grant_option, 'true', 'false'), " +
+		"ifnull(d.datname, ''), ifnull(t.reldatabase, ''), ifnull(t.relname, '') from mo_catalog.mo_role_privs p " +
+		"left join mo_catalog.mo_database d on d.dat_id = p.obj_id and (p.obj_type = 'database' or p.privilege_level = 'd.*') " +
+		"left join mo_catalog.mo_tables t on t.rel_id = p.obj_id and p.obj_type = 'table' and p.privilege_level in ('d.t', 't') " +
+		fmt.Sprintf("where p.role_id > %d order by p.role_name, p.obj_type, p.privilege_name", lastBuiltinRoleID)
+	return queryStrings(ctx, query, 8, func(v []string) error {
+		object, ok := privilegeObject(v[2], v[3], v[5], v[6], v[7])
+		if !ok {
+			fmt.Fprintf(os.Stderr, "skip privilege %s on %s %s of role `%s`\n", v[1], v[2], v[3], v[0])
+			return nil
+		}
+		_, err := fmt.Fprintf(w, "GRANT %s ON %s TO %s%s;\n", strings.ToUpper(v[1]), object, quoteIdent(v[0]), grantOption(v[4]))
+		return err
+	})
+}
+
+func grantOption(v string) string {
+	if v == "true" {
+		return " WITH GRANT OPTION"
+	}
+	return ""
+}
+
+// privilegeObject returns the object of a privilege of mo_role_privs in a
+// GRANT statement. The object of a privilege on a database or table which
+// no longer exists, or of another type, can not be named.
+func privilegeObject(objType, level, db, tblDB, tbl string) (string, bool) {
+	switch objType {
+	case "account":
+		return "ACCOUNT *", true
+	case "database":
+		switch level {
+		case "*", "*.*":
+			return "DATABASE " + level, true
+		case "d":
+			return "DATABASE " + quoteIdent(db), db != ""
+		}
+	case "table":
+		switch level {
+		case "*", "*.*":
+			return "TABLE " + level, true
+		case "d.*":
+			return "TABLE " + quoteIdent(db) + ".*", db != ""
+		case "d.t", "t":
+			return "TABLE " + quoteIdent(tblDB) + "." + quoteIdent(tbl), tbl != ""
+		}
+	}
+	return "", false
+}
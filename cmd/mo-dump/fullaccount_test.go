@@ -0,0 +1,222 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/matrixorigin/matrixone/pkg/sql/parsers/dialect/mysql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAccountDatabases(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	mock.ExpectQuery("show databases").
+		WillReturnRows(sqlmock.NewRows([]string{"Database"}).AddRow("db1").AddRow("mo_catalog").AddRow("sub1").AddRow("system").AddRow("db2"))
+	mock.ExpectQuery("select datname, dat_createsql from mo_catalog.mo_database where dat_type = 'subscription'").
+		WillReturnRows(sqlmock.NewRows([]string{"datname", "dat_createsql"}).AddRow("sub1", "create database sub1 from acc1 publication pub1"))
+	dbs, subs, err := getAccountDatabases(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Equal(t, []string{"db1", "db2"}, dbs)
+	require.Equal(t, []subscription{{"sub1", "create database sub1 from acc1 publication pub1"}}, subs)
+}
+
+func TestCheckAccount(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	ctx := context.Background()
+	opt := Options{accountID: -1}
+	require.NoError(t, opt.checkAccount(ctx))
+
+	opt.accountID = 3
+	mock.ExpectQuery("select current_account_id()").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	require.NoError(t, opt.checkAccount(ctx))
+	mock.ExpectQuery("select current_account_id()").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	require.Error(t, opt.checkAccount(ctx))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPrivilegeObject(t *testing.T) {
+	for _, c := range []struct {
+		objType, level, db, tblDB, tbl string
+		want                           string
+		ok                             bool
+	}{
+		{"account", "*", "", "", "", "ACCOUNT *", true},
+		{"database", "*", "", "", "", "DATABASE *", true},
+		{"database", "d", "db1", "", "", "DATABASE `db1`", true},
+		{"database", "d", "", "", "", "DATABASE ``", false},
+		{"table", "*.*", "", "", "", "TABLE *.*", true},
+		{"table", "d.*", "db1", "", "", "TABLE `db1`.*", true},
+		{"table", "d.t", "", "db1", "t1", "TABLE `db1`.`t1`", true},
+		{"function", "*", "", "", "", "", false},
+	} {
+		got, ok := privilegeObject(c.objType, c.level, c.db, c.tblDB, c.tbl)
+		require.Equal(t, c.ok, ok, c)
+		if ok {
+			require.Equal(t, c.want, got)
+		}
+	}
+}
+
+func TestDumpFullAccount(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	opt := Options{
+		dbs:             []string{"db1"},
+		emptyTables:     true,
+		routines:        true,
+		netBufferLength: defaultNetBufferLength,
+		format:          formatSQL,
+		consistency:     consistencyNone,
+		fullAccount:     true,
+		subscriptions:   []subscription{{"sub1", "create database sub1 from acc1 publication pub1"}},
+	}
+	mock.ExpectQuery("select role_name from mo_catalog.mo_role where role_id > 2").
+		WillReturnRows(sqlmock.NewRows([]string{"role_name"}).AddRow("reader"))
+	mock.ExpectQuery("select u.user_name, ifnull\\(u.status, ''\\), ifnull\\(r.role_name, ''\\) from mo_catalog.mo_user u").
+		WillReturnRows(sqlmock.NewRows([]string{"user_name", "status", "role_name"}).AddRow("u1", "unlock", "reader").AddRow("u2", "lock", ""))
+	mock.ExpectQuery("show create database").
+		WillReturnRows(sqlmock.NewRows([]string{"Database", "Create Database"}).AddRow("db1", "CREATE DATABASE `db1`"))
+	mock.ExpectQuery("from mo_catalog.mo_user_defined_function").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "args", "retType", "body", "language"}))
+	mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables").
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).AddRow("t1", "r"))
+	mock.ExpectQuery("show create table").
+		WillReturnRows(sqlmock.NewRows([]string{"Table", "Create Table"}).AddRow("t1", "CREATE TABLE `t1` (`a` INT)"))
+	mock.ExpectQuery("select \\* from `db1`.`t1`").
+		WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("1"))
+	mock.ExpectQuery("from mo_catalog.mo_stored_procedure").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "args", "body"}))
+	mock.ExpectQuery("from mo_catalog.mo_pubs").
+		WillReturnRows(sqlmock.NewRows([]string{"pub_name", "database_name", "account_list", "comment"}).AddRow("pub1", "db1", "all", "for 'all'"))
+	mock.ExpectQuery("from mo_catalog.mo_role_grant").
+		WillReturnRows(sqlmock.NewRows([]string{"granted", "grantee", "option"}).AddRow("reader", "writer", "false"))
+	mock.ExpectQuery("from mo_catalog.mo_user_grant").
+		WillReturnRows(sqlmock.NewRows([]string{"role", "user", "option"}).AddRow("reader", "u1", "true"))
+	mock.ExpectQuery("from mo_catalog.mo_role_privs").
+		WillReturnRows(sqlmock.NewRows([]string{"role", "priv", "type", "level", "option", "db", "tbldb", "tbl"}).
+			AddRow("reader", "select", "table", "d.t", "false", "", "db1", "t1").
+			AddRow("reader", "show tables", "database", "d", "false", "db1", "", "").
+			AddRow("reader", "execute", "function", "*", "false", "", "", ""))
+	var out string
+	warn := captureStderr(t, func() {
+		out = captureStdout(t, func() {
+			err = opt.dumpFullAccount(context.Background())
+		})
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Equal(t, "skip privilege execute on function * of role `reader`\n", warn)
+
+	// the categories are written in the order they are restored
+	order := []string{
+		"CREATE ROLE IF NOT EXISTS `reader`;",
+		"CREATE USER IF NOT EXISTS `u1` IDENTIFIED BY RANDOM PASSWORD DEFAULT ROLE `reader`;",
+		"CREATE USER IF NOT EXISTS `u2` IDENTIFIED BY RANDOM PASSWORD LOCK;",
+		"CREATE DATABASE `db1`",
+		"CREATE TABLE `t1` (`a` INT);",
+		"INSERT INTO `t1` VALUES (1);",
+		"CREATE PUBLICATION IF NOT EXISTS `pub1` DATABASE `db1` ACCOUNT ALL COMMENT 'for \\'all\\'';",
+		"create database sub1 from acc1 publication pub1;",
+		"GRANT `reader` TO `writer`;",
+		"GRANT `reader` TO `u1` WITH GRANT OPTION;",
+		"GRANT SELECT ON TABLE `db1`.`t1` TO `reader`;",
+		"GRANT SHOW TABLES ON DATABASE `db1` TO `reader`;",
+	}
+	last := -1
+	for _, stmt := range order {
+		i := strings.Index(out, stmt)
+		require.Greater(t, i, last, "%s in\n%s", stmt, out)
+		last = i
+	}
+	for _, stmt := range order {
+		if !strings.HasSuffix(stmt, ";") {
+			continue
+		}
+		_, err = mysql.ParseOne(context.Background(), stmt, 1)
+		require.NoError(t, err, stmt)
+	}
+}
+
+func TestDumpFullAccountSubscriptionFirst(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	// the subscription comes before the regular database in show databases
+	ctx := context.Background()
+	mock.ExpectQuery("show databases").
+		WillReturnRows(sqlmock.NewRows([]string{"Database"}).AddRow("a_sub").AddRow("db1"))
+	mock.ExpectQuery("select datname, dat_createsql from mo_catalog.mo_database where dat_type = 'subscription'").
+		WillReturnRows(sqlmock.NewRows([]string{"datname", "dat_createsql"}).AddRow("a_sub", "create database a_sub from acc1 publication pub1"))
+	opt := Options{
+		emptyTables:     true,
+		netBufferLength: defaultNetBufferLength,
+		format:          formatSQL,
+		consistency:     consistencyNone,
+		fullAccount:     true,
+	}
+	opt.dbs, opt.subscriptions, err = getAccountDatabases(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"db1"}, opt.dbs)
+
+	// the regular database is read on the connection of the dump, the
+	// subscription only by its DDL
+	mock.ExpectQuery("select role_name from mo_catalog.mo_role where role_id > 2").
+		WillReturnRows(sqlmock.NewRows([]string{"role_name"}))
+	mock.ExpectQuery("select u.user_name, ifnull\\(u.status, ''\\), ifnull\\(r.role_name, ''\\) from mo_catalog.mo_user u").
+		WillReturnRows(sqlmock.NewRows([]string{"user_name", "status", "role_name"}))
+	mock.ExpectQuery("show create database `db1`").
+		WillReturnRows(sqlmock.NewRows([]string{"Database", "Create Database"}).AddRow("db1", "CREATE DATABASE `db1`"))
+	mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables").
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).AddRow("t1", "r"))
+	mock.ExpectQuery("show create table `db1`.`t1`").
+		WillReturnRows(sqlmock.NewRows([]string{"Table", "Create Table"}).AddRow("t1", "CREATE TABLE `t1` (`a` INT)"))
+	mock.ExpectQuery("select \\* from `db1`.`t1`").
+		WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("1"))
+	mock.ExpectQuery("from mo_catalog.mo_pubs").
+		WillReturnRows(sqlmock.NewRows([]string{"pub_name", "database_name", "account_list", "comment"}))
+	mock.ExpectQuery("from mo_catalog.mo_role_grant").
+		WillReturnRows(sqlmock.NewRows([]string{"granted", "grantee", "option"}))
+	mock.ExpectQuery("from mo_catalog.mo_user_grant").
+		WillReturnRows(sqlmock.NewRows([]string{"role", "user", "option"}))
+	mock.ExpectQuery("from mo_catalog.mo_role_privs").
+		WillReturnRows(sqlmock.NewRows([]string{"role", "priv", "type", "level", "option", "db", "tbldb", "tbl"}))
+	out := captureStdout(t, func() {
+		err = opt.dumpFullAccount(ctx)
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Same(t, db, conn)
+	data := strings.Index(out, "INSERT INTO `t1` VALUES (1);")
+	require.GreaterOrEqual(t, data, 0, out)
+	require.Greater(t, strings.Index(out, "create database a_sub from acc1 publication pub1;"), data, out)
+	require.NotContains(t, out, "USE `a_sub`")
+}
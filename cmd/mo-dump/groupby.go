@@ -0,0 +1,75 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"strings"
+
+	"github.com/matrixorigin/matrixone/pkg/common/moerr"
+)
+
+// parseGroupBy parses tbl:col;tbl:col into the shard column of each table,
+// keyed by table name
+func parseGroupBy(ctx context.Context, spec string) (map[string]string, error) {
+	groups := make(map[string]string)
+	for _, item := range strings.Split(spec, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		tbl, col, ok := strings.Cut(item, ":")
+		tbl = strings.TrimSpace(tbl)
+		col = strings.TrimSpace(col)
+		if !ok || tbl == "" || col == "" || strings.Contains(col, ",") {
+			return nil, moerr.NewInvalidInput(ctx, "group-by must be in the format tbl:col, got %s", item)
+		}
+		if _, ok := groups[tbl]; ok {
+			return nil, moerr.NewInvalidInput(ctx, "table %s is given more than once in group-by", tbl)
+		}
+		groups[tbl] = col
+	}
+	return groups, nil
+}
+
+// shardColumn returns the position of the group-by column of the table in
+// the result, or -1 if the rows of the table are not grouped
+func (opt *Options) shardColumn(tbl string, cols []*Column) (int, error) {
+	name, ok := opt.groupBy[tbl]
+	if !ok {
+		return -1, nil
+	}
+	for i, col := range cols {
+		if col.Name == name {
+			return i, nil
+		}
+	}
+	return -1, moerr.NewInvalidInputNoCtx("group-by column `%s` is not dumped of table `%s`", name, tbl)
+}
+
+// showShard writes the comment starting the rows of a shard value. A
+// sharding-aware restore tool routes the statements up to the next comment
+// to the shard.
+func showShard(w io.Writer, v *sql.RawBytes) error {
+	value := "NULL"
+	if *v != nil {
+		// the value can not end the comment
+		value = strings.ReplaceAll(string(*v), "*/", "* /")
+	}
+	_, err := io.WriteString(w, "/* shard="+value+" */\n")
+	return err
+}
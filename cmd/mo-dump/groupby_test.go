@@ -0,0 +1,93 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGroupBy(t *testing.T) {
+	ctx := context.Background()
+	groups, err := parseGroupBy(ctx, "t1:tenant_id; t2 : region;")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"t1": "tenant_id", "t2": "region"}, groups)
+
+	for _, spec := range []string{"t1", ":a", "t1:", "t1:a,b", "t1:a;t1:b"} {
+		_, err = parseGroupBy(ctx, spec)
+		require.Error(t, err, spec)
+	}
+}
+
+func TestSelectQueriesGroupBy(t *testing.T) {
+	ctx := context.Background()
+	opt := Options{
+		netBufferLength:    defaultNetBufferLength,
+		groupBy:            map[string]string{"t1": "region"},
+		sortForCompression: map[string][]string{"t1": {"kind"}},
+	}
+	// the shard column comes first so that its values are contiguous
+	queries, err := opt.selectQueries(ctx, "db1", "t1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"select * from `db1`.`t1` order by `region`,`kind`"}, queries)
+}
+
+func TestGenOutputGroupBy(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	bufPool := &sync.Pool{
+		New: func() any {
+			return &bytes.Buffer{}
+		},
+	}
+	opt := Options{
+		netBufferLength: defaultNetBufferLength,
+		insertBatchRows: 2,
+		format:          formatSQL,
+		groupBy:         map[string]string{"t1": "region"},
+	}
+	mock.ExpectQuery("select \\* from `db1`.`t1` order by `region`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "region"}).
+			AddRow(5, nil).
+			AddRow(1, "eu").AddRow(2, "eu").AddRow(3, "eu").
+			AddRow(4, "us*/"))
+	queries, err := opt.selectQueries(context.Background(), "db1", "t1")
+	require.NoError(t, err)
+	out := captureStdout(t, func() {
+		require.NoError(t, opt.genOutput(context.Background(), queries, "db1", "t1", bufPool))
+	})
+	// a statement never mixes shards, and every shard starts with a comment
+	require.Equal(t, "/* shard=NULL */\n"+
+		"INSERT INTO `t1` VALUES (5,NULL);\n"+
+		"/* shard=eu */\n"+
+		"INSERT INTO `t1` VALUES (1,'eu'),(2,'eu');\n"+
+		"INSERT INTO `t1` VALUES (3,'eu');\n"+
+		"/* shard=us* / */\n"+
+		"INSERT INTO `t1` VALUES (4,'us*/');\n", out)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// the shard column has to be dumped
+	opt.groupBy = map[string]string{"t1": "tenant_id"}
+	mock.ExpectQuery("select").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	err = opt.genOutput(context.Background(), []string{"select * from `db1`.`t1`"}, "db1", "t1", bufPool)
+	require.Error(t, err)
+}
@@ -0,0 +1,104 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/matrixorigin/matrixone/pkg/common/moerr"
+)
+
+// fileHook runs a shell command for every data file once it is complete,
+// at most concurrency commands at a time. {} in the command is replaced by
+// the file name.
+type fileHook struct {
+	command      string
+	ignoreErrors bool
+	sem          chan struct{}
+	wg           sync.WaitGroup
+	mu           sync.Mutex
+	err          error
+}
+
+func newFileHook(command string, concurrency int, ignoreErrors bool) *fileHook {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &fileHook{
+		command:      command,
+		ignoreErrors: ignoreErrors,
+		sem:          make(chan struct{}, concurrency),
+	}
+}
+
+// run starts the command for the file in the background. It blocks while
+// concurrency commands are running. A nil hook does nothing.
+func (h *fileHook) run(fname string) {
+	if h == nil {
+		return
+	}
+	h.sem <- struct{}{}
+	h.wg.Add(1)
+	go func() {
+		defer func() {
+			<-h.sem
+			h.wg.Done()
+		}()
+		command := strings.ReplaceAll(h.command, "{}", shellQuote(fname))
+		out, err := exec.Command("sh", "-c", command).CombinedOutput()
+		if err == nil {
+			return
+		}
+		if h.ignoreErrors {
+			fmt.Fprintf(os.Stderr, "post-file-command for %s failed: %v %s\n", fname, err, out)
+			return
+		}
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if h.err == nil {
+			h.err = moerr.NewInternalErrorNoCtx("post-file-command `%s` failed: %v %s", command, err, out)
+		}
+	}()
+}
+
+// wait waits for the running commands and returns the first failure
+func (h *fileHook) wait() error {
+	if h == nil {
+		return nil
+	}
+	h.wg.Wait()
+	return h.err
+}
+
+// resultFilesDone hands the result files of a complete dump, the -o file
+// or schema.sql and data.sql, to the post-file command once they are closed
+// and signed, and waits for all the commands
+func (opt *Options) resultFilesDone(files ...*resultFile) error {
+	for _, f := range files {
+		if f != nil {
+			opt.fileHook.run(f.f.Name())
+		}
+	}
+	return opt.fileHook.wait()
+}
+
+// shellQuote quotes s as one word for sh
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,118 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileHook(t *testing.T) {
+	dir := t.TempDir()
+	log := filepath.Join(dir, "log")
+	h := newFileHook("echo {} >> "+shellQuote(log), 2, false)
+	names := []string{"db1_t1.csv", "db1_t2.csv", "it's a file.csv", "db1_t3.csv"}
+	for _, name := range names {
+		h.run(name)
+	}
+	require.NoError(t, h.wait())
+	data, err := os.ReadFile(log)
+	require.NoError(t, err)
+	got := strings.Split(strings.TrimSpace(string(data)), "\n")
+	sort.Strings(got)
+	want := append([]string(nil), names...)
+	sort.Strings(want)
+	require.Equal(t, want, got)
+
+	h = newFileHook("test {} != db1_t2.csv", 1, false)
+	h.run("db1_t1.csv")
+	h.run("db1_t2.csv")
+	err = h.wait()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "db1_t2.csv")
+
+	h = newFileHook("exit 3", 1, true)
+	h.run("db1_t1.csv")
+	require.NoError(t, h.wait())
+
+	var nilHook *fileHook
+	nilHook.run("db1_t1.csv")
+	require.NoError(t, nilHook.wait())
+}
+
+func TestResultFilesDone(t *testing.T) {
+	dir := t.TempDir()
+	log := filepath.Join(dir, "log")
+	out, _, err := openResultFile(filepath.Join(dir, "dump.sql"))
+	require.NoError(t, err)
+	_, err = out.WriteString("CREATE TABLE t1 (a int);\n")
+	require.NoError(t, err)
+	require.NoError(t, out.Close())
+
+	// the command sees the complete file
+	opt := Options{fileHook: newFileHook("cat {} >> "+shellQuote(log), 1, false)}
+	require.NoError(t, opt.resultFilesDone(nil, out))
+	data, err := os.ReadFile(log)
+	require.NoError(t, err)
+	require.Equal(t, "CREATE TABLE t1 (a int);\n", string(data))
+
+	opt.fileHook = newFileHook("exit 1", 1, false)
+	require.ErrorContains(t, opt.resultFilesDone(nil, out), "post-file-command")
+
+	opt.fileHook = nil
+	require.NoError(t, opt.resultFilesDone(nil, out))
+}
+
+func TestGenOutputFileHook(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	defer os.Chdir(wd)
+
+	bufPool := &sync.Pool{
+		New: func() any {
+			return &bytes.Buffer{}
+		},
+	}
+	opt := Options{
+		format:   formatSQL,
+		csvConf:  csvConfig{enable: true, fieldDelimiter: ','},
+		fileHook: newFileHook("cp {} {}.done", 1, false),
+	}
+	mock.ExpectQuery("select").WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("1"))
+	_ = captureStdout(t, func() {
+		err = opt.genOutput(context.Background(), []string{"select * from `db1`.`t1`"}, "db1", "t1", bufPool)
+	})
+	require.NoError(t, err)
+	require.NoError(t, opt.fileHook.wait())
+	data, err := os.ReadFile(filepath.Join(dir, "db1_t1.csv.done"))
+	require.NoError(t, err)
+	require.Equal(t, "1\n", string(data))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
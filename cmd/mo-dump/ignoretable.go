@@ -0,0 +1,85 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/matrixorigin/matrixone/pkg/common/moerr"
+)
+
+// ignoreTables is the set of tables -ignore-table excludes from the dump,
+// given as db.table or as table for the table of that name in every
+// database. The flag may be repeated and takes comma separated names.
+// -data-tables and -schema-only-tables take their tables the same way.
+type ignoreTables map[string]bool
+
+func (t *ignoreTables) String() string {
+	names := make([]string, 0, len(*t))
+	for name := range *t {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+func (t *ignoreTables) Set(value string) error {
+	if *t == nil {
+		*t = make(ignoreTables)
+	}
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".") {
+			return moerr.NewInvalidInputNoCtx("table must be in the format db.table or table, got %s", name)
+		}
+		(*t)[name] = true
+	}
+	return nil
+}
+
+// has reports if the table of db is ignored
+func (t ignoreTables) has(db, tbl string) bool {
+	return t[db+"."+tbl] || t[tbl]
+}
+
+// checkRequested fails if a table of db requested by -tbl is ignored as
+// well, as the two options contradict
+func (t ignoreTables) checkRequested(ctx context.Context, db string, requested Tables) error {
+	for _, tbl := range requested {
+		if t.has(db, tbl.Name) {
+			return moerr.NewInvalidInput(ctx, "table `%s`.`%s` is requested by -tbl and ignored by -ignore-table", db, tbl.Name)
+		}
+	}
+	return nil
+}
+
+// filter removes the ignored tables of db
+func (t ignoreTables) filter(db string, tables Tables) Tables {
+	if len(t) == 0 {
+		return tables
+	}
+	ret := tables[:0]
+	for _, tbl := range tables {
+		if !t.has(db, tbl.Name) {
+			ret = append(ret, tbl)
+		}
+	}
+	return ret
+}
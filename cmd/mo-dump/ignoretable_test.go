@@ -0,0 +1,67 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIgnoreTablesSet(t *testing.T) {
+	var ignored ignoreTables
+	require.NoError(t, ignored.Set("db1.t1, t2"))
+	require.NoError(t, ignored.Set("db2.t3"))
+	require.Equal(t, "db1.t1,db2.t3,t2", ignored.String())
+	require.True(t, ignored.has("db1", "t1"))
+	require.False(t, ignored.has("db2", "t1"))
+	require.True(t, ignored.has("db2", "t2"))
+	require.True(t, ignored.has("db2", "t3"))
+
+	require.Error(t, ignored.Set("db1."))
+	require.Error(t, ignored.Set(".t1"))
+}
+
+func TestGetTablesIgnored(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	ctx := context.Background()
+	var ignored ignoreTables
+	require.NoError(t, ignored.Set("db1.t2,log"))
+	newRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"relname", "relkind"}).
+			AddRow("t1", "r").AddRow("t2", "r").AddRow("log", "r")
+	}
+
+	mock.ExpectQuery("reldatabase = 'db1'").WillReturnRows(newRows())
+	tables, err := getTables(ctx, "db1", nil, ignored, false, false, false)
+	require.NoError(t, err)
+	require.Equal(t, Tables{{"t1", "r"}}, tables)
+
+	mock.ExpectQuery("reldatabase = 'db2'").WillReturnRows(newRows())
+	tables, err = getTables(ctx, "db2", nil, ignored, false, false, false)
+	require.NoError(t, err)
+	require.Equal(t, Tables{{"t1", "r"}, {"t2", "r"}}, tables)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// a table can not be requested and ignored at once
+	_, err = getTables(ctx, "db1", Tables{{"t1", ""}, {"t2", ""}}, ignored, false, false, false)
+	require.ErrorContains(t, err, "table `db1`.`t2` is requested by -tbl and ignored by -ignore-table")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,51 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+
+	"github.com/matrixorigin/matrixone/pkg/common/moerr"
+)
+
+const (
+	insertModeInsert = "insert"
+	// insertModeIgnore skips the rows which conflict with the rows of the
+	// restored table
+	insertModeIgnore = "ignore"
+	// insertModeReplace overwrites the conflicting rows of the restored table
+	insertModeReplace = "replace"
+)
+
+func checkInsertMode(ctx context.Context, mode string) error {
+	switch mode {
+	case "", insertModeInsert, insertModeIgnore, insertModeReplace:
+		return nil
+	default:
+		return moerr.NewInvalidInput(ctx, "insert-mode must be one of %s, %s, %s, got %s", insertModeInsert, insertModeIgnore, insertModeReplace, mode)
+	}
+}
+
+// insertKeyword returns the keywords which start the statements of the rows
+func insertKeyword(mode string) string {
+	switch mode {
+	case insertModeIgnore:
+		return "INSERT IGNORE INTO"
+	case insertModeReplace:
+		return "REPLACE INTO"
+	default:
+		return "INSERT INTO"
+	}
+}
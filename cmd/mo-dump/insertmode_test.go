@@ -0,0 +1,70 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"sync"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/matrixorigin/matrixone/pkg/sql/parsers/dialect/mysql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckInsertMode(t *testing.T) {
+	ctx := context.Background()
+	for _, mode := range []string{"", insertModeInsert, insertModeIgnore, insertModeReplace} {
+		require.NoError(t, checkInsertMode(ctx, mode))
+	}
+	require.Error(t, checkInsertMode(ctx, "upsert"))
+}
+
+func TestShowInsertMode(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	cols := []*Column{{Name: "a", Type: "INT"}}
+	bufPool := &sync.Pool{New: func() any { return &bytes.Buffer{} }}
+	for _, c := range []struct {
+		mode string
+		want string
+	}{
+		{insertModeInsert, "INSERT INTO `t` VALUES (1),(2);\nINSERT INTO `t` VALUES (3);\n"},
+		{insertModeIgnore, "INSERT IGNORE INTO `t` VALUES (1),(2);\nINSERT IGNORE INTO `t` VALUES (3);\n"},
+		{insertModeReplace, "REPLACE INTO `t` VALUES (1),(2);\nREPLACE INTO `t` VALUES (3);\n"},
+	} {
+		// the batch of two rows starts a second statement with the same keyword
+		mock.ExpectQuery("select").WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("1").AddRow("2").AddRow("3"))
+		r, err := db.Query("select")
+		require.NoError(t, err)
+		var v sql.RawBytes
+		var out bytes.Buffer
+		require.NoError(t, showInsert(r, &out, []any{&v}, cols, "t", bufPool, 1024, 2, 0, "", false, c.mode, -1))
+		r.Close()
+		require.Equal(t, c.want, out.String())
+		// the parser of the MatrixOne version in go.mod has no INSERT IGNORE yet
+		if c.mode == insertModeIgnore {
+			continue
+		}
+		for _, stmt := range bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n")) {
+			_, err = mysql.ParseOne(context.Background(), string(stmt), 1)
+			require.NoError(t, err, string(stmt))
+		}
+	}
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,98 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+
+	"github.com/matrixorigin/matrixone/pkg/common/moerr"
+)
+
+// modes of -json-mode
+const (
+	jsonCompact  = "compact"
+	jsonPretty   = "pretty"
+	jsonValidate = "validate"
+)
+
+// checkJSONMode checks the mode. Pretty printed values span several lines,
+// which only the csv files keep reloadable.
+func checkJSONMode(ctx context.Context, mode string, toCsv bool) error {
+	switch mode {
+	case jsonCompact, jsonValidate:
+		return nil
+	case jsonPretty:
+		if !toCsv {
+			return moerr.NewInvalidInput(ctx, "json-mode %s requires the option 'csv'", mode)
+		}
+		return nil
+	default:
+		return moerr.NewInvalidInput(ctx, "unsupported json-mode %s", mode)
+	}
+}
+
+// jsonRows checks or re-indents the values of the json columns as they are
+// scanned
+type jsonRows struct {
+	rowIterator
+	mode string
+	cols []*Column
+	tbl  string
+	row  int
+	buf  bytes.Buffer
+}
+
+func (j *jsonRows) Scan(dest ...any) error {
+	err := j.rowIterator.Scan(dest...)
+	if err != nil {
+		return err
+	}
+	j.row++
+	for i, col := range j.cols {
+		if !strings.EqualFold(col.Type, "json") {
+			continue
+		}
+		raw := dest[i].(*sql.RawBytes)
+		if *raw == nil {
+			continue
+		}
+		switch j.mode {
+		case jsonValidate:
+			if !json.Valid(*raw) {
+				return moerr.NewInvalidInputNoCtx("column `%s` of row %d of table `%s` is not valid json", col.Name, j.row, j.tbl)
+			}
+		case jsonPretty:
+			j.buf.Reset()
+			// a malformed value is kept as it is
+			if json.Indent(&j.buf, *raw, "", "  ") == nil {
+				*raw = append((*raw)[:0:0], j.buf.Bytes()...)
+			}
+		}
+	}
+	return nil
+}
+
+// wrapJSONRows applies the json-mode to the rows of r, the compact mode
+// keeps the values as they are
+func (opt *Options) wrapJSONRows(r rowIterator, cols []*Column, tbl string) rowIterator {
+	if opt.jsonMode == "" || opt.jsonMode == jsonCompact {
+		return r
+	}
+	return &jsonRows{rowIterator: r, mode: opt.jsonMode, cols: cols, tbl: tbl}
+}
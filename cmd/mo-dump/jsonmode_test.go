@@ -0,0 +1,117 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"sync"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+const nestedJSON = `{"a":{"b":[1,{"c":"x y"}]},"d":null}`
+
+func TestCheckJSONMode(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, checkJSONMode(ctx, jsonCompact, false))
+	require.NoError(t, checkJSONMode(ctx, jsonValidate, false))
+	require.NoError(t, checkJSONMode(ctx, jsonPretty, true))
+	require.Error(t, checkJSONMode(ctx, jsonPretty, false))
+	require.Error(t, checkJSONMode(ctx, "indent", true))
+}
+
+// jsonModeRows returns the rows of a table with a json and a varchar column
+// wrapped for the mode
+func jsonModeRows(t *testing.T, mode string, values ...any) (rowIterator, []*Column, []any) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	rows := sqlmock.NewRows([]string{"j", "s"})
+	for _, v := range values {
+		rows.AddRow(v, `{"kept": 1}`)
+	}
+	mock.ExpectQuery("select").WillReturnRows(rows)
+	r, err := db.Query("select")
+	require.NoError(t, err)
+	t.Cleanup(func() { r.Close() })
+
+	cols := []*Column{{Name: "j", Type: "JSON"}, {Name: "s", Type: "VARCHAR"}}
+	var j, s sql.RawBytes
+	opt := Options{jsonMode: mode}
+	return opt.wrapJSONRows(r, cols, "t"), cols, []any{&j, &s}
+}
+
+func TestJSONModeCompact(t *testing.T) {
+	r, cols, args := jsonModeRows(t, jsonCompact, nestedJSON, "{bad")
+	_, ok := r.(*jsonRows)
+	require.False(t, ok)
+	bufPool := &sync.Pool{
+		New: func() any {
+			return &bytes.Buffer{}
+		},
+	}
+	out := captureStdout(t, func() {
+		require.NoError(t, showInsert(r, os.Stdout, args, cols, "t", bufPool, 1024, 0, 0, "", false, "", -1))
+	})
+	require.Equal(t, "INSERT INTO `t` VALUES ('"+nestedJSON+"','{\"kept\": 1}'),('{bad','{\"kept\": 1}');\n", out)
+}
+
+func TestJSONModeValidate(t *testing.T) {
+	bufPool := &sync.Pool{
+		New: func() any {
+			return &bytes.Buffer{}
+		},
+	}
+	r, cols, args := jsonModeRows(t, jsonValidate, nestedJSON, nil)
+	out := captureStdout(t, func() {
+		require.NoError(t, showInsert(r, os.Stdout, args, cols, "t", bufPool, 1024, 0, 0, "", false, "", -1))
+	})
+	require.Equal(t, "INSERT INTO `t` VALUES ('"+nestedJSON+"','{\"kept\": 1}'),(NULL,'{\"kept\": 1}');\n", out)
+
+	r, cols, args = jsonModeRows(t, jsonValidate, nestedJSON, `{"a":[1,}`)
+	captureStdout(t, func() {
+		err := showInsert(r, os.Stdout, args, cols, "t", bufPool, 1024, 0, 0, "", false, "", -1)
+		require.ErrorContains(t, err, "column `j` of row 2 of table `t` is not valid json")
+	})
+}
+
+func TestJSONModePretty(t *testing.T) {
+	r, cols, args := jsonModeRows(t, jsonPretty, nestedJSON, "{bad")
+	var out bytes.Buffer
+	err := toCsv(r, &out, "t", args, cols, &csvConfig{enable: true, fieldDelimiter: '\t'})
+	require.NoError(t, err)
+
+	cr := csv.NewReader(&out)
+	cr.Comma = '\t'
+	records, err := cr.ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	// the json value is enclosed in quotes, see convertValue2
+	pretty := records[0][0][1 : len(records[0][0])-1]
+	require.Equal(t, "{\n  \"a\": {\n    \"b\": [\n      1,\n      {\n        \"c\": \"x y\"\n      }\n    ]\n  },\n  \"d\": null\n}", pretty)
+	var compact bytes.Buffer
+	require.NoError(t, json.Compact(&compact, []byte(pretty)))
+	require.Equal(t, nestedJSON, compact.String())
+	// other columns and malformed values are kept
+	require.Equal(t, `{"kept": 1}`, records[0][1])
+	require.Equal(t, `"{bad"`, records[1][0])
+}
@@ -0,0 +1,102 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/matrixorigin/matrixone/pkg/catalog"
+)
+
+// how -list-kinds reports the handling of a relation
+const (
+	handlingData        = "dump data"
+	handlingDDL         = "DDL only"
+	handlingValue       = "sequence value"
+	handlingSequence    = "DDL and value"
+	handlingSkip        = "skip"
+	handlingUnsupported = "unsupported"
+)
+
+// kindHandling tells how the dump would handle a relation of the kind with
+// the current options. It follows filterTableKinds and dumpData.
+func (opt *Options) kindHandling(kind string) string {
+	switch kind {
+	case catalog.SystemOrdinaryRel:
+		if opt.noData {
+			return handlingDDL
+		}
+		return handlingData
+	case catalog.SystemExternalRel:
+		if opt.truncate {
+			return handlingSkip
+		}
+		return handlingDDL
+	case catalog.SystemViewRel:
+		if opt.materializeViews {
+			return handlingData
+		}
+		if opt.truncate {
+			return handlingSkip
+		}
+		return handlingDDL
+	case catalog.SystemSequenceRel:
+		if opt.sequences {
+			if opt.truncate {
+				return handlingValue
+			}
+			return handlingSequence
+		}
+		return handlingSkip
+	case catalog.SystemIndexRel, catalog.SystemClusterRel, catalog.SystemPartitionRel,
+		catalog.SystemMaterializedRel, catalog.SystemStreamRel:
+		return handlingSkip
+	default:
+		if opt.ignoreErrors {
+			return handlingSkip
+		}
+		return handlingUnsupported
+	}
+}
+
+// showKinds lists every relation of the databases with its relkind and how
+// the dump would handle it, without dumping anything
+func (opt *Options) showKinds(ctx context.Context, w io.Writer) (err error) {
+	if conn == nil {
+		conn, err = opt.openDBConnection(ctx, opt.dbs[0])
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "DATABASE\tTABLE\tRELKIND\tHANDLING\n")
+	requested := opt.tables
+	for _, db := range opt.dbs {
+		tables := append(Tables(nil), requested...)
+		tables, err = getTables(ctx, db, tables, opt.ignoreTables, opt.skipMissingTables, opt.allowEmptyMatch, opt.includeTemporary)
+		if err != nil {
+			return err
+		}
+		for _, tbl := range tables {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", db, tbl.Name, tbl.Kind, opt.kindHandling(tbl.Kind))
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,70 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShowKinds(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	ctx := context.Background()
+	opt := Options{dbs: []string{"db1", "db2"}}
+	mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables").
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).
+			AddRow("t1", "r").
+			AddRow("idx", "i").
+			AddRow("v1", "v").
+			AddRow("seq", "S"))
+	mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables").
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).
+			AddRow("ext", "e").
+			AddRow("odd", "x"))
+
+	var buf bytes.Buffer
+	err = opt.showKinds(ctx, &buf)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Equal(t, ""+
+		"DATABASE  TABLE  RELKIND  HANDLING\n"+
+		"db1       t1     r        dump data\n"+
+		"db1       idx    i        skip\n"+
+		"db1       v1     v        DDL only\n"+
+		"db1       seq    S        skip\n"+
+		"db2       ext    e        DDL only\n"+
+		"db2       odd    x        unsupported\n", buf.String())
+}
+
+func TestKindHandlingOptions(t *testing.T) {
+	opt := Options{noData: true, materializeViews: true, sequences: true, ignoreErrors: true}
+	require.Equal(t, handlingDDL, opt.kindHandling("r"))
+	require.Equal(t, handlingData, opt.kindHandling("v"))
+	require.Equal(t, handlingSequence, opt.kindHandling("S"))
+	require.Equal(t, handlingSkip, opt.kindHandling("x"))
+
+	opt = Options{truncate: true, sequences: true}
+	require.Equal(t, handlingData, opt.kindHandling("r"))
+	require.Equal(t, handlingValue, opt.kindHandling("S"))
+	require.Equal(t, handlingSkip, opt.kindHandling("e"))
+	require.Equal(t, handlingSkip, opt.kindHandling("v"))
+}
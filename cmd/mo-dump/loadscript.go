@@ -0,0 +1,58 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// loadScript collects the LOAD DATA statements of the csv files so that the
+// data can be loaded by one script after the schema is restored
+type loadScript struct {
+	path  string
+	db    string // database of the last statement
+	stmts []string
+	// noFKToggle leaves out the statements of the foreign key checks
+	noFKToggle bool
+}
+
+// add appends the statement of a table in db. A USE statement is inserted
+// whenever the database changes since LOAD DATA names the table only.
+func (s *loadScript) add(db, stmt string) {
+	if db != s.db {
+		s.stmts = append(s.stmts, fmt.Sprintf("USE %s;", quoteIdent(db)))
+		s.db = db
+	}
+	s.stmts = append(s.stmts, stmt)
+}
+
+// write writes the statements in the order they were added, with the foreign
+// key checks disabled around them unless no-fk-toggle is set
+func (s *loadScript) write() error {
+	var sb strings.Builder
+	if !s.noFKToggle {
+		sb.WriteString("SET FOREIGN_KEY_CHECKS = 0;\n")
+	}
+	for _, stmt := range s.stmts {
+		sb.WriteString(stmt)
+		sb.WriteString("\n")
+	}
+	if !s.noFKToggle {
+		sb.WriteString("SET FOREIGN_KEY_CHECKS = 1;\n")
+	}
+	return os.WriteFile(s.path, []byte(sb.String()), 0644)
+}
@@ -0,0 +1,100 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadScriptWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "load.sql")
+	s := &loadScript{path: path}
+	s.add("db1", "LOAD 1;")
+	s.add("db1", "LOAD 2;")
+	s.add("db2", "LOAD 3;")
+	require.NoError(t, s.write())
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "SET FOREIGN_KEY_CHECKS = 0;\nUSE `db1`;\nLOAD 1;\nLOAD 2;\nUSE `db2`;\nLOAD 3;\nSET FOREIGN_KEY_CHECKS = 1;\n", string(data))
+}
+
+func TestLoadScriptWriteNoFKToggle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "load.sql")
+	s := &loadScript{path: path, noFKToggle: true}
+	s.add("db1", "LOAD 1;")
+	require.NoError(t, s.write())
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "USE `db1`;\nLOAD 1;\n", string(data))
+}
+
+func TestDumpDataLoadScript(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	defer os.Chdir(wd)
+	t.Setenv("PWD", dir)
+
+	ctx := context.Background()
+	path := filepath.Join(dir, "load.sql")
+	opt := Options{
+		dbs:             []string{"db1"},
+		netBufferLength: defaultNetBufferLength,
+		format:          formatSQL,
+		consistency:     consistencyNone,
+		toCsv:           true,
+		csvConf:         csvConfig{enable: true, fieldDelimiter: defaultFieldDelimiter},
+		loadScript:      &loadScript{path: path},
+	}
+	mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables").
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).
+			AddRow("t1", "r").
+			AddRow("t2", "r"))
+	mock.ExpectQuery("show create table").
+		WillReturnRows(sqlmock.NewRows([]string{"Table", "Create"}).AddRow("t1", "create table t1 (a int)"))
+	mock.ExpectQuery("show create table").
+		WillReturnRows(sqlmock.NewRows([]string{"Table", "Create"}).AddRow("t2", "create table t2 (a int)"))
+	mock.ExpectQuery("select \\* from `db1`.`t1`").
+		WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("1"))
+	mock.ExpectQuery("select \\* from `db1`.`t2`").
+		WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("2"))
+	out := captureStdout(t, func() {
+		err = opt.dumpData(ctx)
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.NotContains(t, out, "LOAD DATA")
+	require.Contains(t, out, "/* LOAD SCRIPT '"+path+"' */")
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	want := "SET FOREIGN_KEY_CHECKS = 0;\n" +
+		"USE `db1`;\n" +
+		loadDataStmt(dir+"/db1_t1.csv", "t1", false, &opt.csvConf, nil) + "\n" +
+		loadDataStmt(dir+"/db1_t2.csv", "t2", false, &opt.csvConf, nil) + "\n" +
+		"SET FOREIGN_KEY_CHECKS = 1;\n"
+	require.Equal(t, want, string(data))
+}
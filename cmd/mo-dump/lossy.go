@@ -0,0 +1,41 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strings"
+
+	"github.com/matrixorigin/matrixone/pkg/common/moerr"
+)
+
+// isLossyType reports if the values of the column type are written by the
+// catch-all branches of convertValue and convertValue2, i.e. the empty type
+// whose values are guessed to be booleans, numbers or strings, and the types
+// mo-dump does not know which are quoted as strings
+func isLossyType(typ string) bool {
+	typ = strings.ToLower(typ)
+	return !castTypes[typ] && !isStringType(typ)
+}
+
+// checkLossless fails on the first column of the table whose values can not
+// be written faithfully. A column fixed by -cast passes.
+func checkLossless(cols []*Column, tbl string) error {
+	for _, col := range cols {
+		if isLossyType(col.Type) {
+			return moerr.NewNotSupportedNoCtx("column `%s` of table `%s` has type '%s' which can not be dumped without guessing, use -cast to set its type", col.Name, tbl, col.Type)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"sync"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsLossyType(t *testing.T) {
+	for _, typ := range []string{"INT", "UNSIGNED BIGINT", "FLOAT", "DECIMAL", "VARCHAR", "TEXT", "LONGTEXT", "JSON", "DATETIME", "BLOB", "VECF32", "UUID"} {
+		require.False(t, isLossyType(typ), typ)
+	}
+	for _, typ := range []string{"", "GEOMETRY", "ENUM"} {
+		require.True(t, isLossyType(typ), typ)
+	}
+}
+
+func TestGenOutputFailOnLossy(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	defer os.Chdir(wd)
+
+	bufPool := &sync.Pool{
+		New: func() any {
+			return &bytes.Buffer{}
+		},
+	}
+	kases := []struct {
+		name    string
+		typ     string
+		csvConf csvConfig
+		err     string
+	}{
+		// the bool-guess branch of convertValue
+		{"empty type insert", "", csvConfig{}, "column `b` of table `t1` has type ''"},
+		// the default branch of convertValue
+		{"unknown type insert", "GEOMETRY", csvConfig{}, "column `b` of table `t1` has type 'GEOMETRY'"},
+		// the empty type and default branches of convertValue2
+		{"empty type csv", "", csvConfig{enable: true, fieldDelimiter: ','}, "column `b` of table `t1` has type ''"},
+		{"unknown type csv", "GEOMETRY", csvConfig{enable: true, fieldDelimiter: ','}, "column `b` of table `t1` has type 'GEOMETRY'"},
+	}
+	for _, k := range kases {
+		opt := Options{netBufferLength: defaultNetBufferLength, failOnLossy: true, csvConf: k.csvConf}
+		rows := mock.NewRowsWithColumnDefinition(
+			sqlmock.NewColumn("a").OfType("INT", int64(0)),
+			sqlmock.NewColumn("b").OfType(k.typ, ""),
+		).AddRow("1", "x")
+		mock.ExpectQuery("select").WillReturnRows(rows)
+		out := captureStdout(t, func() {
+			err = opt.genOutput(context.Background(), []string{"select * from `db1`.`t1`"}, "db1", "t1", bufPool)
+		})
+		require.ErrorContains(t, err, k.err, k.name)
+		require.Empty(t, out, k.name)
+		require.NoError(t, mock.ExpectationsWereMet(), k.name)
+	}
+
+	// a cast makes the column known
+	opt := Options{netBufferLength: defaultNetBufferLength, failOnLossy: true, casts: map[string]map[string]string{"t1": {"b": "varchar"}}}
+	rows := mock.NewRowsWithColumnDefinition(
+		sqlmock.NewColumn("a").OfType("INT", int64(0)),
+		sqlmock.NewColumn("b").OfType("", ""),
+	).AddRow("1", "x")
+	mock.ExpectQuery("select").WillReturnRows(rows)
+	out := captureStdout(t, func() {
+		err = opt.genOutput(context.Background(), []string{"select * from `db1`.`t1`"}, "db1", "t1", bufPool)
+	})
+	require.NoError(t, err)
+	require.Equal(t, "INSERT INTO `t1` VALUES (1,'x');\n", out)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
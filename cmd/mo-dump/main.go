@@ -19,10 +19,13 @@ import (
 	"context"
 	"database/sql"
 	"encoding/csv"
+	"encoding/hex"
 	"flag"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
+	"regexp"
 	"strings"
 	"sync"
 	"time"
@@ -36,7 +39,11 @@ import (
 type Options struct {
 	username             string
 	password             string
+	passwordStdin        bool
+	ssl                  sslOptions
 	host                 string
+	hosts                []string
+	socket               string
 	database             string
 	tbl                  string
 	dbs                  []string
@@ -46,9 +53,129 @@ type Options struct {
 	toCsv                bool
 	localInfile          bool
 	noData               bool
+	skipEmptyTables      bool
+	skipEmptyData        bool
+	truncate             bool
+	safeRestore          bool
+	reportOnly           bool
+	listKinds            bool
+	probeTypes           bool
+	estimateSize         bool
+	completeInserts      bool
+	singleLine           bool
+	routines             bool
+	verifyConn           bool
+	materializeViews     bool
 	emptyTables          bool
 	csvConf              csvConfig
 	csvFieldDelimiterStr string
+	csvQuoteAll          bool
+	csvCompress          string
+	maxOpenFiles         int
+	resultFile           string
+	compress             bool
+	safeColumns          bool
+	splitSchemaData      bool
+	schemaOut            io.Writer
+	signFiles            bool
+	includeTemporary     bool
+	verifyFile           string
+	out                  io.Writer
+	dataDir              string
+	validateUTF8         string
+	jsonMode             string
+	postFileCommand      string
+	postFileConcurrency  int
+	ignoreHookErrors     bool
+	fileHook             *fileHook
+	stamp                stamp
+	txnRangeSpec         string
+	txnRange             txnRange
+	loadScriptPath       string
+	loadScript           *loadScript
+	dumpStart            time.Time
+	window               timeWindow
+	addLocks             bool
+	insertBatchRows      int
+	maxRowSize           int
+	format               string
+	consistency          string
+	consistencyFallback  bool
+	capturePosition      bool
+	ignoreErrors         bool
+	retryFailed          int
+	retries              int
+	retryInterval        time.Duration
+	queryTimeout         time.Duration
+	rowCountComments     string
+	failOnLossy          bool
+	charset              charsetOverride
+	normalizeDDL         bool
+	schemaHashFile       string
+	forceStdout          bool
+	sequences            bool
+	noSequences          bool
+	checksumAlgorithm    string
+	rowChecksums         bool
+	excludeColumnsSpec   string
+	excludeColumns       *regexp.Regexp
+	sortSpec             string
+	sortForCompression   map[string][]string
+	groupBySpec          string
+	groupBy              map[string]string
+	failedTables         []failedTable
+	skipMissingTables    bool
+	allowEmptyMatch      bool
+	authPlugin           string
+	authToken            string
+	dsnParams            string
+	ignoreTables         ignoreTables
+	dataTables           ignoreTables
+	schemaOnlyTables     ignoreTables
+	connectionAttributes string
+	dumpOrder            string
+	failOnEmpty          bool
+	dumpStatistics       bool
+	where                string
+	tableWhere           map[string]string
+	keepAliveInterval    time.Duration
+	deadline             time.Duration
+	whereInSpec          string
+	whereIn              *whereIn
+	castSpec             string
+	chunkTableSpec       string
+	chunkSize            string
+	resume               string
+	parallel             int
+	parallelSchemaFetch  int
+	insertMode           string
+	fullAccount          bool
+	singleTransaction    bool
+	lockTables           bool
+	noLock               bool
+	orderByPrimaryKey    bool
+	fkOrder              bool
+	noFKToggle           bool
+	excludeDatabasesSpec string
+	excludeDatabases     []string
+	accountID            int64
+	accountsSpec         string
+	accounts             []string
+	progress             bool
+	chunkTable           *chunkTable
+	casts                map[string]map[string]string
+	// dumpedObjects counts the tables and views written to the dump
+	dumpedObjects int
+	// lastTable is the last table dumped in full, `db`.`tbl`
+	lastTable string
+	// truncated is set if the dump stopped at the deadline
+	truncated bool
+	// temporaryTables are the temporary tables of the current database
+	temporaryTables map[string]bool
+	// subscriptions are the subscription databases of -full-account
+	subscriptions []subscription
+	// checkpoint records the complete tables for -resume
+	checkpoint *checkpoint
 }
 
 func (t *Tables) String() string {
@@ -61,17 +188,55 @@ func (t *Tables) Set(value string) error {
 }
 
 var usage = func() {
-	fmt.Fprintf(os.Stderr, "Usage: %s -u <username> -p <password> -h <host> -P <port> -db <database> [--local-infile=true] [-csv] [-tbl <table>...] [-no-data] -net-buffer-length <net-buffer-length>\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "Usage: %s -u <username> -p <password>|- [-password-stdin] -h <host>[,<host>...] -P <port> [-socket <path>] -db <database|pattern>[,...]|-full-account [-exclude-database <pattern>[,...]] [-account-id <id>] [-accounts <account,...>] [-connection-attributes <key=value,...>] [-dsn-params <key=value&...>] [-ssl-mode <mode> [-ssl-ca <path>] [-ssl-cert <path> -ssl-key <path>]] [--local-infile=true] [-csv] [-csv-quote-all] [-csv-compress gzip] [-max-open-files <n>] [-load-script <path>] [-post-file-command <command>] [-format <sql|mongo-json|ndjson|prepared|framed|tsv>] [-add-locks | -single-transaction | -lock-tables | -no-lock] [-tbl <table|pattern>... [-allow-empty-match]] [-ignore-table <db.table>...] [-report] [-list-kinds] [-probe-types] [-estimate-size] [-checksum-algorithm <crc32|sha256|xxhash> [-row-checksums]] [-no-sequences] [-routines] [-include-temporary] [-force-stdout] [-single-line-statements] [-o <path>] [-compress] [-split-schema-data] [-resume <path>] [-sign-files] [-verify-file <path>] [-force-charset <charset>] [-force-collation <collation>] [-normalize-ddl] [-schema-hash-file <path>] [-row-count-comments <estimate|exact>] [-ignore-errors [-retry-failed <n>]] [-retries <n> [-retry-interval <duration>]] [-query-timeout <duration>] [-no-data] [-data-tables <table>...] [-schema-only-tables <table>...] [-skip-empty-tables | -skip-empty-data-only] [-truncate] [-safe-restore] [-materialize-views] [-insert-batch-flush <rows>] [-validate-utf8 <error|hex>] [-json-mode <compact|pretty|validate>] [-where <condition>] [-where-in <tbl.col:file>] [-cast <tbl.col:type;...>] [-exclude-columns-regexp <pattern>] [-sort-for-compression <tbl:col1,col2;...>] [-order-by-primary-key] [-fk-order] [-no-fk-toggle] [-complete-insert] [-insert-mode <insert|ignore|replace>] [-safe-columns] [-fail-fast-on-lossy] [-progress] [-parallel <n>] [-parallel-schema-fetch <n>] [-chunk-table <tbl:pk:N>] [-chunk-size <bytes|Nrows>] [-group-by <tbl:col>] [-time-column <column> -from <from> -to <to>] [-txn-range <lo:hi>] [-deadline <duration>] [-stamp-table <table> -stamp-version <version> [-create-stamp-table]] [-verify-conn] -net-buffer-length <net-buffer-length>\n", os.Args[0])
 	flag.PrintDefaults()
 }
 
 func main() {
 	var (
-		err error
-		opt Options
+		err     error
+		opt     Options
+		out     *resultFile
+		schema  *resultFile
+		gz      *gzipMembers
+		signers []*signer
 	)
 	dumpStart := time.Now()
+	opt.dumpStart = dumpStart
 	defer func() {
+		// the gzip stream is finished before the result file is flushed
+		if gz != nil {
+			if e := gz.Close(); e != nil && err == nil {
+				err = e
+			}
+		}
+		// the footer ends a complete dump only
+		for _, s := range signers {
+			if err != nil {
+				break
+			}
+			err = s.sign()
+		}
+		for _, f := range []*resultFile{schema, out} {
+			if f == nil {
+				continue
+			}
+			if e := f.Close(); e != nil && err == nil {
+				err = e
+			}
+		}
+		if err == nil && !opt.truncated {
+			err = opt.resultFilesDone(schema, out)
+		}
+		if opt.checkpoint != nil {
+			if e := opt.checkpoint.Close(); e != nil && err == nil {
+				err = e
+			}
+			// a complete dump starts over the next time
+			if err == nil && !opt.truncated {
+				err = os.Remove(opt.resume)
+			}
+		}
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "modump error: %v\n", err)
 			os.Exit(1)
@@ -83,26 +248,138 @@ func main() {
 				os.Exit(1)
 			}
 		}
-		if err == nil && flag.NFlag() != 0 {
-			fmt.Fprintf(os.Stdout, "/* MODUMP SUCCESS, COST %v */\n", time.Since(dumpStart))
-			if opt.toCsv {
-				fmt.Fprintf(os.Stdout, "/* !!!MUST KEEP FILE IN CURRENT DIRECTORY, OR YOU SHOULD CHANGE THE PATH IN LOAD DATA STMT!!! */ \n")
+		if opt.truncated {
+			os.Exit(exitCodeTruncated)
+		}
+		if err == nil && flag.NFlag() != 0 && !opt.inspectOnly() && opt.verifyFile == "" {
+			// the banner is kept out of a result file or a gzip stream
+			banner := io.Writer(os.Stdout)
+			if !opt.toStdout() {
+				banner = os.Stderr
+			}
+			opt.showResult(banner, time.Since(dumpStart))
+			if opt.dumpedObjects == 0 && opt.failOnEmpty {
+				os.Exit(exitCodeEmpty)
 			}
 		}
 	}()
 
 	ctx := context.Background()
 	flag.StringVar(&opt.username, "u", defaultUsername, "username")
-	flag.StringVar(&opt.password, "p", defaultPassword, "password")
-	flag.StringVar(&opt.host, "h", defaultHost, "hostname")
+	flag.StringVar(&opt.password, "p", defaultPassword, "password. without -p the password is read from the MO_PWD environment variable if it is set. -p- reads it from stdin, before MO_PWD")
+	flag.BoolVar(&opt.passwordStdin, "password-stdin", defaultPasswordStdin, "read the password from the first line of stdin if neither -p nor MO_PWD is given (default false)")
+	flag.StringVar(&opt.host, "h", defaultHost, "hostname, or a comma separated list of hostnames tried in order until one connects")
+	flag.StringVar(&opt.authPlugin, "auth-plugin", "", "authentication plugin: mysql_native_password, caching_sha2_password or mysql_clear_password (default negotiated with the server)")
+	flag.StringVar(&opt.connectionAttributes, "connection-attributes", "", "key=value pairs separated by commas sent as connection attributes to tag the dump session on the server, e.g. \"program=mo-dump,purpose=nightly-backup\"")
+	flag.StringVar(&opt.ssl.mode, "ssl-mode", sslDisabled, "TLS of the connection: disabled, preferred (TLS if the server supports it), required (TLS without verifying the server), verify-ca (verify the server certificate against -ssl-ca) or verify-identity (verify the CA and the host name)")
+	flag.StringVar(&opt.ssl.ca, "ssl-ca", "", "file of the PEM encoded CA certificates to verify the server with, the system CAs if it is not set")
+	flag.StringVar(&opt.ssl.cert, "ssl-cert", "", "file of the PEM encoded client certificate")
+	flag.StringVar(&opt.ssl.key, "ssl-key", "", "file of the PEM encoded key of -ssl-cert")
+	flag.StringVar(&opt.dsnParams, "dsn-params", "", "raw go-sql-driver/mysql parameters appended to the data source name, e.g. \"interpolateParams=true&readTimeout=30s\". parameters set by other options can not be overridden")
+	flag.StringVar(&opt.authToken, "auth-token", "", "authentication token used instead of the password, sent with mysql_clear_password unless -auth-plugin is set")
 	flag.IntVar(&opt.port, "P", defaultPort, "portNumber")
+	flag.StringVar(&opt.socket, "socket", "", "connect through the Unix socket file at the path instead of TCP, -h and -P are ignored")
+	flag.DurationVar(&opt.keepAliveInterval, "keepalive-interval", defaultKeepAliveInterval, "ping the server at this interval during the dump so idle connections are not dropped, the pinned connection of -consistency is pinged while no statement runs on it (default 0, off)")
 	flag.IntVar(&opt.netBufferLength, "net-buffer-length", defaultNetBufferLength, "net_buffer_length")
-	flag.StringVar(&opt.database, "db", "", "databaseName, must be specified")
-	flag.StringVar(&opt.tbl, "tbl", "", "tableNameList (default all)")
+	flag.IntVar(&opt.insertBatchRows, "insert-batch-flush", defaultInsertBatchRows, "max rows in one INSERT statement, the statement is flushed when either this or net_buffer_length is reached (default 0, no limit)")
+	flag.IntVar(&opt.maxRowSize, "max-row-size", defaultMaxRowSize, "warn about rows whose single-row INSERT is larger than this size in bytes, which may exceed max_allowed_packet of the restore target, 0 disables it")
+	flag.StringVar(&opt.database, "db", "", "databaseName, must be specified. takes comma separated names or shell style globs such as app_*, expanded against show databases. all is an alias for *. a glob which is the name of a database selects that database only, a backslash escapes the next character")
+	flag.StringVar(&opt.tbl, "tbl", "", "tableNameList (default all). takes shell style globs such as log_2024_*, matched against the tables of each database. a glob which is the name of a table selects that table only, a backslash escapes the next character")
+	flag.StringVar(&opt.dumpOrder, "dump-order", dumpOrderCatalog, "order of the tables in the dump: alphabetical, size-asc or size-desc (default catalog order). views always follow the tables they depend on")
+	flag.Var(&opt.ignoreTables, "ignore-table", "skip this table, given as db.table or as table for every database. may be repeated or comma separated")
+	flag.BoolVar(&opt.skipMissingTables, "skip-missing-tables", defaultSkipMissingTables, "skip the tables in -tbl which do not exist with a warning instead of failing (default false)")
+	flag.StringVar(&opt.format, "format", formatSQL, "set export format of the data, sql, mongo-json, ndjson, prepared, framed or tsv. mongo-json writes one json document per line to a file for each table, ndjson writes one json object per row to a .ndjson file for each table, prepared writes one INSERT template and a file of parameter tuples for each table, framed writes a file of length-prefixed frames with a schema fingerprint for each table, tsv writes tab separated files with backslash escapes loaded like -csv")
 	flag.BoolVar(&opt.toCsv, "csv", defaultCsv, "set export format to csv (default false)")
 	flag.StringVar(&opt.csvFieldDelimiterStr, "csv-field-delimiter", string(defaultFieldDelimiter), "set csv field delimiter (only one utf8 character). enabled only when the option 'csv' is set.")
+	flag.BoolVar(&opt.csvQuoteAll, "csv-quote-all", defaultCsvQuoteAll, "enclose every csv field in double quotes to keep leading and trailing spaces and empty strings exactly. enabled only when the option 'csv' is set (default false)")
+	flag.IntVar(&opt.maxOpenFiles, "max-open-files", 0, fmt.Sprintf("the most data files open at once, such as csv files and the temporary files of chunk-table. opening more waits until a file is closed (default %d, below the limit of open files of the process)", openFiles.limit()))
+	flag.StringVar(&opt.csvCompress, "csv-compress", "", "compress each csv file, only gzip is supported. the files are named db_tbl.csv.gz and the LOAD DATA statements stay plain text. enabled only when the option 'csv' is set")
+	flag.StringVar(&opt.jsonMode, "json-mode", jsonCompact, "how to write the values of json columns: compact as read, pretty re-indented (requires the option 'csv') or validate failing the dump on a malformed value")
+	flag.StringVar(&opt.validateUTF8, "validate-utf8", "", "check that char, varchar and text values are valid utf8. error fails the dump on an invalid value, hex writes it as a hex literal with a warning, INSERT output only (default no check)")
+	flag.StringVar(&opt.postFileCommand, "post-file-command", "", "shell command run for each data file (csv, json, tuples or frames) once it is written, and for the -o file or schema.sql and data.sql once the dump is complete, {} is replaced by the file name, e.g. \"gzip {}\"")
+	flag.IntVar(&opt.postFileConcurrency, "post-file-concurrency", defaultPostFileConcurrency, "max number of post-file-command running at the same time")
+	flag.BoolVar(&opt.ignoreHookErrors, "ignore-hook-errors", defaultIgnoreHookErrors, "warn about a failed post-file-command instead of failing the dump (default false)")
+	flag.StringVar(&opt.loadScriptPath, "load-script", "", "collect the LOAD DATA statements of all csv files into this file, in dump order with foreign key checks disabled, instead of writing them between the DDL. enabled only when the option 'csv' is set")
+	flag.StringVar(&opt.rowCountComments, "row-count-comments", "", "write the row count of each table as a comment before its data. 'estimate' takes it from the table metadata, 'exact' runs count(*) with the same filters as the dump")
+	flag.StringVar(&opt.charset.charset, "force-charset", "", "replace the charset of the databases, tables and columns in the dumped DDL, e.g. utf8mb4. collations not forced by force-collation are removed")
+	flag.StringVar(&opt.charset.collation, "force-collation", "", "replace the collation of the databases, tables and columns in the dumped DDL, e.g. utf8mb4_general_ci")
+	flag.BoolVar(&opt.normalizeDDL, "normalize-ddl", defaultNormalizeDDL, "write the table DDL in a canonical form so that dumps of the same schema from different servers compare equal with diff. AUTO_INCREMENT counters are removed (default false)")
+	flag.StringVar(&opt.schemaHashFile, "schema-hash-file", "", "record a hash of the DDL of all tables and views in the file before the dump. if the file exists, the dump fails when the schema no longer matches the recorded hash")
 	flag.BoolVar(&opt.localInfile, "local-infile", defaultLocalInfile, "use load data local infile")
 	flag.BoolVar(&opt.noData, "no-data", defaultNoData, "dump database and table definitions only without data (default false)")
+	flag.BoolVar(&opt.skipEmptyTables, "skip-empty-tables", defaultSkipEmptyTables, "skip the tables without any row, neither their DDL nor their data is dumped (default false)")
+	flag.BoolVar(&opt.skipEmptyData, "skip-empty-data-only", defaultSkipEmptyData, "dump the DDL of the tables without any row but skip their empty data section (default false)")
+	flag.BoolVar(&opt.truncate, "truncate", defaultTruncate, "emit TRUNCATE TABLE before the data of each table instead of DROP and CREATE, to reload data into the existing schema. views and external tables are skipped (default false)")
+	flag.BoolVar(&opt.safeRestore, "safe-restore", defaultSafeRestore, "emit CREATE DATABASE IF NOT EXISTS instead of DROP DATABASE and CREATE DATABASE, so that a restore only replaces the dumped tables and keeps the other tables of an existing database (default false)")
+	flag.BoolVar(&opt.forceStdout, "force-stdout", defaultForceStdout, "write the dump even if the standard output is a terminal (default false)")
+	flag.BoolVar(&opt.reportOnly, "report", defaultReportOnly, "list the tables and views to dump with the row count and size of each table, then exit without dumping anything (default false)")
+	flag.BoolVar(&opt.listKinds, "list-kinds", defaultListKinds, "list every table and view with its relkind and how it would be dumped (dump data, DDL only, skip or unsupported), then exit without dumping anything (default false)")
+	flag.BoolVar(&opt.verifyConn, "verify-conn", defaultVerifyConn, "ping the connection before dumping each database and reconnect if it is lost, for long dumps of many databases. not possible with consistency snapshot or flush (default false)")
+	flag.BoolVar(&opt.routines, "routines", defaultRoutines, "dump the user-defined functions and the stored procedures of the databases, not when -tbl is given (default false)")
+	flag.BoolVar(&opt.singleLine, "single-line-statements", defaultSingleLineStatements, "write every statement on exactly one line without blank lines, escaping line breaks in values, for consumers reading the dump line by line (default false)")
+	flag.BoolVar(&opt.probeTypes, "probe-types", defaultProbeTypes, "sample a few rows of every dumped table and list the columns whose type the driver reports empty, with the sampled values and how they would be written, then exit without dumping anything. use -cast to fix the type of such columns (default false)")
+	flag.BoolVar(&opt.materializeViews, "materialize-views", defaultMaterializeViews, "dump each view as a table with the rows the view returns at the time of the dump instead of CREATE VIEW, for targets which can not evaluate the view definition (default false)")
+	flag.BoolVar(&opt.dumpStatistics, "dump-statistics", defaultDumpStatistics, "write row count, size and column min/max of each table to <db>.statistics.json (default false)")
+	flag.BoolVar(&opt.failOnEmpty, "fail-on-empty", defaultFailOnEmpty, fmt.Sprintf("exit with code %d if no table or view was dumped (default false)", exitCodeEmpty))
+	flag.BoolVar(&opt.safeColumns, "safe-columns", defaultSafeColumns, "name the columns in every INSERT and LOAD DATA statement, so the data is restored right into a table whose columns are in another order, and warn if the columns are read in another order than their definition (default false)")
+	flag.BoolVar(&opt.failOnLossy, "fail-fast-on-lossy", defaultFailOnLossy, "fail on the first column whose type is unknown to mo-dump, instead of guessing how to write its values (default false)")
+	flag.BoolVar(&opt.sequences, "sequences", defaultSequences, "dump the sequences of each database, CREATE SEQUENCE before its tables and setval restoring their current value after its data")
+	flag.BoolVar(&opt.noSequences, "no-sequences", defaultNoSequences, "do not dump the sequences (default false)")
+	flag.StringVar(&opt.checksumAlgorithm, "checksum-algorithm", "", "write a checksum of the data of each table after it, with crc32, sha256 or xxhash. it does not depend on the row order")
+	flag.BoolVar(&opt.rowChecksums, "row-checksums", defaultRowChecksums, "also write the checksum of each row to db_tbl.rowsums, one per line in dump order. requires the option 'checksum-algorithm' (default false)")
+	flag.StringVar(&opt.excludeColumnsSpec, "exclude-columns-regexp", "", "leave out the columns whose names match the regular expression from the data of every table, e.g. \"^(created_at|updated_at|_internal_.*)$\". the INSERT and LOAD DATA statements name their columns")
+	flag.StringVar(&opt.sortSpec, "sort-for-compression", "", "order the rows of the tables by the given columns, e.g. \"t1:status,country;t2:kind\", so that similar values are close together and the dump compresses better. the row order changes with the data, do not use it for dumps compared with diff")
+	flag.BoolVar(&opt.ignoreErrors, "ignore-errors", defaultIgnoreErrors, "skip objects that can not be dumped, such as tables of unsupported kind or tables whose data fails to dump, with a warning instead of failing (default false)")
+	flag.IntVar(&opt.retryFailed, "retry-failed", defaultRetryFailed, "retry the tables skipped by ignore-errors up to this many passes after the dump, with backoff. requires the option 'ignore-errors'")
+	flag.BoolVar(&opt.addLocks, "add-locks", defaultAddLocks, "surround each table's data with LOCK TABLES and UNLOCK TABLES statements (default false)")
+	flag.DurationVar(&opt.deadline, "deadline", 0, fmt.Sprintf("stop the dump after this duration, e.g. 2h. the statements written so far are complete, a comment marks the end of the dump and mo-dump exits with code %d (default no deadline)", exitCodeTruncated))
+	flag.StringVar(&opt.consistency, "consistency", consistencyNone, "how to get a consistent dump: none, snapshot (one transaction), lock (LOCK TABLES ... READ per database) or flush (FLUSH TABLES WITH READ LOCK)")
+	flag.BoolVar(&opt.consistencyFallback, "consistency-fallback", defaultConsistencyFallback, "fall back to the next best consistency if the server does not support the requested one, otherwise fail")
+	flag.BoolVar(&opt.capturePosition, "capture-position", defaultCapturePosition, "emit the position of the dumped snapshot at the top of the dump for CDC consumers, requires -consistency snapshot (default false)")
+	flag.StringVar(&opt.where, "where", "", "dump only rows selected by the condition, the same for every table, or by the conditions of the tables given as tbl:cond,db.tbl:cond. tables without a condition are dumped in full. ${now}, ${now-Nd} and ${now-Nh} are replaced by datetime literals of the start time")
+	flag.StringVar(&opt.whereInSpec, "where-in", "", "dump only rows of table tbl whose column col is one of the values in the file, one value per line. format: tbl.col:/path/to/values.txt")
+	flag.StringVar(&opt.castSpec, "cast", "", "override the column type the driver reports, which decides how values are formatted. format: tbl.col:type;tbl.col:type, e.g. t1.id:uuid;t1.flag:bool")
+	flag.BoolVar(&opt.progress, "progress", defaultProgress, "report the rows written of each table against its row count on stderr every few seconds. the rows are counted before the data is read (default false)")
+	flag.IntVar(&opt.parallel, "parallel", defaultParallel, "dump the data of this many tables at once, each on a connection of its own. tables are handed out in table order as earlier ones are written, at most this many are buffered in memory, requires -consistency none")
+	flag.StringVar(&opt.groupBySpec, "group-by", "", "order the rows of the tables by a shard column, e.g. \"t1:tenant_id;t2:region\", and write a /* shard=<value> */ comment before the INSERTs of each value, so that a sharding-aware restore can route them. format: tbl:col")
+	flag.StringVar(&opt.chunkTableSpec, "chunk-table", "", "split the integer primary key range of one table into N chunks which are read in parallel and written in key order. format: tbl:pk:N, requires -consistency none")
+	flag.StringVar(&opt.stamp.table, "stamp-table", "", "append an INSERT into this tracking table at the end of the dump, recording -stamp-version, the dump time and the source")
+	flag.StringVar(&opt.stamp.version, "stamp-version", "", "version of the dump recorded in -stamp-table")
+	flag.BoolVar(&opt.stamp.create, "create-stamp-table", defaultCreateStampTable, "create -stamp-table if it does not exist before the INSERT (default false)")
+	flag.StringVar(&opt.txnRangeSpec, "txn-range", "", "only dump rows committed inside [lo, hi), given as physical timestamps lo:hi. either bound may be omitted. requires row level commit metadata of the server")
+	flag.StringVar(&opt.window.column, "time-column", "", "only dump rows whose value of this column is inside [-from, -to). partitions outside the window are pruned")
+	flag.StringVar(&opt.window.from, "from", "", "inclusive lower bound of the -time-column window")
+	flag.StringVar(&opt.window.to, "to", "", "exclusive upper bound of the -time-column window")
+	flag.StringVar(&opt.resultFile, "o", "", "write the dump to this file instead of the standard output. the data files are put in its directory")
+	flag.StringVar(&opt.resultFile, "result-file", "", "same as -o")
+	flag.BoolVar(&opt.compress, "compress", defaultCompress, "gzip the dump, and the csv files as with -csv-compress gzip. the summary is written to stderr (default false)")
+	flag.BoolVar(&opt.compress, "gzip", defaultCompress, "same as -compress")
+	flag.BoolVar(&opt.splitSchemaData, "split-schema-data", defaultSplitSchemaData, "write the DDL of all databases to schema.sql and the data statements to data.sql in the working directory, so the schema can be restored and checked before the data is loaded (default false)")
+	flag.BoolVar(&opt.signFiles, "sign-files", defaultSignFiles, "end the SQL files with a comment holding the SHA-256 of their content, and write a <file>.sha256 for each data file. requires -o or -split-schema-data (default false)")
+	flag.StringVar(&opt.verifyFile, "verify-file", "", "check a file written with -sign-files against its checksum and exit")
+	flag.BoolVar(&opt.includeTemporary, "include-temporary", defaultIncludeTemporary, "also dump the temporary tables visible to the session of mo-dump as CREATE TEMPORARY TABLE. temporary tables belong to the session that created them, so usually none is visible (default false)")
+	flag.BoolVar(&opt.estimateSize, "estimate-size", defaultEstimateSize, "estimate the size of the data of every dumped table from a sample of its rows written as INSERT statements or csv and its row count, then exit without dumping anything (default false)")
+	flag.BoolVar(&opt.completeInserts, "complete-insert", defaultCompleteInsert, "name the columns in every INSERT and LOAD DATA statement, so the data is restored right into a table whose columns are in another order (default false)")
+	flag.IntVar(&opt.parallelSchemaFetch, "parallel-schema-fetch", defaultParallelSchemaFetch, "read the DDL of this many tables at once before the dump of each database, on connections of their own. requires -consistency none")
+	flag.StringVar(&opt.insertMode, "insert-mode", insertModeInsert, "the statement of the rows, insert, ignore or replace. ignore writes INSERT IGNORE INTO to skip the rows which conflict with the rows of the table, replace writes REPLACE INTO to overwrite them")
+	flag.BoolVar(&opt.fullAccount, "full-account", defaultFullAccount, "dump the whole account of the connection for a restore: the roles and users, all databases with their routines, the publications and subscriptions, then the grants. can not be used with -db or -tbl (default false)")
+	flag.Int64Var(&opt.accountID, "account-id", -1, "with -full-account, fail unless the connection belongs to this account")
+	flag.BoolVar(&opt.singleTransaction, "single-transaction", defaultSingleTransaction, "read all tables in one transaction as -consistency snapshot, and wrap the data statements of each table in BEGIN and COMMIT so a failed restore leaves no table half loaded. can not be used with -add-locks or -parallel (default false)")
+	flag.BoolVar(&opt.orderByPrimaryKey, "order-by-primary-key", defaultOrderByPrimaryKey, "read the rows of each table ordered by its primary key, after the columns of -group-by and -sort-for-compression, so dumps of the same data are identical. tables without primary key are read unordered with a warning (default false)")
+	flag.StringVar(&opt.chunkSize, "chunk-size", "", "with -csv or -format tsv, roll the file of a table over to db_tbl.0001.csv, db_tbl.0002.csv and so on when it reaches the size, with a LOAD DATA statement for each file. the size is bytes before compression with an optional K, M or G suffix, or rows such as 100000rows")
+	flag.StringVar(&opt.resume, "resume", "", "record the tables whose output is complete in this checkpoint file. run again with the same options after an interruption, the complete tables are skipped and the -o file is continued from the last of them. the file is removed when the dump completes. requires -o")
+	flag.Var(&opt.dataTables, "data-tables", "dump the definition and the data of this table, also with -no-data. given as db.table or as table for every database. may be repeated or comma separated")
+	flag.Var(&opt.schemaOnlyTables, "schema-only-tables", "dump the definition of this table without its data. given as db.table or as table for every database. may be repeated or comma separated")
+	flag.BoolVar(&opt.lockTables, "lock-tables", defaultLockTables, "hold LOCK TABLES ... READ on the tables of each database while it is dumped, same as -consistency lock. can not be used with -single-transaction or -no-lock (default false)")
+	flag.BoolVar(&opt.noLock, "no-lock", defaultNoLock, "read the tables without any lock or transaction, same as -consistency none. concurrent writes may make the tables inconsistent with each other. can not be used with -single-transaction or -lock-tables (default false)")
+	flag.IntVar(&opt.retries, "retries", defaultRetries, "retry a query up to this many times when the connection is lost or times out, such as the queries of the table list, the DDL and the start of the data of each table. a failure in the middle of the data is not retried. requires -consistency none")
+	flag.DurationVar(&opt.retryInterval, "retry-interval", defaultRetryInterval, "the wait before the first retry of -retries, doubled for every next retry")
+	flag.DurationVar(&opt.queryTimeout, "query-timeout", defaultQueryTimeout, "fail a query which takes longer than this, from its start to its last row, such as the data of a table or the table list. 0 for no timeout")
+	flag.StringVar(&opt.accountsSpec, "accounts", "", "dump the databases of -db from each of these comma separated accounts in turn, logging in to each as account#user with the same password. the output of each account starts with a comment naming it. INSERT output only")
+	flag.BoolVar(&opt.fkOrder, "fk-order", defaultFKOrder, "dump the tables of each database in the order of their foreign keys, the referenced tables before the tables referencing them, so the dump restores with the foreign key checks on. on a cycle of foreign keys the order is kept with a warning (default false)")
+	flag.BoolVar(&opt.noFKToggle, "no-fk-toggle", defaultNoFKToggle, "leave out SET FOREIGN_KEY_CHECKS = 0 at the start and = 1 at the end of the split files and the load script, so the restore keeps the foreign key checks of the target. the data of self-referencing tables under -fk-order keeps its own toggle (default false)")
+	flag.StringVar(&opt.excludeDatabasesSpec, "exclude-database", "", "leave out the databases matching these comma separated shell style globs, e.g. \"app_test*,tmp_?\"")
+	flag.BoolVar(&opt.allowEmptyMatch, "allow-empty-match", defaultAllowEmptyMatch, "warn instead of failing when a table pattern of -tbl matches no table of a database (default false)")
 	flag.Parse()
 
 	flag.Usage = usage
@@ -110,6 +387,22 @@ func main() {
 		flag.Usage()
 		return
 	}
+	if opt.verifyFile != "" {
+		err = verifyFile(opt.verifyFile)
+		if err == nil {
+			fmt.Printf("%s: OK\n", opt.verifyFile)
+		}
+		return
+	}
+
+	opt.password, err = resolvePassword(ctx, opt.password, flagSet("p"), opt.passwordStdin, os.Stdin)
+	if err != nil {
+		return
+	}
+	err = opt.ssl.load(ctx)
+	if err != nil {
+		return
+	}
 
 	if opt.netBufferLength < minNetBufferLength {
 		fmt.Fprintf(os.Stderr, "net_buffer_length must be greater than %d, set to %d\n", minNetBufferLength, minNetBufferLength)
@@ -127,6 +420,11 @@ func main() {
 	if len(opt.tbl) > 0 {
 		tbls := strings.Split(opt.tbl, ",")
 		for _, t := range tbls {
+			if isPattern(t) {
+				if _, err = parsePatterns(ctx, "tbl", t); err != nil {
+					return
+				}
+			}
 			if len(t) != 0 {
 				opt.tables = append(opt.tables, Table{t, ""})
 			}
@@ -140,135 +438,1144 @@ func main() {
 	//password can have ":".
 	opt.username = strings.ReplaceAll(opt.username, ":", "#")
 
-	// if host has ":", reports error
-	if strings.Count(opt.host, ":") > 0 {
-		err = moerr.NewInvalidInput(ctx, "host can not have character ':'")
+	// the host and the port are not used with a Unix socket
+	host := opt.host
+	if opt.socket == "" {
+		opt.hosts, err = parseHosts(ctx, opt.host)
+		if err != nil {
+			return
+		}
+		host = opt.hosts[0]
+	}
+
+	_, err = opt.dsn(ctx, host, "")
+	if err != nil {
+		return
+	}
+
+	if opt.noSequences {
+		opt.sequences = false
+	}
+
+	for _, db := range opt.dbs {
+		if isPattern(db) {
+			if _, err = parsePatterns(ctx, "db", db); err != nil {
+				return
+			}
+		}
+	}
+	if opt.excludeDatabasesSpec != "" {
+		opt.excludeDatabases, err = parsePatterns(ctx, "exclude-database", opt.excludeDatabasesSpec)
+		if err != nil {
+			return
+		}
+	}
+
+	if opt.fullAccount {
+		if opt.database != "" || opt.tbl != "" || opt.truncate || opt.excludeDatabases != nil {
+			err = moerr.NewInvalidInput(ctx, "full-account dumps all databases of the account, it can not be used with -db, -tbl, -exclude-database or -truncate")
+			return
+		}
+		opt.routines = true
+	}
+
+	if opt.accountsSpec != "" {
+		opt.accounts, err = parseAccounts(ctx, opt.accountsSpec)
+		if err != nil {
+			return
+		}
+		if strings.Contains(opt.username, "#") {
+			err = moerr.NewInvalidInput(ctx, "accounts logs in to each account as account#user, the user %s names an account already", opt.username)
+			return
+		}
+		if opt.fullAccount || opt.inspectOnly() || opt.resume != "" || opt.loadScriptPath != "" || opt.format != formatSQL || opt.toCsv {
+			err = moerr.NewInvalidInput(ctx, "accounts only supports INSERT output, it can not be used with -full-account, -resume, -load-script or the inspection options")
+			return
+		}
+	}
+
+	if opt.truncate && opt.noData {
+		err = moerr.NewInvalidInput(ctx, "option truncate can not be used with no-data")
+		return
+	}
+	err = checkDataTables(ctx, opt.dataTables, opt.schemaOnlyTables)
+	if err != nil {
+		return
+	}
+
+	switch opt.format {
+	case formatSQL:
+	case formatMongoJSON, formatNDJSON, formatPrepared, formatFramed, formatTSV:
+		if opt.toCsv {
+			err = moerr.NewInvalidInput(ctx, "option csv can not be used with format %s", opt.format)
+			return
+		}
+	default:
+		err = moerr.NewInvalidInput(ctx, "unsupported format %s", opt.format)
+		return
+	}
+
+	if opt.format == formatTSV {
+		if opt.csvQuoteAll {
+			err = moerr.NewInvalidInput(ctx, "option csv-quote-all can not be used with format %s", opt.format)
+			return
+		}
+		opt.csvConf.tsv = true
+	}
+	if opt.toCsv || opt.csvConf.tsv {
+		opt.csvConf.enable = true
+		opt.csvConf.quoteAll = opt.csvQuoteAll
+		opt.csvConf.validateUTF8 = opt.validateUTF8
+		if opt.loadScriptPath != "" {
+			opt.loadScript = &loadScript{path: opt.loadScriptPath, noFKToggle: opt.noFKToggle}
+		}
+		if opt.compress && opt.csvCompress == "" {
+			opt.csvCompress = csvCompressGzip
+		}
+		switch opt.csvCompress {
+		case "", csvCompressGzip:
+			opt.csvConf.compress = opt.csvCompress
+		default:
+			err = moerr.NewInvalidInput(ctx, "unsupported csv compression %s", opt.csvCompress)
+			return
+		}
+		opt.csvConf.fieldDelimiter, err = checkFieldDelimiter(ctx, opt.csvFieldDelimiterStr)
+		if err != nil {
+			return
+		}
+	}
+	if opt.chunkSize != "" {
+		if !opt.csvConf.enable {
+			err = moerr.NewInvalidInput(ctx, "option chunk-size only supports -csv and -format tsv")
+			return
+		}
+		opt.csvConf.chunk, err = parseChunkSize(ctx, opt.chunkSize)
+		if err != nil {
+			return
+		}
+	}
+
+	err = opt.window.check(ctx)
+	if err != nil {
+		return
+	}
+
+	err = checkValidateUTF8(ctx, opt.validateUTF8, opt.csvConf.enable)
+	if err != nil {
+		return
+	}
+
+	err = checkInsertMode(ctx, opt.insertMode)
+	if err != nil {
+		return
+	}
+
+	err = checkJSONMode(ctx, opt.jsonMode, opt.csvConf.enable)
+	if err != nil {
+		return
+	}
+
+	err = opt.stamp.check(ctx)
+	if err != nil {
+		return
+	}
+
+	if opt.txnRangeSpec != "" {
+		opt.txnRange, err = parseTxnRange(ctx, opt.txnRangeSpec)
+		if err != nil {
+			return
+		}
+	}
+
+	opt.where, opt.tableWhere, err = parseWhere(ctx, opt.where, dumpStart)
+	if err != nil {
+		return
+	}
+
+	if opt.whereInSpec != "" {
+		opt.whereIn, err = parseWhereIn(ctx, opt.whereInSpec)
+		if err != nil {
+			return
+		}
+	}
+
+	if opt.maxOpenFiles != 0 {
+		if opt.maxOpenFiles < minOpenFiles {
+			err = moerr.NewInvalidInput(ctx, "max-open-files must be at least %d, got %d", minOpenFiles, opt.maxOpenFiles)
+			return
+		}
+		openFiles = newFileLimiter(opt.maxOpenFiles)
+	}
+
+	if opt.estimateSize && opt.format != formatSQL && !opt.csvConf.tsv {
+		err = moerr.NewInvalidInput(ctx, "option estimate-size only supports INSERT and csv output")
+		return
+	}
+
+	if opt.chunkTableSpec != "" {
+		if opt.format != formatSQL || opt.toCsv {
+			err = moerr.NewInvalidInput(ctx, "option chunk-table only supports INSERT output")
+			return
+		}
+		opt.chunkTable, err = parseChunkTable(ctx, opt.chunkTableSpec)
+		if err != nil {
+			return
+		}
+	}
+
+	if opt.groupBySpec != "" {
+		if opt.format != formatSQL || opt.toCsv {
+			err = moerr.NewInvalidInput(ctx, "option group-by only supports INSERT output")
+			return
+		}
+		opt.groupBy, err = parseGroupBy(ctx, opt.groupBySpec)
+		if err != nil {
+			return
+		}
+		if opt.chunkTable != nil {
+			if _, ok := opt.groupBy[opt.chunkTable.table]; ok {
+				err = moerr.NewInvalidInput(ctx, "table %s can not be given to both chunk-table and group-by", opt.chunkTable.table)
+				return
+			}
+		}
+	}
+
+	if opt.sortSpec != "" {
+		opt.sortForCompression, err = parseSortForCompression(ctx, opt.sortSpec)
+		if err != nil {
+			return
+		}
+	}
+
+	if opt.excludeColumnsSpec != "" {
+		opt.excludeColumns, err = regexp.Compile(opt.excludeColumnsSpec)
+		if err != nil {
+			err = moerr.NewInvalidInput(ctx, "invalid exclude-columns-regexp %s: %v", opt.excludeColumnsSpec, err)
+			return
+		}
+	}
+
+	if opt.castSpec != "" {
+		opt.casts, err = parseCasts(ctx, opt.castSpec)
+		if err != nil {
+			return
+		}
+	}
+
+	if opt.postFileCommand != "" {
+		opt.fileHook = newFileHook(opt.postFileCommand, opt.postFileConcurrency, opt.ignoreHookErrors)
+	}
+
+	if opt.retries < 0 || opt.retryInterval < 0 {
+		err = moerr.NewInvalidInput(ctx, "retries and retry-interval must be non-negative, got %d and %v", opt.retries, opt.retryInterval)
+		return
+	}
+	connRetry = retryPolicy{retries: opt.retries, interval: opt.retryInterval}
+	if opt.queryTimeout < 0 {
+		err = moerr.NewInvalidInput(ctx, "query-timeout must be non-negative, got %v", opt.queryTimeout)
+		return
+	}
+	queryTimeout = opt.queryTimeout
+	if opt.retryFailed < 0 {
+		err = moerr.NewInvalidInput(ctx, "retry-failed must be non-negative, got %d", opt.retryFailed)
+		return
+	}
+	if opt.retryFailed > 0 && !opt.ignoreErrors {
+		err = moerr.NewInvalidInput(ctx, "retry-failed requires ignore-errors")
+		return
+	}
+
+	err = opt.charset.check(ctx)
+	if err != nil {
+		return
+	}
+
+	err = checkChecksum(ctx, opt.checksumAlgorithm, opt.rowChecksums)
+	if err != nil {
+		return
+	}
+	if opt.rowChecksums && opt.chunkTable != nil {
+		err = moerr.NewInvalidInput(ctx, "row-checksums can not be used with chunk-table")
+		return
+	}
+
+	err = checkRowCountComments(ctx, opt.rowCountComments)
+	if err != nil {
+		return
+	}
+
+	err = checkDumpOrder(ctx, opt.dumpOrder)
+	if err != nil {
+		return
+	}
+
+	if opt.singleTransaction {
+		if flagSet("consistency") && opt.consistency != consistencySnapshot {
+			err = moerr.NewInvalidInput(ctx, "single-transaction reads with consistency %s, it can not be used with consistency %s", consistencySnapshot, opt.consistency)
+			return
+		}
+		if opt.addLocks || opt.parallel > 1 {
+			err = moerr.NewInvalidInput(ctx, "single-transaction can not be used with -add-locks or -parallel")
+			return
+		}
+		opt.consistency = consistencySnapshot
+	}
+	err = opt.checkLockOptions(ctx)
+	if err != nil {
+		return
+	}
+	err = checkConsistency(ctx, opt.consistency)
+	if err != nil {
+		return
+	}
+	if opt.skipEmptyTables && opt.skipEmptyData {
+		err = moerr.NewInvalidInput(ctx, "skip-empty-tables and skip-empty-data-only can not be used together")
+		return
+	}
+	if opt.parallel < 1 {
+		err = moerr.NewInvalidInput(ctx, "parallel must be at least 1, got %d", opt.parallel)
+		return
+	}
+	if opt.parallelSchemaFetch < 1 {
+		err = moerr.NewInvalidInput(ctx, "parallel-schema-fetch must be at least 1, got %d", opt.parallelSchemaFetch)
+		return
+	}
+	err = opt.checkSessionOptions(ctx)
+	if err != nil {
+		return
+	}
+	if opt.capturePosition && opt.consistency != consistencySnapshot {
+		err = moerr.NewInvalidInput(ctx, "capture-position requires consistency %s", consistencySnapshot)
+		return
+	}
+
+	if opt.splitSchemaData && (opt.resultFile != "" || opt.compress) {
+		err = moerr.NewInvalidInput(ctx, "split-schema-data can not be used with -o or -compress")
+		return
+	}
+	if opt.signFiles && (opt.resultFile == "" && !opt.splitSchemaData || opt.compress) {
+		err = moerr.NewInvalidInput(ctx, "sign-files requires -o or -split-schema-data and can not be used with -compress")
+		return
+	}
+	if opt.resume != "" && (opt.resultFile == "" || opt.fullAccount || opt.loadScriptPath != "" || opt.signFiles) {
+		err = moerr.NewInvalidInput(ctx, "resume requires -o and can not be used with -full-account, -load-script or -sign-files")
+		return
+	}
+	if opt.resume != "" && !opt.inspectOnly() {
+		opt.checkpoint, err = loadCheckpoint(opt.resume)
+		if err != nil {
+			return
+		}
+		out, opt.dataDir, err = opt.checkpoint.openResult(opt.resultFile)
+		if err != nil {
+			return
+		}
+		opt.out = out
+		opt.csvConf.dir = opt.dataDir
+	} else if opt.resultFile != "" {
+		out, opt.dataDir, err = openResultFile(opt.resultFile)
+		if err != nil {
+			return
+		}
+		opt.out = out
+		opt.csvConf.dir = opt.dataDir
+	} else if opt.splitSchemaData && !opt.inspectOnly() {
+		schema, out, err = openSplitFiles(".")
+		if err != nil {
+			return
+		}
+		opt.schemaOut = schema
+		opt.out = out
+	} else {
+		err = checkStdout(ctx, os.Stdout, opt.inspectOnly() || opt.forceStdout)
+		if err != nil {
+			return
+		}
+	}
+
+	if (opt.expandsDatabases() || opt.fullAccount) && opt.accounts == nil {
+		conn, err = opt.openDBConnection(ctx, "")
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		if opt.fullAccount {
+			err = opt.checkAccount(ctx)
+			if err != nil {
+				return
+			}
+			opt.dbs, opt.subscriptions, err = getAccountDatabases(ctx)
+		} else {
+			opt.dbs, err = opt.expandDatabases(ctx, opt.dbs)
+		}
+		if err != nil {
+			return
+		}
+	}
+
+	if opt.reportOnly {
+		err = opt.showReport(ctx, os.Stdout)
+		return
+	}
+	if opt.listKinds {
+		err = opt.showKinds(ctx, os.Stdout)
 		return
 	}
+	if opt.probeTypes {
+		err = opt.showProbeTypes(ctx, os.Stdout)
+		return
+	}
+	if opt.estimateSize {
+		err = opt.showEstimate(ctx, os.Stdout)
+		return
+	}
+
+	if opt.signFiles && opt.out != nil {
+		s := newSigner(opt.out)
+		opt.out = s
+		signers = append(signers, s)
+		if opt.schemaOut != nil {
+			s = newSigner(opt.schemaOut)
+			opt.schemaOut = s
+			signers = append(signers, s)
+		}
+	}
+	if opt.compress {
+		gz = newGzipMembers(opt.stdout())
+		opt.out = gz
+	}
+	if opt.singleLine {
+		opt.out = newLineWriter(opt.stdout())
+		if opt.schemaOut != nil {
+			opt.schemaOut = newLineWriter(opt.schemaOut)
+		}
+	}
+
+	if opt.fullAccount {
+		err = opt.dumpFullAccount(ctx)
+	} else if opt.accounts != nil {
+		err = opt.dumpAccounts(ctx)
+	} else {
+		err = opt.dumpData(ctx)
+	}
+	if err != nil {
+		return
+	}
+}
+
+func (opt *Options) dumpData(ctx context.Context) (err error) {
+	var (
+		createDb    string
+		createTable []string
+	)
+
+	if conn == nil {
+		conn, err = opt.openDBConnection(ctx, opt.dbs[0])
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+	}
+
+	// the dump is done when the commands of the files are done
+	defer func() {
+		if e := opt.fileHook.wait(); e != nil && err == nil {
+			err = e
+		}
+	}()
+
+	opt.consistency, err = resolveConsistency(ctx, opt.consistency, opt.consistencyFallback)
+	if err != nil {
+		return err
+	}
+	err = beginConsistency(ctx, opt.consistency)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if e := endConsistency(ctx, opt.consistency); e != nil && err == nil {
+			err = e
+		}
+	}()
+	// the keepalive stops before the pinned connection is given back
+	stopKeepAlive := func() {}
+	if opt.keepAliveInterval > 0 {
+		stopKeepAlive = startKeepAlive(ctx, keepAlivePing(), opt.keepAliveInterval)
+	}
+	defer func() { stopKeepAlive() }()
+	if opt.schemaHashFile != "" {
+		err = opt.checkSchemaHash(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	if opt.capturePosition {
+		if opt.consistency != consistencySnapshot {
+			return moerr.NewNotSupported(ctx, "capture-position without consistency %s", consistencySnapshot)
+		}
+		var pos string
+		pos, err = capturePosition(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(opt.stdout(), "/* MODUMP POSITION: %s */\n\n", pos)
+	}
+
+	opt.foreignKeyChecks(false)
+
+	if opt.addLocks && !supportLockTables(ctx) {
+		fmt.Fprintf(os.Stderr, "server does not support LOCK TABLES, ignore option add-locks\n")
+		opt.addLocks = false
+	}
+
+	// the deadline interrupts the data queries, the metadata queries and
+	// the end of the consistency still run
+	dataCtx, cancel := opt.withDeadline(ctx)
+	defer cancel()
+
+	// getTables resolves the requested tables in place, keep the request
+	// for the next database
+	requested := opt.tables
+	for _, db := range opt.dbs {
+		if opt.deadlineExceeded(dataCtx) {
+			break
+		}
+		if opt.checkpoint.doneDatabase(db) {
+			opt.dumpedObjects += opt.checkpoint.count[db]
+			continue
+		}
+		// the DDL of a resumed database is in the result file already
+		resumed := opt.checkpoint.started(db)
+		if opt.verifyConn {
+			var reconnected bool
+			reconnected, err = opt.verifyConnection(ctx, db)
+			if err != nil {
+				return err
+			}
+			if reconnected && opt.keepAliveInterval > 0 {
+				stopKeepAlive()
+				stopKeepAlive = startKeepAlive(ctx, keepAlivePing(), opt.keepAliveInterval)
+			}
+		}
+		opt.tables = append(Tables(nil), requested...)
+		if opt.emptyTables { //dump all tables
+			if !opt.truncate && !resumed {
+				createDb, err = getCreateDB(ctx, db)
+				if err != nil {
+					return err
+				}
+				createDb = opt.charset.rewriteDB(createDb)
+				if opt.safeRestore {
+					createDb = createDBIfNotExists(createDb)
+				} else {
+					fmt.Fprintf(opt.schema(), "DROP DATABASE IF EXISTS %s;\n", quoteIdent(db))
+				}
+				fmt.Fprintln(opt.schema(), createDb, ";")
+			}
+			opt.useDatabase(db)
+		}
+		if opt.dumpRoutines() && !resumed {
+			err = showFunctions(ctx, opt.schema(), db)
+			if err != nil {
+				return err
+			}
+		}
+		opt.tables, err = getTables(ctx, db, opt.tables, opt.ignoreTables, opt.skipMissingTables, opt.allowEmptyMatch, opt.includeTemporary)
+		if err != nil {
+			return err
+		}
+		if opt.includeTemporary {
+			opt.temporaryTables, err = getTemporaryTables(ctx, db)
+			if err != nil {
+				return err
+			}
+		}
+		var sequences []string
+		if opt.sequences {
+			sequences = sequenceNames(opt.tables)
+		}
+		opt.tables, err = opt.filterTableKinds(ctx, db, opt.tables)
+		if err != nil {
+			return err
+		}
+		if opt.consistency == consistencyLock {
+			err = lockTables(ctx, db, opt.tables)
+			if err != nil {
+				return err
+			}
+		}
+		if !opt.truncate && !resumed {
+			err = showCreateSequences(ctx, opt.schema(), db, sequences)
+			if err != nil {
+				return err
+			}
+		}
+		var sizes map[string]int64
+		if opt.dumpOrder == dumpOrderSizeAsc || opt.dumpOrder == dumpOrderSizeDesc {
+			sizes, err = getTableSizes(ctx, db)
+			if err != nil {
+				return err
+			}
+		}
+		sortTables(opt.tables, opt.dumpOrder, sizes)
+		var selfReferencing map[string]bool
+		if opt.fkOrder {
+			selfReferencing, err = orderByForeignKeys(ctx, db, opt.tables)
+			if err != nil {
+				return err
+			}
+		}
+		left := moveViewsLast(opt.tables)
+		createTable, err = getCreateTables(db, opt.tables, opt.parallelSchemaFetch)
+		if err != nil {
+			return err
+		}
+		for i, tbl := range opt.tables {
+			if tbl.Kind != catalog.SystemViewRel {
+				createTable[i] = opt.charset.rewrite(createTable[i])
+				if opt.normalizeDDL {
+					createTable[i] = normalizeDDL(createTable[i])
+				}
+			}
+		}
+		bufPool := &sync.Pool{
+			New: func() any {
+				return &bytes.Buffer{}
+			},
+		}
+		adjustViewOrder(createTable, opt.tables, left)
+		// the workers read the data of the next tables while the dump
+		// writes it in table order
+		var parallel *parallelData
+		if opt.parallel > 1 {
+			parallel, err = opt.startParallelData(dataCtx, db, opt.tables, bufPool)
+			if err != nil {
+				return err
+			}
+			defer parallel.close()
+		}
+	tables:
+		for i, create := range createTable {
+			if opt.deadlineExceeded(dataCtx) {
+				break
+			}
+			err = opt.endTableOutput()
+			if err != nil {
+				return err
+			}
+			tbl := opt.tables[i]
+			if opt.checkpoint.done(db, tbl.Name) {
+				opt.dumpedObjects++
+				continue
+			}
+			if err = opt.checkWithData(ctx, db, tbl.Name); err != nil {
+				return err
+			}
+			if opt.materializeViews && tbl.Kind == catalog.SystemViewRel {
+				err = opt.materializeView(dataCtx, db, tbl.Name, bufPool)
+				if err != nil {
+					if opt.deadlineExceeded(dataCtx) {
+						break
+					}
+					return err
+				}
+				opt.dumpedObjects++
+				opt.lastTable = quoteIdent(db) + "." + quoteIdent(tbl.Name)
+				if err = opt.tableDone(db, tbl.Name); err != nil {
+					return err
+				}
+				continue
+			}
+			if opt.truncate && tbl.Kind != catalog.SystemOrdinaryRel {
+				// views and external tables have no data to reload
+				continue
+			}
+			switch tbl.Kind {
+			case catalog.SystemOrdinaryRel:
+				withData := opt.withData(db, tbl.Name)
+				if opt.truncate && !withData {
+					// a table without data is not emptied for the reload
+					continue
+				}
+				var output *tableOutput
+				if parallel != nil && withData {
+					output = parallel.wait(tbl.Name)
+				}
+				var empty bool
+				if opt.skipEmptyTables || opt.skipEmptyData {
+					if output != nil {
+						// the worker checked it before reading the data
+						empty = output.empty
+					} else {
+						empty, err = isEmptyTable(dataCtx, db, tbl.Name)
+					}
+					if err != nil {
+						return err
+					}
+					if empty && opt.skipEmptyTables {
+						fmt.Fprintf(os.Stderr, "skip empty table `%s`.`%s`\n", db, tbl.Name)
+						continue
+					}
+				}
+				if opt.truncate {
+					fmt.Fprintf(opt.stdout(), "TRUNCATE TABLE %s;\n", quoteIdent(tbl.Name))
+				} else {
+					if opt.temporaryTables[tbl.Name] {
+						create = temporaryCreate(create)
+					}
+					fmt.Fprintf(opt.schema(), "DROP TABLE IF EXISTS %s;\n", quoteIdent(tbl.Name))
+					showCreateTable(opt.schema(), create, opt.splitSchema() || empty)
+				}
+				if withData && !empty {
+					if selfReferencing[tbl.Name] {
+						opt.tableForeignKeyChecks(db, false)
+					}
+					if output != nil {
+						err = opt.writeTableOutput(db, output)
+					} else {
+						err = opt.dumpTableData(dataCtx, db, tbl.Name, bufPool)
+					}
+					if selfReferencing[tbl.Name] {
+						opt.tableForeignKeyChecks(db, true)
+					}
+					if err != nil {
+						if opt.deadlineExceeded(dataCtx) {
+							fmt.Fprintf(os.Stderr, "data of table `%s`.`%s` is incomplete: %v\n", db, tbl.Name, err)
+							break tables
+						}
+						if !opt.ignoreErrors {
+							return err
+						}
+						opt.skipFailedTable(db, tbl.Name, err)
+					}
+				}
+			case catalog.SystemExternalRel:
+				fmt.Fprintf(opt.schema(), "/*!EXTERNAL TABLE %s*/\n", quoteIdent(tbl.Name))
+				fmt.Fprintf(opt.schema(), "DROP TABLE IF EXISTS %s;\n", quoteIdent(tbl.Name))
+				showCreateTable(opt.schema(), create, true)
+			case catalog.SystemViewRel:
+				fmt.Fprintf(opt.schema(), "DROP VIEW IF EXISTS %s;\n", quoteIdent(tbl.Name))
+				showCreateTable(opt.schema(), create, true)
+			default:
+				return unsupportedKindError(ctx, db, tbl)
+			}
+			opt.dumpedObjects++
+			opt.lastTable = quoteIdent(db) + "." + quoteIdent(tbl.Name)
+			if err = opt.tableDone(db, tbl.Name); err != nil {
+				return err
+			}
+		}
+		if parallel != nil {
+			parallel.close()
+		}
+		if opt.dumpRoutines() && !opt.truncated {
+			err = showProcedures(ctx, opt.schema(), db)
+			if err != nil {
+				return err
+			}
+		}
+		if !opt.truncated {
+			err = showSequenceValues(ctx, opt.stdout(), db, sequences)
+			if err != nil {
+				return err
+			}
+			if opt.dumpStatistics {
+				err = dumpStatistics(ctx, opt.stdout(), opt.dataDir, db, opt.tables)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		if opt.consistency == consistencyLock {
+			err = unlockTables(ctx)
+			if err != nil {
+				return err
+			}
+		}
+		if !opt.truncated {
+			if err = opt.databaseDone(db); err != nil {
+				return err
+			}
+		}
+	}
+	// the retries and the stamp belong to a complete dump
+	if len(opt.failedTables) > 0 && !opt.truncated {
+		opt.retryFailedTables(ctx, opt.dbs[len(opt.dbs)-1])
+	}
+	if opt.loadScript != nil {
+		err = opt.loadScript.write()
+		if err != nil {
+			return err
+		}
+		if opt.signFiles {
+			err = signFile(opt.loadScript.path)
+			if err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(opt.stdout(), "/* LOAD SCRIPT '%s' */\n", opt.loadScript.path)
+		opt.fileHook.run(opt.loadScript.path)
+	}
+	if opt.stamp.enabled() && !opt.truncated {
+		opt.showStamp()
+	}
+	opt.foreignKeyChecks(true)
+	return nil
+}
+
+// showResult writes the final banner of the dump. A dump without any table
+// or view is reported distinctly, as it usually means a wrong database or
+// table list.
+func (opt *Options) showResult(w io.Writer, cost time.Duration) {
+	if opt.dumpedObjects == 0 {
+		fmt.Fprintf(w, "/* MODUMP: NOTHING TO DUMP, COST %v */\n", cost)
+		return
+	}
+	fmt.Fprintf(w, "/* MODUMP SUCCESS, COST %v */\n", cost)
+	if opt.csvConf.enable {
+		fmt.Fprintf(w, "/* !!!MUST KEEP FILE IN CURRENT DIRECTORY, OR YOU SHOULD CHANGE THE PATH IN LOAD DATA STMT!!! */ \n")
+	}
+}
+
+// filterTableKinds removes the relations which are not dumped. Index,
+// cluster and partition tables are maintained by MatrixOne itself and
+// are skipped with a warning, as well as kinds mo-dump can not recreate.
+// Unknown kinds fail the dump unless ignore-errors is set.
+func (opt *Options) filterTableKinds(ctx context.Context, db string, tables Tables) (Tables, error) {
+	ret := tables[:0]
+	for _, tbl := range tables {
+		switch tbl.Kind {
+		case catalog.SystemOrdinaryRel, catalog.SystemExternalRel, catalog.SystemViewRel:
+			ret = append(ret, tbl)
+		case catalog.SystemSequenceRel:
+			if !opt.sequences {
+				fmt.Fprintf(os.Stderr, "skip table `%s`.`%s` of kind %s\n", db, tbl.Name, tbl.Kind)
+			}
+		case catalog.SystemIndexRel, catalog.SystemClusterRel, catalog.SystemPartitionRel,
+			catalog.SystemMaterializedRel, catalog.SystemStreamRel:
+			fmt.Fprintf(os.Stderr, "skip table `%s`.`%s` of kind %s\n", db, tbl.Name, tbl.Kind)
+		default:
+			if !opt.ignoreErrors {
+				return nil, unsupportedKindError(ctx, db, tbl)
+			}
+			fmt.Fprintf(os.Stderr, "skip table `%s`.`%s` of unsupported kind %s\n", db, tbl.Name, tbl.Kind)
+		}
+	}
+	return ret, nil
+}
+
+func unsupportedKindError(ctx context.Context, db string, tbl Table) error {
+	return moerr.NewNotSupported(ctx, "table `%s`.`%s` of kind '%s', use -ignore-errors to skip it", db, tbl.Name, tbl.Kind)
+}
+
+// dumpTableData writes the data of the table, wrapped in LOCK TABLES and
+// UNLOCK TABLES if add-locks is set, or in a transaction if
+// single-transaction is set
+func (opt *Options) dumpTableData(ctx context.Context, db, tbl string, bufPool *sync.Pool) error {
+	if opt.format != formatSQL && !opt.csvConf.tsv {
+		queries, err := opt.selectQueries(ctx, db, tbl)
+		if err != nil {
+			return err
+		}
+		return opt.genOutput(ctx, queries, db, tbl, bufPool)
+	}
+	if opt.addLocks {
+		fmt.Fprintf(opt.stdout(), "LOCK TABLES %s WRITE;\n", quoteIdent(tbl))
+	}
+	if opt.singleTransaction {
+		fmt.Fprintf(opt.stdout(), "BEGIN;\n")
+	}
+	var err error
+	if opt.chunkTable != nil && opt.chunkTable.table == tbl {
+		err = opt.dumpTableChunks(ctx, db, tbl, bufPool)
+	} else {
+		var queries []string
+		queries, err = opt.selectQueries(ctx, db, tbl)
+		if err == nil {
+			err = opt.genOutput(ctx, queries, db, tbl, bufPool)
+		}
+	}
+	if opt.singleTransaction {
+		// a table which failed under ignore-errors is not restored half loaded
+		if err != nil {
+			fmt.Fprintf(opt.stdout(), "ROLLBACK;\n")
+		} else {
+			fmt.Fprintf(opt.stdout(), "COMMIT;\n")
+		}
+	}
+	if opt.addLocks {
+		// also on failure, the dump may go on under ignore-errors
+		fmt.Fprintf(opt.stdout(), "UNLOCK TABLES;\n")
+	}
+	if err != nil {
+		return err
+	}
+	if !opt.csvConf.enable {
+		fmt.Fprintf(opt.stdout(), "\n\n\n")
+	}
+	return nil
+}
+
+// supportLockTables checks if the server accepts the LOCK TABLES syntax.
+// UNLOCK TABLES is harmless when the session holds no lock.
+func supportLockTables(ctx context.Context) bool {
+	_, err := session(ctx).ExecContext(ctx, "UNLOCK TABLES")
+	return err == nil
+}
+
+// selectQueries returns the queries used to read the data of the table.
+// There is more than one query only when the rows are selected by a large
+// -where-in list, whose values are spread over several queries. The extra
+// conditions are added to the ones of the options.
+func (opt *Options) selectQueries(ctx context.Context, db, tbl string, extra ...string) ([]string, error) {
+	list, err := opt.selectList(ctx, db, tbl)
+	if err != nil {
+		return nil, err
+	}
+	query := "select " + list + " from " + quoteIdent(db) + "." + quoteIdent(tbl)
+	var conds []string
+	if opt.window.enabled() {
+		parts, err := getPartitions(ctx, db, tbl)
+		if err != nil {
+			return nil, err
+		}
+		if names := opt.window.prunePartitions(parts); names != nil {
+			if len(names) == 0 {
+				return []string{query + " where 1 = 0"}, nil
+			}
+			query += " partition (" + quoteIdents(names) + ")"
+		}
+		conds = append(conds, opt.window.predicate())
+	}
+	if opt.txnRange.enabled() {
+		err := checkCommitTSColumn(ctx, db, tbl)
+		if err != nil {
+			return nil, err
+		}
+		conds = append(conds, opt.txnRange.predicate())
+	}
+	if where := opt.whereCond(db, tbl); where != "" {
+		conds = append(conds, "("+where+")")
+	}
+	conds = append(conds, extra...)
+	order, err := opt.primaryKeyOrder(ctx, db, tbl, opt.orderBy(tbl))
+	if err != nil {
+		return nil, err
+	}
+	if opt.whereIn == nil || opt.whereIn.table != tbl {
+		if len(conds) > 0 {
+			query += " where " + strings.Join(conds, " AND ")
+		}
+		return []string{query + order}, nil
+	}
+	query += " where "
+	for _, cond := range conds {
+		query += cond + " AND "
+	}
+	preds := opt.whereIn.predicates(opt.netBufferLength - len(query) - len(order))
+	queries := make([]string, len(preds))
+	for i, pred := range preds {
+		queries[i] = query + pred + order
+	}
+	return queries, nil
+}
+
+// parseHosts splits the -h list. All hosts share the port given by -P.
+func parseHosts(ctx context.Context, host string) ([]string, error) {
+	hosts := strings.Split(host, ",")
+	for i, h := range hosts {
+		h = strings.TrimSpace(h)
+		if h == "" {
+			return nil, moerr.NewInvalidInput(ctx, "empty host in %s", host)
+		}
+		// if host has ":", reports error
+		if strings.Contains(h, ":") {
+			return nil, moerr.NewInvalidInput(ctx, "host can not have character ':'")
+		}
+		hosts[i] = h
+	}
+	return hosts, nil
+}
+
+// parseConnectionAttributes converts key=value,key=value to the key:value
+// list of the driver
+func parseConnectionAttributes(ctx context.Context, spec string) (string, error) {
+	var attrs []string
+	for _, item := range strings.Split(spec, ",") {
+		k, v, ok := strings.Cut(item, "=")
+		k = strings.TrimSpace(k)
+		v = strings.TrimSpace(v)
+		if !ok || k == "" || strings.Contains(k, ":") || strings.Contains(v, ":") {
+			return "", moerr.NewInvalidInput(ctx, "connection attribute must be in the format key=value without ':', got %s", item)
+		}
+		attrs = append(attrs, k+":"+v)
+	}
+	return strings.Join(attrs, ","), nil
+}
+
+// parseDSNParams splits key=value&key=value into the parameters appended
+// to the data source name. A key given twice keeps its last value at its
+// first position. The keys mo-dump sets itself from other options can not
+// be overridden.
+func parseDSNParams(ctx context.Context, spec string, reserved []string) ([]string, error) {
+	var (
+		keys   []string
+		values = make(map[string]string)
+	)
+	for _, item := range strings.Split(spec, "&") {
+		if item == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(item, "=")
+		if !ok || k == "" {
+			return nil, moerr.NewInvalidInput(ctx, "dsn parameter must be in the format key=value, got %s", item)
+		}
+		for _, r := range reserved {
+			if k == r {
+				return nil, moerr.NewInvalidInput(ctx, "dsn parameter %s conflicts with the value set by mo-dump", k)
+			}
+		}
+		if _, ok := values[k]; !ok {
+			keys = append(keys, k)
+		}
+		values[k] = v
+	}
+	params := make([]string, 0, len(keys))
+	for _, k := range keys {
+		params = append(params, k+"="+values[k])
+	}
+	return params, nil
+}
 
-	if opt.toCsv {
-		opt.csvConf.enable = opt.toCsv
-		opt.csvConf.fieldDelimiter, err = checkFieldDelimiter(ctx, opt.csvFieldDelimiterStr)
-		if err != nil {
-			return
+// dsn returns the data source name of the database. A token replaces the
+// password, it is sent in clear text unless another plugin is asked for.
+func (opt *Options) dsn(ctx context.Context, host string, database string) (string, error) {
+	password := opt.password
+	plugin := opt.authPlugin
+	if opt.authToken != "" {
+		password = opt.authToken
+		if plugin == "" {
+			plugin = authClearPassword
 		}
 	}
-
-	if opt.database == "all" {
-		conn, err = opt.openDBConnection(ctx, "")
+	var params []string
+	switch plugin {
+	case "", authNativePassword, authCachingSha2Password:
+	case authClearPassword:
+		params = append(params, "allowCleartextPasswords=true")
+	default:
+		return "", moerr.NewInvalidInput(ctx, "unsupported auth plugin %s", plugin)
+	}
+	if tlsParam := opt.ssl.dsnParam(host); tlsParam != "" {
+		params = append(params, tlsParam)
+	}
+	if opt.connectionAttributes != "" {
+		attrs, err := parseConnectionAttributes(ctx, opt.connectionAttributes)
 		if err != nil {
-			return
+			return "", err
 		}
-		defer conn.Close()
-
-		opt.dbs, err = getDatabases(ctx)
+		params = append(params, "connectionAttributes="+url.QueryEscape(attrs))
+	}
+	if opt.dsnParams != "" {
+		reserved := make([]string, 0, len(params))
+		for _, p := range params {
+			k, _, _ := strings.Cut(p, "=")
+			reserved = append(reserved, k)
+		}
+		extra, err := parseDSNParams(ctx, opt.dsnParams, reserved)
 		if err != nil {
-			return
+			return "", err
 		}
+		params = append(params, extra...)
 	}
-
-	err = opt.dumpData(ctx)
-	if err != nil {
-		return
+	dsn := fmt.Sprintf("%s:%s@%s/%s", opt.username, password, opt.address(host), database)
+	if len(params) > 0 {
+		dsn += "?" + strings.Join(params, "&")
 	}
+	return dsn, nil
 }
 
-func (opt *Options) dumpData(ctx context.Context) error {
-	var (
-		createDb    string
-		createTable []string
-		err         error
-	)
-
-	if conn == nil {
-		conn, err = opt.openDBConnection(ctx, opt.dbs[0])
-		if err != nil {
-			return err
-		}
-		defer conn.Close()
+// keepAlivePing returns the ping of the keepalive. The statements of the
+// dump run on the pinned connection if there is one, so it is the one to
+// keep alive, else the pool is pinged.
+func keepAlivePing() func(context.Context) error {
+	if pinned != nil {
+		return pinned.pingIdle
 	}
+	return conn.PingContext
+}
 
-	for _, db := range opt.dbs {
-		if opt.emptyTables {
-			opt.tables = nil
-		}
-		if len(opt.tables) == 0 { //dump all tables
-			createDb, err = getCreateDB(ctx, db)
-			if err != nil {
-				return err
+// startKeepAlive pings in the background until the returned function is
+// called. The pings keep idle connections alive while the dump is busy with
+// other work.
+func startKeepAlive(ctx context.Context, ping func(context.Context) error, interval time.Duration) func() {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := ping(ctx); err != nil && ctx.Err() == nil {
+					fmt.Fprintf(os.Stderr, "keepalive ping failed: %v\n", err)
+				}
 			}
-			fmt.Printf("DROP DATABASE IF EXISTS `%s`;\n", db)
-			fmt.Println(createDb, ";")
-			fmt.Printf("USE `%s`;\n\n\n", db)
 		}
-		opt.tables, err = getTables(ctx, db, opt.tables)
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// sqlOpen is replaced in tests
+var sqlOpen = sql.Open
+
+// openDBConnection connects to the first reachable host of -h, trying the
+// hosts in order
+func (opt *Options) openDBConnection(ctx context.Context, database string) (*sql.DB, error) {
+	hosts := opt.hosts
+	if len(hosts) == 0 {
+		hosts = []string{opt.host}
+	}
+	var lastErr error
+	for _, host := range hosts {
+		dsn, err := opt.dsn(ctx, host, database)
 		if err != nil {
-			return err
-		}
-		createTable = make([]string, len(opt.tables))
-		for i, tbl := range opt.tables {
-			createTable[i], err = getCreateTable(db, tbl.Name)
-			if err != nil {
-				return err
-			}
-		}
-		bufPool := &sync.Pool{
-			New: func() any {
-				return &bytes.Buffer{}
-			},
+			return nil, err
 		}
-		left, right := 0, len(createTable)-1
-		for left < right {
-			for left < len(createTable) && opt.tables[left].Kind != catalog.SystemViewRel {
-				left++
-			}
-			for right >= 0 && opt.tables[right].Kind == catalog.SystemViewRel {
-				right--
+		conn, err := connect(ctx, dsn)
+		if err == nil {
+			if len(hosts) > 1 {
+				fmt.Fprintf(os.Stderr, "connected to host %s\n", host)
 			}
-			if left >= right {
-				break
-			}
-			createTable[left], createTable[right] = createTable[right], createTable[left]
-			opt.tables[left], opt.tables[right] = opt.tables[right], opt.tables[left]
+			// the host in use from now on
+			opt.host = host
+			return conn, nil
 		}
-		adjustViewOrder(createTable, opt.tables, left)
-		for i, create := range createTable {
-			tbl := opt.tables[i]
-			switch tbl.Kind {
-			case catalog.SystemOrdinaryRel:
-				fmt.Printf("DROP TABLE IF EXISTS `%s`;\n", tbl.Name)
-				showCreateTable(create, false)
-				if !opt.noData {
-					err = genOutput(db, tbl.Name, bufPool, opt.netBufferLength, opt.localInfile, &opt.csvConf)
-					if err != nil {
-						return err
-					}
-				}
-			case catalog.SystemExternalRel:
-				fmt.Printf("/*!EXTERNAL TABLE `%s`*/\n", tbl.Name)
-				fmt.Printf("DROP TABLE IF EXISTS `%s`;\n", tbl.Name)
-				showCreateTable(create, true)
-			case catalog.SystemViewRel:
-				fmt.Printf("DROP VIEW IF EXISTS `%s`;\n", tbl.Name)
-				showCreateTable(create, true)
-			default:
-				err = moerr.NewNotSupported(ctx, "table: %s table type: %s", tbl.Name, tbl.Kind)
-				return err
-			}
+		if len(hosts) > 1 {
+			fmt.Fprintf(os.Stderr, "connect to host %s failed: %v\n", host, err)
 		}
+		lastErr = err
 	}
-	return nil
+	if len(hosts) == 1 {
+		return nil, lastErr
+	}
+	return nil, moerr.NewInternalError(ctx, "can not connect to any host of %s: %v", strings.Join(hosts, ","), lastErr)
 }
 
-func (opt *Options) openDBConnection(ctx context.Context, database string) (*sql.DB, error) {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s", opt.username, opt.password, opt.host, opt.port, database)
-
-	conn, err := sql.Open("mysql", dsn)
+func connect(ctx context.Context, dsn string) (*sql.DB, error) {
+	conn, err := sqlOpen("mysql", dsn)
 	if err != nil {
 		return nil, err
 	}
 
-	ch := make(chan error)
+	ch := make(chan error, 1)
 	go func() {
 		err := conn.Ping()
 		ch <- err
@@ -277,62 +1584,149 @@ func (opt *Options) openDBConnection(ctx context.Context, database string) (*sql
 	select {
 	case err = <-ch:
 	case <-time.After(timeout):
+		conn.Close()
 		return nil, moerr.NewInternalError(ctx, "connect to %s timeout", dsn)
 	}
 	if err != nil {
+		conn.Close()
 		return nil, err
 	}
 
 	return conn, nil
 }
 
+// adjustViewOrder orders the views from start on so that every view comes
+// after the views it selects from. The views of a cycle can not be ordered,
+// they are left in their order with a warning.
 func adjustViewOrder(createTable []string, tables Tables, start int) {
-	viewName := make([]string, 0)
-	viewPos := make(map[string]int)
-	cnt := len(tables)
-	for i := start; i < cnt; i++ {
-		viewPos[tables[i].Name] = i - start
-		viewName = append(viewName, tables[i].Name)
-	}
-	viewCount := make([]int, len(viewName))
-	viewRef := make([][]int, len(viewName))
-	for i := start; i < cnt; i++ {
-		for j := start; j < cnt; j++ {
-			if i == j {
-				continue
-			}
-			if strings.Count(createTable[i], tables[j].Name) > 0 {
-				viewCount[viewPos[tables[i].Name]]++
-				viewRef[viewPos[tables[j].Name]] = append(viewRef[viewPos[tables[j].Name]], viewPos[tables[i].Name])
+	views := tables[start:]
+	idents := make([]map[string]bool, len(views))
+	for i := range views {
+		idents[i] = sqlIdentifiers(createTable[start+i])
+	}
+	// a view naming itself is no dependency
+	order, cycle := dependencyOrder(len(views), func(i, j int) bool {
+		return i != j && idents[i][strings.ToLower(views[j].Name)]
+	})
+	if len(cycle) > 0 {
+		fmt.Fprintf(os.Stderr, "views %s can not be ordered, their dependencies form a cycle. they are dumped in their current order\n", quotedNames(views, cycle))
+	}
+	newCreate := make([]string, len(views))
+	newTables := make([]Table, len(views))
+	for i, k := range order {
+		newCreate[i] = createTable[start+k]
+		newTables[i] = views[k]
+	}
+	_ = copy(createTable[start:], newCreate)
+	_ = copy(views, newTables)
+}
+
+// dependencyOrder orders n items so that each one comes after the items it
+// depends on, and keeps their order otherwise. The items which can not be
+// ordered, as they depend on a cycle, are put last in their order and
+// returned as cycle.
+func dependencyOrder(n int, dependsOn func(i, j int) bool) (order []int, cycle []int) {
+	count := make([]int, n)
+	refs := make([][]int, n)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if dependsOn(i, j) {
+				count[i]++
+				refs[j] = append(refs[j], i)
 			}
 		}
 	}
-	order := 0
-	orderArr := make([]int, 0)
-	visit := make([]bool, len(viewName))
-	for order < len(viewName) {
-		for i := 0; i < len(viewName); i++ {
-			if viewCount[i] == 0 && !visit[i] {
+	visit := make([]bool, n)
+	for len(order) < n {
+		last := len(order)
+		for i := 0; i < n; i++ {
+			if count[i] == 0 && !visit[i] {
 				visit[i] = true
-				order++
-				orderArr = append(orderArr, i)
-				for j := 0; j < len(viewRef[i]); j++ {
-					viewCount[viewRef[i][j]]--
+				order = append(order, i)
+				for _, k := range refs[i] {
+					count[k]--
 				}
 			}
 		}
+		if len(order) == last {
+			for i := 0; i < n; i++ {
+				if !visit[i] {
+					cycle = append(cycle, i)
+				}
+			}
+			order = append(order, cycle...)
+		}
+	}
+	return order, cycle
+}
+
+// quotedNames lists the tables at the positions
+func quotedNames(tables Tables, pos []int) string {
+	names := make([]string, len(pos))
+	for i, k := range pos {
+		names[i] = quoteIdent(tables[k].Name)
 	}
-	newCreate := make([]string, cnt)
-	newTables := make([]Table, cnt)
-	for i := 0; i < len(orderArr); i++ {
-		newCreate[i] = createTable[orderArr[i]+start]
-		newTables[i] = tables[orderArr[i]+start]
+	return strings.Join(names, ", ")
+}
+
+// sqlIdentifiers returns the identifiers in the SQL text in lower case,
+// backtick quoted or bare, leaving out the string literals. A name matches
+// a whole identifier only, so view a is not found in abc.
+func sqlIdentifiers(text string) map[string]bool {
+	idents := make(map[string]bool)
+	isIdent := func(c byte) bool {
+		return c == '_' || c == '$' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
 	}
-	_ = copy(createTable[start:], newCreate)
-	_ = copy(tables[start:], newTables)
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '`' || c == '\'' || c == '"':
+			// a doubled quote is a quote within the token
+			var tok strings.Builder
+			j := i + 1
+			for j < len(text) {
+				if text[j] == c {
+					if j+1 < len(text) && text[j+1] == c {
+						tok.WriteByte(c)
+						j += 2
+						continue
+					}
+					break
+				}
+				if text[j] == '\\' && c != '`' && j+1 < len(text) {
+					j++
+				}
+				tok.WriteByte(text[j])
+				j++
+			}
+			if c == '`' {
+				idents[strings.ToLower(tok.String())] = true
+			}
+			i = j + 1
+		case isIdent(c):
+			j := i
+			for j < len(text) && isIdent(text[j]) {
+				j++
+			}
+			idents[strings.ToLower(text[i:j])] = true
+			i = j
+		default:
+			i++
+		}
+	}
+	return idents
+}
+
+// insertHead returns the start of the INSERT statements of the table
+func insertHead(tbl string, cols []*Column, completeInsert bool, insertMode string) string {
+	head := insertKeyword(insertMode) + " " + quoteIdent(tbl) + " "
+	if completeInsert {
+		head += columnList(cols) + " "
+	}
+	return head + "VALUES "
 }
 
-func showCreateTable(createSql string, withNextLine bool) {
+func showCreateTable(w io.Writer, createSql string, withNextLine bool) {
 	var suffix string
 	if !strings.HasSuffix(createSql, ";") {
 		suffix = ";"
@@ -340,33 +1734,49 @@ func showCreateTable(createSql string, withNextLine bool) {
 	if withNextLine {
 		suffix += "\n\n"
 	}
-	fmt.Printf("%s%s\n", createSql, suffix)
+	fmt.Fprintf(w, "%s%s\n", createSql, suffix)
 }
 
-func getTables(ctx context.Context, db string, tables Tables) (Tables, error) {
-	sql := "select relname,relkind from mo_catalog.mo_tables where reldatabase = '" + db + "'"
-	tableNames := make(map[string]bool, len(tables))
-	if len(tables) > 0 {
+func getTables(ctx context.Context, db string, tables Tables, ignored ignoreTables, skipMissing bool, allowEmptyMatch bool, temporary bool) (Tables, error) {
+	if err := ignored.checkRequested(ctx, db, tables); err != nil {
+		return nil, err
+	}
+	// the patterns are matched against all the tables of db
+	names, patterns := splitTablePatterns(tables)
+	sql := "select relname,relkind from mo_catalog.mo_tables where reldatabase = '" + escapeString(db) + "'"
+	tableNames := make(map[string]bool, len(names))
+	for _, tbl := range names {
+		tableNames[tbl.Name] = false
+	}
+	if len(names) > 0 && len(patterns) == 0 {
 		sql += " and relname in ("
-		for i, tbl := range tables {
+		for i, tbl := range names {
 			if i != 0 {
 				sql += ","
 			}
-			sql += "'" + tbl.Name + "'"
-			tableNames[tbl.Name] = false
+			sql += "'" + escapeString(tbl.Name) + "'"
 		}
 		sql += ")"
 	}
-	r, err := conn.Query(sql) //TODO: after unified sys table prefix, add condition in where clause
+	cond, err := persistenceCond(ctx, temporary)
 	if err != nil {
 		return nil, err
 	}
+	sql += cond
+	qctx, cancel := queryContext(ctx)
+	defer cancel()
+	what := "list tables of " + quoteIdent(db)
+	r, err := connRetry.query(qctx, what, sql) //TODO: after unified sys table prefix, add condition in where clause
+	if err != nil {
+		return nil, queryTimeoutError(ctx, qctx, err, what)
+	}
 	defer r.Close()
 
 	if tables == nil {
 		tables = Tables{}
 	}
-	tables = tables[:0]
+	var all Tables
+	var allNames []string
 	for r.Next() {
 		var table string
 		var kind string
@@ -377,26 +1787,42 @@ func getTables(ctx context.Context, db string, tables Tables) (Tables, error) {
 		if strings.HasPrefix(table, "__mo_") || strings.HasPrefix(table, "%!%") { //TODO: after adding condition in where clause, remove this
 			continue
 		}
-		tables = append(tables, Table{table, kind})
-		tableNames[table] = true
+		all = append(all, Table{table, kind})
+		allNames = append(allNames, table)
 	}
 	if err := r.Err(); err != nil {
+		return nil, queryTimeoutError(ctx, qctx, err, what)
+	}
+	globs, exact := exactPatterns(patterns, allNames)
+	tables = tables[:0]
+	for _, tbl := range all {
+		if _, ok := tableNames[tbl.Name]; len(patterns) > 0 && !ok && !exact[tbl.Name] && !matchAny(globs, tbl.Name) {
+			continue
+		}
+		tables = append(tables, tbl)
+		tableNames[tbl.Name] = true
+	}
+	if err := checkTablePatterns(ctx, db, globs, tables, allowEmptyMatch); err != nil {
 		return nil, err
 	}
 
 	for k, v := range tableNames {
 		if !v {
+			if skipMissing {
+				fmt.Fprintf(os.Stderr, "table %s not exists in database %s, skip it\n", k, db)
+				continue
+			}
 			return nil, moerr.NewInvalidInput(ctx, "table %s not exists", k)
 		}
 	}
 
-	return tables, nil
+	return ignored.filter(db, tables), nil
 }
 
 func getCreateDB(ctx context.Context, db string) (string, error) {
-	r := conn.QueryRow("show create database `" + db + "`")
 	var create string
-	err := r.Scan(&db, &create)
+	query := "show create database " + quoteIdent(db)
+	err := connRetry.queryRow(ctx, query, query, &db, &create)
 	if err != nil {
 		return "", err
 	}
@@ -405,9 +1831,11 @@ func getCreateDB(ctx context.Context, db string) (string, error) {
 }
 
 func getDatabases(ctx context.Context) ([]string, error) {
-	r, err := conn.QueryContext(ctx, "show databases")
+	qctx, cancel := queryContext(ctx)
+	defer cancel()
+	r, err := connRetry.query(qctx, "show databases", "show databases")
 	if err != nil {
-		return nil, err
+		return nil, queryTimeoutError(ctx, qctx, err, "show databases")
 	}
 	if r.Err() != nil {
 		return nil, r.Err()
@@ -427,25 +1855,47 @@ func getDatabases(ctx context.Context) ([]string, error) {
 	return dbs, nil
 }
 
-func getCreateTable(db, tbl string) (string, error) {
-	r := conn.QueryRow("show create table `" + db + "`.`" + tbl + "`")
+func getCreateTable(ctx context.Context, db, tbl string) (string, error) {
+	query := "show create table " + quoteIdent(db) + "." + quoteIdent(tbl)
 	var create string
-	err := r.Scan(&tbl, &create)
+	err := connRetry.queryRow(ctx, query, query, &tbl, &create)
 	if err != nil {
 		return "", err
 	}
 	return create, nil
 }
 
-func showInsert(r *sql.Rows, args []any, cols []*Column, tbl string, bufPool *sync.Pool, netBufferLength int) error {
-	var err error
+// showInsert writes the rows as INSERT statements. If a single-row INSERT
+// of some row is larger than maxRowSize, the largest such row is reported,
+// as it may exceed max_allowed_packet of the restore target.
+func showInsert(r rowIterator, w io.Writer, args []any, cols []*Column, tbl string, bufPool *sync.Pool, netBufferLength int, batchRows int, maxRowSize int, validateUTF8 string, completeInsert bool, insertMode string, shardCol int) error {
+	var (
+		err        error
+		rows       int
+		widestRow  int
+		widestSize int
+		// the shard value of the last row, and the value of the next shard
+		// whose comment waits for the statement of the last shard
+		shard        []byte
+		shardNull    bool
+		pendingShard *sql.RawBytes
+	)
 	buf := bufPool.Get().(*bytes.Buffer)
 	curBuf := bufPool.Get().(*bytes.Buffer)
 	buf.Grow(netBufferLength)
-	initInert := "INSERT INTO `" + tbl + "` VALUES "
+	initInert := insertHead(tbl, cols, completeInsert, insertMode)
 	for {
+		if pendingShard != nil {
+			if err = showShard(w, pendingShard); err != nil {
+				return err
+			}
+			pendingShard = nil
+		}
 		buf.WriteString(initInert)
 		preLen := buf.Len()
+		// a statement is flushed when either its size reaches netBufferLength
+		// or it holds batchRows tuples, whichever comes first
+		tuples := 0
 		first := true
 		if curBuf.Len() > 0 {
 			bts := curBuf.Bytes()
@@ -454,13 +1904,35 @@ func showInsert(r *sql.Rows, args []any, cols []*Column, tbl string, bufPool *sy
 			}
 			buf.Write(bts)
 			curBuf.Reset()
+			tuples++
 			first = false
 		}
-		for r.Next() {
+		for (batchRows <= 0 || tuples < batchRows) && r.Next() {
 			err = r.Scan(args...)
 			if err != nil {
 				return err
 			}
+			// a new shard value starts a new statement
+			newShard := false
+			if shardCol >= 0 {
+				v := args[shardCol].(*sql.RawBytes)
+				if rows == 0 || (*v == nil) != shardNull || string(*v) != string(shard) {
+					shard, shardNull = append(shard[:0], *v...), *v == nil
+					if buf.Len() == preLen && curBuf.Len() == 0 {
+						if err = showShard(w, v); err != nil {
+							return err
+						}
+					} else {
+						// the row goes to the next statement, after the comment
+						newShard = true
+						var next sql.RawBytes
+						if !shardNull {
+							next = append(make(sql.RawBytes, 0, len(shard)), shard...)
+						}
+						pendingShard = &next
+					}
+				}
+			}
 			if !first {
 				curBuf.WriteString(",(")
 			} else {
@@ -472,107 +1944,294 @@ func showInsert(r *sql.Rows, args []any, cols []*Column, tbl string, bufPool *sy
 				if i > 0 {
 					curBuf.WriteString(",")
 				}
+				if invalidUTF8(validateUTF8, v, cols[i].Type) {
+					err = reportInvalidUTF8(validateUTF8, tbl, cols[i].Name, rows+1)
+					if err != nil {
+						return err
+					}
+					// a hex literal restores the original bytes
+					curBuf.WriteString(hexLiteral(*(v.(*sql.RawBytes))))
+					continue
+				}
 				curBuf.WriteString(convertValue(v, cols[i].Type))
 			}
 			curBuf.WriteString(")")
-			if buf.Len()+curBuf.Len() >= netBufferLength {
+			rows++
+			size := len(initInert) + curBuf.Len() + len(";\n")
+			if curBuf.Bytes()[0] == ',' {
+				size--
+			}
+			if size > widestSize {
+				widestRow, widestSize = rows, size
+			}
+			if newShard || buf.Len()+curBuf.Len() >= netBufferLength {
 				break
 			}
 			buf.Write(curBuf.Bytes())
 			curBuf.Reset()
+			tuples++
 		}
 		if buf.Len() > preLen {
 			buf.WriteString(";\n")
-			_, err = buf.WriteTo(os.Stdout)
+			_, err = buf.WriteTo(w)
 			if err != nil {
 				return err
 			}
 			continue
 		}
+		buf.Reset()
 		if curBuf.Len() > 0 {
+			// the first tuple alone exceeds netBufferLength
 			continue
 		}
-		buf.Reset()
-		curBuf.Reset()
 		break
 	}
+	if err = r.Err(); err != nil {
+		return err
+	}
+	if maxRowSize > 0 && widestSize > maxRowSize {
+		fmt.Fprintf(os.Stderr, "row %d of table `%s` takes %d bytes in a single-row INSERT, more than max-row-size %d. raise max_allowed_packet of the restore target or dump the table with -csv\n", widestRow, tbl, widestSize, maxRowSize)
+	}
 	bufPool.Put(buf)
 	bufPool.Put(curBuf)
-	fmt.Printf("\n\n\n")
 	return nil
 }
 
-func showLoad(r *sql.Rows, rowResults []any, cols []*Column, db string, tbl string, localInfile bool, csvConf *csvConfig) error {
-	fname := fmt.Sprintf("%s_%s.%s", db, tbl, "csv")
-	pwd := os.Getenv("PWD")
-	f, err := os.Create(fname)
+// showLoad writes the rows of the table to db_tbl.csv, or db_tbl.tsv for
+// -format tsv, and returns the file names, several with chunk-size. The
+// LOAD DATA statements of the files are left to the caller.
+func showLoad(r rowIterator, rowResults []any, cols []*Column, db string, tbl string, csvConf *csvConfig) ([]string, error) {
+	files := newCsvFiles(db, tbl, csvConf)
+	// an empty table still has its file
+	err := files.open()
 	if err != nil {
-		return err
+		return nil, err
+	}
+	err = toCsv(r, files, tbl, rowResults, cols, csvConf)
+	if e := files.closeFile(); err == nil {
+		err = e
 	}
-	defer f.Close()
-
-	err = toCsv(r, f, rowResults, cols, csvConf)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	return files.names, nil
+}
+
+// loadDataStmt returns the LOAD DATA statement of the csv file of the table.
+// A compressed file is named with its compression in the INFILE options.
+func loadDataStmt(path string, tbl string, localInfile bool, csvConf *csvConfig, cols []*Column) string {
+	infile := "INFILE '" + escapeString(path) + "'"
+	if csvConf.compress != "" {
+		infile = "INFILE {'filepath'='" + escapeString(path) + "', 'compression'='" + csvConf.compress + "'}"
 	}
 	if localInfile {
-		fmt.Printf("LOAD DATA LOCAL INFILE '%s' INTO TABLE `%s` FIELDS TERMINATED BY '\\t' ENCLOSED BY '\"' LINES TERMINATED BY '\\n' PARALLEL 'FALSE';\n", fmt.Sprintf("%s/%s", pwd, fname), tbl)
-	} else {
-		fmt.Printf("LOAD DATA INFILE '%s' INTO TABLE `%s` FIELDS TERMINATED BY '\\t' ENCLOSED BY '\"' LINES TERMINATED BY '\\n' PARALLEL 'FALSE';\n", fmt.Sprintf("%s/%s", pwd, fname), tbl)
+		infile = "LOCAL " + infile
 	}
-	return nil
+	var list string
+	if cols != nil {
+		list = " " + loadColumns(cols)
+	}
+	if csvConf.tsv {
+		return fmt.Sprintf("LOAD DATA %s INTO TABLE %s FIELDS TERMINATED BY '\\t' ESCAPED BY '\\\\' LINES TERMINATED BY '\\n'%s PARALLEL 'FALSE';", infile, quoteIdent(tbl), list)
+	}
+	return fmt.Sprintf("LOAD DATA %s INTO TABLE %s FIELDS TERMINATED BY '\\t' ENCLOSED BY '\"' LINES TERMINATED BY '\\n'%s PARALLEL 'FALSE';", infile, quoteIdent(tbl), list)
 }
 
 // toCsv converts the result from mo to csv file
-func toCsv(r *sql.Rows, output io.Writer, rowResults []any, cols []*Column, csvConf *csvConfig) error {
+func toCsv(r rowIterator, output io.Writer, tbl string, rowResults []any, cols []*Column, csvConf *csvConfig) error {
 	var err error
-	csvWriter := csv.NewWriter(output)
-	csvWriter.Comma = csvConf.fieldDelimiter
+	var csvWriter csvRecordWriter
+	if csvConf.tsv {
+		csvWriter = newTSVWriter(output)
+	} else if csvConf.quoteAll {
+		csvWriter = newQuoteAllWriter(output, csvConf.fieldDelimiter)
+	} else {
+		w := csv.NewWriter(output)
+		w.Comma = csvConf.fieldDelimiter
+		csvWriter = w
+	}
 	line := make([]string, len(rowResults))
+	nulls := make([]bool, len(rowResults))
 
+	row := 0
 	for r.Next() {
 		err = r.Scan(rowResults...)
 		if err != nil {
 			return err
 		}
-		err = toCsvLine(csvWriter, rowResults, cols, line)
+		row++
+		for i, v := range rowResults {
+			// hex is rejected for csv by checkValidateUTF8
+			if invalidUTF8(csvConf.validateUTF8, v, cols[i].Type) {
+				return reportInvalidUTF8(utf8Error, tbl, cols[i].Name, row)
+			}
+		}
+		err = toCsvLine(csvWriter, rowResults, cols, line, nulls)
 		if err != nil {
 			return err
 		}
+		if e, ok := output.(rowEnder); ok {
+			if err = e.endRow(); err != nil {
+				return err
+			}
+		}
 	}
 	return err
 }
 
-// toCsvFields converts the result from mo to string
-func toCsvFields(rowResults []any, cols []*Column, line []string) {
+// toCsvFields converts the result from mo to string. nulls, if not nil,
+// marks the NULL values, which are written as \N like the string
+func toCsvFields(rowResults []any, cols []*Column, line []string, nulls []bool) {
 	for i, v := range rowResults {
 		dt, format := convertValue2(v, cols[i].Type)
 		str := fmt.Sprintf(format, dt)
 		line[i] = str
+		if nulls != nil {
+			nulls[i] = *(v.(*sql.RawBytes)) == nil
+		}
 	}
 }
 
 // toCsvLine converts the result from mo to csv single line
-func toCsvLine(csvWriter *csv.Writer, rowResults []any, cols []*Column, line []string) error {
+func toCsvLine(csvWriter csvRecordWriter, rowResults []any, cols []*Column, line []string, nulls []bool) error {
 	var err error
-	toCsvFields(rowResults, cols, line)
-	err = csvWriter.Write(line)
+	toCsvFields(rowResults, cols, line, nulls)
+	if w, ok := csvWriter.(nullRecordWriter); ok {
+		err = w.writeRow(line, nulls)
+	} else {
+		err = csvWriter.Write(line)
+	}
 	if err != nil {
 		return err
 	}
 	csvWriter.Flush()
-	return err
+	return csvWriter.Error()
 }
 
-func genOutput(db string, tbl string, bufPool *sync.Pool, netBufferLength int, localInfile bool, csvConf *csvConfig) error {
-	r, err := conn.Query("select * from `" + db + "`.`" + tbl + "`")
+func (opt *Options) genOutput(ctx context.Context, queries []string, db string, tbl string, bufPool *sync.Pool) error {
+	err := opt.showRowCount(ctx, queries, db, tbl)
+	if err != nil {
+		return err
+	}
+	p, err := opt.startTableProgress(ctx, queries, db, tbl)
+	if err != nil {
+		return err
+	}
+	defer p.finish()
+	// the DDL is read before the rows, the connection may be the only one
+	var create string
+	if opt.format == formatFramed {
+		create, err = getCreateTable(ctx, db, tbl)
+		if err != nil {
+			return err
+		}
+	}
+	var defined []string
+	if opt.safeColumns {
+		defined, err = getColumnNames(ctx, db, tbl)
+		if err != nil {
+			return err
+		}
+	}
+	rows, cols, rowResults, err := opt.openRows(ctx, queries, tbl)
 	if err != nil {
 		return err
 	}
-	colTypes, err := r.ColumnTypes()
+	defer rows.Close()
+	if opt.safeColumns {
+		checkColumnOrder(db, tbl, defined, cols)
+	}
+	var (
+		r  rowIterator = p.wrap(rows)
+		cr *checksumRows
+	)
+	if opt.checksumAlgorithm != "" {
+		cr, err = newChecksumRows(r, opt.checksumAlgorithm, opt.rowChecksums, opt.dataDir, db, tbl)
+		if err != nil {
+			return err
+		}
+		defer cr.close()
+		r = cr
+	}
+	r = opt.wrapJSONRows(r, cols, tbl)
+	var fname string
+	switch {
+	case opt.format == formatMongoJSON:
+		fname, err = showMongoJSON(r, opt.stdout(), rowResults, cols, opt.dataDir, db, tbl)
+	case opt.format == formatNDJSON:
+		fname, err = showNDJSON(r, opt.stdout(), rowResults, cols, opt.dataDir, db, tbl)
+	case opt.format == formatPrepared:
+		fname, err = showPrepared(r, opt.stdout(), rowResults, cols, opt.dataDir, db, tbl)
+	case opt.format == formatFramed:
+		fname, err = showFramed(r, opt.stdout(), rowResults, cols, opt.dataDir, db, tbl, create)
+	case opt.csvConf.enable:
+		var fnames []string
+		fnames, err = showLoad(r, rowResults, cols, db, tbl, &opt.csvConf)
+		if err != nil {
+			return err
+		}
+		// the columns are named when some are excluded or decoded
+		var loadCols []*Column
+		if opt.completeInsert() || hasBinaryColumn(cols) {
+			loadCols = cols
+		}
+		for _, name := range fnames {
+			stmt := loadDataStmt(refPath(name), tbl, opt.localInfile, &opt.csvConf, loadCols)
+			if opt.loadScript != nil {
+				opt.loadScript.add(db, stmt)
+			} else {
+				fmt.Fprintln(opt.stdout(), stmt)
+			}
+			if err = opt.dataFileDone(name); err != nil {
+				return err
+			}
+		}
+	default:
+		var shardCol int
+		shardCol, err = opt.shardColumn(tbl, cols)
+		if err != nil {
+			return err
+		}
+		err = showInsert(r, opt.stdout(), rowResults, cols, tbl, bufPool, opt.netBufferLength, opt.insertBatchRows, opt.maxRowSize, opt.validateUTF8, opt.completeInsert(), opt.insertMode, shardCol)
+	}
 	if err != nil {
 		return err
 	}
+	if fname != "" {
+		err = opt.dataFileDone(fname)
+		if err != nil {
+			return err
+		}
+	}
+	if cr != nil {
+		fname, err = cr.finish(opt.stdout(), tbl)
+		if err != nil {
+			return err
+		}
+		if fname != "" {
+			err = opt.dataFileDone(fname)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// openRows runs the queries of the table and returns their rows with the
+// columns of the result and the values to scan them into
+func (opt *Options) openRows(ctx context.Context, queries []string, tbl string) (*multiRows, []*Column, []any, error) {
+	r := &multiRows{ctx: ctx, queries: queries[1:], tbl: tbl}
+	err := r.open(queries[0])
+	if err != nil {
+		r.Close()
+		return nil, nil, nil, err
+	}
+	colTypes, err := r.cur.ColumnTypes()
+	if err != nil {
+		r.Close()
+		return nil, nil, nil, err
+	}
 	cols := make([]*Column, 0, len(colTypes))
 	for _, col := range colTypes {
 		var c Column
@@ -580,15 +2239,86 @@ func genOutput(db string, tbl string, bufPool *sync.Pool, netBufferLength int, l
 		c.Type = col.DatabaseTypeName()
 		cols = append(cols, &c)
 	}
+	applyCasts(cols, opt.casts[tbl])
+	if opt.failOnLossy {
+		if err = checkLossless(cols, tbl); err != nil {
+			r.Close()
+			return nil, nil, nil, err
+		}
+	}
 	rowResults := make([]any, 0, len(cols))
 	for range cols {
 		var v sql.RawBytes
 		rowResults = append(rowResults, &v)
 	}
-	if !csvConf.enable {
-		return showInsert(r, rowResults, cols, tbl, bufPool, netBufferLength)
+	return r, cols, rowResults, nil
+}
+
+// multiRows iterates over the rows of several queries of the same table
+// as if they were the result of one query
+type multiRows struct {
+	ctx context.Context
+	cur *sql.Rows
+	// cancel ends the context of the current query
+	cancel  context.CancelFunc
+	qctx    context.Context
+	queries []string
+	tbl     string
+	err     error
+}
+
+// open runs the next query within query-timeout of its own. No row of the
+// query is read yet, so it can be retried.
+func (m *multiRows) open(query string) error {
+	m.qctx, m.cancel = queryContext(m.ctx)
+	var err error
+	m.cur, err = connRetry.query(m.qctx, m.what(), query)
+	return queryTimeoutError(m.ctx, m.qctx, err, m.what())
+}
+
+func (m *multiRows) what() string {
+	return "read table " + quoteIdent(m.tbl)
+}
+
+func (m *multiRows) Next() bool {
+	for m.cur != nil {
+		if m.cur.Next() {
+			return true
+		}
+		if m.err = m.cur.Err(); m.err != nil {
+			m.err = queryTimeoutError(m.ctx, m.qctx, m.err, m.what())
+			return false
+		}
+		if m.err = m.cur.Close(); m.err != nil {
+			return false
+		}
+		m.cur = nil
+		m.cancel()
+		if len(m.queries) == 0 {
+			return false
+		}
+		m.err = m.open(m.queries[0])
+		m.queries = m.queries[1:]
+	}
+	return false
+}
+
+func (m *multiRows) Scan(dest ...any) error {
+	return m.cur.Scan(dest...)
+}
+
+func (m *multiRows) Err() error {
+	return m.err
+}
+
+func (m *multiRows) Close() error {
+	if m.cancel != nil {
+		defer m.cancel()
+	}
+	if m.cur == nil {
+		return nil
 	}
-	return showLoad(r, rowResults, cols, db, tbl, localInfile, csvConf)
+	return m.cur.Close()
 }
 
 func convertValue(v any, typ string) string {
@@ -604,16 +2334,60 @@ func convertValue(v any, typ string) string {
 			return retStr
 		}
 		return "'" + retStr + "'" // NaN, +Inf, -Inf, maybe no hacking need in the future
-	case "int", "tinyint", "smallint", "bigint", "unsigned bigint", "unsigned int", "unsigned tinyint", "unsigned smallint", "double", "bool", "boolean", "":
+	case "int", "tinyint", "smallint", "bigint", "unsigned bigint", "unsigned int", "unsigned tinyint", "unsigned smallint", "double", "bool", "boolean":
+		return string(ret)
+	case "decimal", "numeric":
+		// the exact digits of the server, a quoted value would be converted
+		// back from a string on restore
+		return string(ret)
+	case "date", "datetime", "timestamp", "time":
+		// the text of the server as is, zero dates and fractional seconds
+		// included. it is escaped like a string, as a value forced to the
+		// type by -cast may hold anything
+		return quoteValue(ret)
+	case "":
 		// why empty string in column type?
 		// see https://github.com/matrixorigin/matrixone/issues/8050#issuecomment-1431251524
-		return string(ret)
+		// the type is unknown, only booleans and numbers are kept unquoted.
+		// use -cast to force the type of the column
+		if isBoolOrNumber(ret) {
+			return string(ret)
+		}
+		return quoteValue(ret)
 	case "vecf32", "vecf64":
 		return string(ret)
+	case "blob", "binary", "varbinary":
+		return hexLiteral(ret)
 	default:
-		str := strings.Replace(string(ret), "\\", "\\\\", -1)
-		return "'" + strings.Replace(str, "'", "\\'", -1) + "'"
+		return quoteValue(ret)
+	}
+}
+
+var numberLiteral = regexp.MustCompile(`^[-+]?(\d+\.?\d*|\.\d+)([eE][-+]?\d+)?$`)
+
+func isBoolOrNumber(v []byte) bool {
+	s := string(v)
+	return strings.EqualFold(s, "true") || strings.EqualFold(s, "false") || numberLiteral.MatchString(s)
+}
+
+// quoteIdent quotes an identifier with backticks, doubling the backticks
+// in it as MySQL does
+func quoteIdent(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// quoteIdents quotes the names and joins them with commas
+func quoteIdents(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = quoteIdent(name)
 	}
+	return strings.Join(quoted, ",")
+}
+
+func quoteValue(v []byte) string {
+	str := strings.Replace(string(v), "\\", "\\\\", -1)
+	return "'" + strings.Replace(str, "'", "\\'", -1) + "'"
 }
 
 func convertValue2(v any, typ string) (sql.RawBytes, string) {
@@ -627,10 +2401,17 @@ func convertValue2(v any, typ string) (sql.RawBytes, string) {
 		// why empty string in column type?
 		// see https://github.com/matrixorigin/matrixone/issues/8050#issuecomment-1431251524
 		return ret, defaultFmt
+	case "decimal", "numeric":
+		return ret, defaultFmt
+	case "date", "datetime", "timestamp", "time":
+		return ret, defaultFmt
 	case "json":
 		return ret, jsonFmt
 	case "vecf32", "vecf64":
 		return ret, defaultFmt
+	case "blob", "binary", "varbinary":
+		// decoded by the SET clause of the LOAD DATA statement
+		return sql.RawBytes(hex.EncodeToString(ret)), defaultFmt
 	default:
 		//note: do not use the quoteFmt instead of the standard package csv,
 		//it is error-prone.
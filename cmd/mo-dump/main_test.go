@@ -21,14 +21,25 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 	"unicode/utf8"
 
 	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
+func TestMain(m *testing.M) {
+	// the catalog of the mocked servers has relpersistence, getTables does
+	// not probe it in every test
+	persistenceColumn.probed = true
+	persistenceColumn.exists = true
+	os.Exit(m.Run())
+}
+
 func TestConvertValue(t *testing.T) {
 	kase := []struct {
 		val string
@@ -60,8 +71,10 @@ func TestConvertValue(t *testing.T) {
 	for _, v := range kase {
 		s := convertValue(makeValue(v.val), v.typ)
 		switch v.typ {
-		case "int", "tinyint", "smallint", "bigint", "unsigned bigint", "unsigned int", "unsigned tinyint", "unsigned smallint", "float", "double", "vecf32", "vecf64":
+		case "int", "tinyint", "smallint", "bigint", "unsigned bigint", "unsigned int", "unsigned tinyint", "unsigned smallint", "float", "double", "decimal", "vecf32", "vecf64":
 			require.Equal(t, v.val, s)
+		case "blob":
+			require.Equal(t, "x'617361'", s)
 		default:
 
 			require.Equal(t, fmt.Sprintf("'%v'", v.val), s)
@@ -69,6 +82,78 @@ func TestConvertValue(t *testing.T) {
 	}
 }
 
+func TestConvertValueBoolLikeString(t *testing.T) {
+	kases := []struct {
+		val  string
+		typ  string
+		want string
+	}{
+		{"true", "varchar", "'true'"},
+		{"false", "char", "'false'"},
+		{"true", "bool", "true"},
+		// unknown type, see convertValue
+		{"true", "", "true"},
+		{"FALSE", "", "FALSE"},
+		{"-1.5e3", "", "-1.5e3"},
+		{"truely", "", "'truely'"},
+		{"2021-01-01", "", "'2021-01-01'"},
+		{"it's", "", "'it\\'s'"},
+	}
+	for _, k := range kases {
+		require.Equal(t, k.want, convertValue(makeValue(k.val), k.typ), k.val)
+	}
+}
+
+func TestConvertValueDecimal(t *testing.T) {
+	kases := []string{
+		"12345678901234567890123456789012345678",
+		"-0.00000000000000000000000000000000000001",
+		"99999999999999999999.999999999999999999",
+		"1.10",
+		"0",
+	}
+	for _, v := range kases {
+		require.Equal(t, v, convertValue(makeValue(v), "DECIMAL"))
+		require.Equal(t, v, convertValue(makeValue(v), "numeric"))
+		dt, f := convertValue2(makeValue(v), "DECIMAL")
+		require.Equal(t, v, fmt.Sprintf(f, dt))
+	}
+	require.Equal(t, "NULL", convertValue(new(sql.RawBytes), "decimal"))
+}
+
+func TestConvertValueTemporal(t *testing.T) {
+	kases := []struct {
+		val string
+		typ string
+	}{
+		{"0000-00-00", "date"},
+		{"2024-02-29", "DATE"},
+		{"0000-00-00 00:00:00", "datetime"},
+		{"2024-02-29 23:59:59.999999", "DATETIME"},
+		{"2024-02-29 23:59:59.000001", "timestamp"},
+		{"-838:59:59.000000", "time"},
+		{"12:34:56.123456", "TIME"},
+	}
+	for _, k := range kases {
+		require.Equal(t, "'"+k.val+"'", convertValue(makeValue(k.val), k.typ))
+		dt, f := convertValue2(makeValue(k.val), k.typ)
+		require.Equal(t, k.val, fmt.Sprintf(f, dt))
+
+		// the csv field is written as is
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		line := make([]string, 2)
+		toCsvFields([]any{makeValue(k.val), new(sql.RawBytes)}, []*Column{{Type: k.typ}, {Type: k.typ}}, line, nil)
+		require.NoError(t, w.Write(line))
+		w.Flush()
+		require.Equal(t, k.val+",\\N\n", buf.String())
+	}
+	require.Equal(t, "NULL", convertValue(new(sql.RawBytes), "datetime"))
+
+	// a quote or a backslash is escaped as in a string
+	require.Equal(t, `'2024-02-29\' or \'1\\'`, convertValue(makeValue(`2024-02-29' or '1\`), "datetime"))
+}
+
 func makeValue(val string) interface{} {
 	tmp := sql.RawBytes(val)
 	return &tmp
@@ -112,7 +197,7 @@ func TestShowCreateTable(t *testing.T) {
 	for _, v := range kases {
 		r, w, _ := os.Pipe()
 		os.Stdout = w
-		showCreateTable(v.sql, v.withNextLine)
+		showCreateTable(os.Stdout, v.sql, v.withNextLine)
 
 		e := w.Close()
 		require.Nil(t, e)
@@ -175,7 +260,7 @@ func Test_toCsvFields(t *testing.T) {
 		},
 	}
 	line := make([]string, 1)
-	toCsvFields(args1, cols1, line)
+	toCsvFields(args1, cols1, line, nil)
 	want := "\\10\\36\\86\\"
 	assert.Equal(t, want, line[0])
 }
@@ -193,7 +278,7 @@ func Test_toCsvLine(t *testing.T) {
 	bb := bytes.Buffer{}
 	cw1 := csv.NewWriter(&bb)
 	cw1.Comma = '\t'
-	err := toCsvLine(cw1, args1, cols1, line)
+	err := toCsvLine(cw1, args1, cols1, line, nil)
 	assert.NoError(t, err)
 	want := "\\10\\36\\86\\"
 	assert.Equal(t, want, line[0])
@@ -371,6 +456,33 @@ func TestGetCreateDB(t *testing.T) {
 	}
 }
 
+func TestQuoteIdent(t *testing.T) {
+	require.Equal(t, "`t1`", quoteIdent("t1"))
+	require.Equal(t, "`foo``bar`", quoteIdent("foo`bar"))
+	require.Equal(t, "````", quoteIdent("`"))
+	require.Equal(t, "`a`,`b``c`", quoteIdents([]string{"a", "b`c"}))
+}
+
+func TestGetCreateTableQuoted(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	mock.ExpectQuery("show create table `d``b`.`foo``bar`").
+		WillReturnRows(sqlmock.NewRows([]string{"Table", "Create"}).AddRow("foo`bar", "create table `foo``bar` (a int)"))
+	create, err := getCreateTable(context.Background(), "d`b", "foo`bar")
+	require.NoError(t, err)
+	require.Equal(t, "create table `foo``bar` (a int)", create)
+
+	mock.ExpectQuery("show create database `d``b`").
+		WillReturnRows(sqlmock.NewRows([]string{"Database", "Create"}).AddRow("d`b", "create database `d``b`"))
+	create, err = getCreateDB(context.Background(), "d`b")
+	require.NoError(t, err)
+	require.Equal(t, "create database `d``b`", create)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestGetCreateTable(t *testing.T) {
 	// create mock database
 	db, mock, err := sqlmock.New()
@@ -387,7 +499,7 @@ func TestGetCreateTable(t *testing.T) {
 	conn = db
 
 	// check the results
-	createTable, err := getCreateTable("db1", "table1")
+	createTable, err := getCreateTable(context.Background(), "db1", "table1")
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -401,3 +513,551 @@ func TestGetCreateTable(t *testing.T) {
 		t.Errorf("Unfulfilled expectations: %s", err)
 	}
 }
+
+// captureStdout returns what f writes to os.Stdout
+func captureStdout(t *testing.T, f func()) string {
+	return captureFile(t, &os.Stdout, f)
+}
+
+// captureStderr returns what f writes to os.Stderr
+func captureStderr(t *testing.T, f func()) string {
+	return captureFile(t, &os.Stderr, f)
+}
+
+func captureFile(t *testing.T, file **os.File, f func()) string {
+	old := *file
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	*file = w
+	defer func() {
+		*file = old
+	}()
+	ch := make(chan string)
+	go func() {
+		var buf bytes.Buffer
+		_, _ = buf.ReadFrom(r)
+		ch <- buf.String()
+	}()
+	f()
+	require.NoError(t, w.Close())
+	return <-ch
+}
+
+func TestDumpTableDataAddLocks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	ctx := context.Background()
+	bufPool := &sync.Pool{
+		New: func() any {
+			return &bytes.Buffer{}
+		},
+	}
+	opt := Options{netBufferLength: defaultNetBufferLength, format: formatSQL, addLocks: true}
+
+	mock.ExpectQuery("select \\* from `db1`.`t1`").
+		WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("1").AddRow("2"))
+	out := captureStdout(t, func() {
+		err = opt.dumpTableData(ctx, "db1", "t1", bufPool)
+	})
+	require.NoError(t, err)
+	require.Equal(t, "LOCK TABLES `t1` WRITE;\nINSERT INTO `t1` VALUES (1),(2);\nUNLOCK TABLES;\n\n\n\n", out)
+
+	opt.addLocks = false
+	mock.ExpectQuery("select \\* from `db1`.`t1`").
+		WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("1"))
+	out = captureStdout(t, func() {
+		err = opt.dumpTableData(ctx, "db1", "t1", bufPool)
+	})
+	require.NoError(t, err)
+	require.Equal(t, "INSERT INTO `t1` VALUES (1);\n\n\n\n", out)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDumpTableDataSingleTransaction(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	ctx := context.Background()
+	bufPool := &sync.Pool{
+		New: func() any {
+			return &bytes.Buffer{}
+		},
+	}
+	opt := Options{netBufferLength: defaultNetBufferLength, insertBatchRows: 2, format: formatSQL, singleTransaction: true}
+
+	// all statements of the table are in one transaction
+	mock.ExpectQuery("select \\* from `db1`.`t1`").
+		WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("1").AddRow("2").AddRow("3"))
+	out := captureStdout(t, func() {
+		err = opt.dumpTableData(ctx, "db1", "t1", bufPool)
+	})
+	require.NoError(t, err)
+	require.Equal(t, "BEGIN;\nINSERT INTO `t1` VALUES (1),(2);\nINSERT INTO `t1` VALUES (3);\nCOMMIT;\n\n\n\n", out)
+
+	// the rows of a failed table are rolled back
+	mock.ExpectQuery("select \\* from `db1`.`t1`").
+		WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("1").AddRow("2").AddRow("3").RowError(2, fmt.Errorf("lost")))
+	out = captureStdout(t, func() {
+		err = opt.dumpTableData(ctx, "db1", "t1", bufPool)
+	})
+	require.Error(t, err)
+	require.Equal(t, "BEGIN;\nINSERT INTO `t1` VALUES (1),(2);\nROLLBACK;\n", out)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSupportLockTables(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	ctx := context.Background()
+	mock.ExpectExec("UNLOCK TABLES").WillReturnResult(sqlmock.NewResult(0, 0))
+	require.True(t, supportLockTables(ctx))
+	mock.ExpectExec("UNLOCK TABLES").WillReturnError(fmt.Errorf("syntax error"))
+	require.False(t, supportLockTables(ctx))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestShowInsertFlush(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	bufPool := &sync.Pool{
+		New: func() any {
+			return &bytes.Buffer{}
+		},
+	}
+	cols := []*Column{{Name: "a", Type: "int"}}
+	kases := []struct {
+		netBufferLength int
+		batchRows       int
+		want            string
+	}{
+		// no limit is hit
+		{1024, 0, "INSERT INTO `t` VALUES (1),(2),(3),(4),(5);\n"},
+		// tuple limit only
+		{1024, 2, "INSERT INTO `t` VALUES (1),(2);\nINSERT INTO `t` VALUES (3),(4);\nINSERT INTO `t` VALUES (5);\n"},
+		// byte limit only, each statement holds at most 28 bytes
+		{32, 0, "INSERT INTO `t` VALUES (1),(2);\nINSERT INTO `t` VALUES (3),(4);\nINSERT INTO `t` VALUES (5);\n"},
+		// both limits, the tuple limit hits first
+		{32, 1, "INSERT INTO `t` VALUES (1);\nINSERT INTO `t` VALUES (2);\nINSERT INTO `t` VALUES (3);\nINSERT INTO `t` VALUES (4);\nINSERT INTO `t` VALUES (5);\n"},
+		// a single tuple exceeds the byte limit
+		{8, 0, "INSERT INTO `t` VALUES (1);\nINSERT INTO `t` VALUES (2);\nINSERT INTO `t` VALUES (3);\nINSERT INTO `t` VALUES (4);\nINSERT INTO `t` VALUES (5);\n"},
+	}
+	for _, k := range kases {
+		rows := sqlmock.NewRows([]string{"a"})
+		for i := 1; i <= 5; i++ {
+			rows.AddRow(fmt.Sprint(i))
+		}
+		mock.ExpectQuery("select").WillReturnRows(rows)
+		r, err := db.Query("select")
+		require.NoError(t, err)
+		var v sql.RawBytes
+		out := captureStdout(t, func() {
+			err = showInsert(r, os.Stdout, []any{&v}, cols, "t", bufPool, k.netBufferLength, k.batchRows, 0, "", false, "", -1)
+		})
+		require.NoError(t, err)
+		require.Equal(t, k.want, out)
+		require.NoError(t, r.Close())
+	}
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestShowInsertMaxRowSize(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	bufPool := &sync.Pool{
+		New: func() any {
+			return &bytes.Buffer{}
+		},
+	}
+	cols := []*Column{{Name: "a", Type: "varchar"}}
+	rows := sqlmock.NewRows([]string{"a"}).
+		AddRow("x").
+		AddRow(strings.Repeat("y", 100)).
+		AddRow(strings.Repeat("z", 50))
+	mock.ExpectQuery("select").WillReturnRows(rows)
+	r, err := db.Query("select")
+	require.NoError(t, err)
+	defer r.Close()
+
+	var v sql.RawBytes
+	var out string
+	warn := captureStderr(t, func() {
+		out = captureStdout(t, func() {
+			err = showInsert(r, os.Stdout, []any{&v}, cols, "t", bufPool, 1024, 0, 64, "", false, "", -1)
+		})
+	})
+	require.NoError(t, err)
+	require.Equal(t, "INSERT INTO `t` VALUES ('x'),('"+strings.Repeat("y", 100)+"'),('"+strings.Repeat("z", 50)+"');\n", out)
+	// INSERT INTO `t` VALUES ('yyy...');\n
+	size := len("INSERT INTO `t` VALUES ('');\n") + 100
+	require.Equal(t, fmt.Sprintf("row 2 of table `t` takes %d bytes in a single-row INSERT, more than max-row-size 64. raise max_allowed_packet of the restore target or dump the table with -csv\n", size), warn)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// rows within the limit are not reported
+	mock.ExpectQuery("select").WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("x"))
+	r2, err := db.Query("select")
+	require.NoError(t, err)
+	defer r2.Close()
+	warn = captureStderr(t, func() {
+		_ = captureStdout(t, func() {
+			err = showInsert(r2, os.Stdout, []any{&v}, cols, "t", bufPool, 1024, 0, 64, "", false, "", -1)
+		})
+	})
+	require.NoError(t, err)
+	require.Empty(t, warn)
+}
+
+func TestFilterTableKinds(t *testing.T) {
+	ctx := context.Background()
+	tables := Tables{
+		{"t1", "r"},
+		{"idx", "i"},
+		{"e1", "e"},
+		{"c1", "cluster"},
+		{"p1", "partition"},
+		{"s1", "S"},
+		{"m1", "m"},
+		{"st1", "s"},
+		{"v1", "v"},
+	}
+	opt := Options{}
+	got, err := opt.filterTableKinds(ctx, "db1", tables)
+	require.NoError(t, err)
+	require.Equal(t, Tables{{"t1", "r"}, {"e1", "e"}, {"v1", "v"}}, got)
+
+	for _, kind := range []string{"x", "", "q"} {
+		_, err = opt.filterTableKinds(ctx, "db1", Tables{{"t1", "r"}, {"u1", kind}})
+		require.Error(t, err)
+		require.True(t, strings.Contains(err.Error(), "`db1`.`u1`"))
+		require.True(t, strings.Contains(err.Error(), "-ignore-errors"))
+	}
+
+	opt.ignoreErrors = true
+	got, err = opt.filterTableKinds(ctx, "db1", Tables{{"t1", "r"}, {"u1", "x"}})
+	require.NoError(t, err)
+	require.Equal(t, Tables{{"t1", "r"}}, got)
+}
+
+func TestGetTablesSkipMissing(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	ctx := context.Background()
+	requested := Tables{{"t1", ""}, {"missing", ""}, {"t2", ""}}
+	newRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"relname", "relkind"}).AddRow("t1", "r").AddRow("t2", "r")
+	}
+
+	mock.ExpectQuery("relname in \\('t1','missing','t2'\\)").WillReturnRows(newRows())
+	_, err = getTables(ctx, "db1", append(Tables(nil), requested...), nil, false, false, false)
+	require.Error(t, err)
+	require.True(t, strings.Contains(err.Error(), "table missing not exists"))
+
+	mock.ExpectQuery("relname in \\('t1','missing','t2'\\)").WillReturnRows(newRows())
+	tables, err := getTables(ctx, "db1", append(Tables(nil), requested...), nil, true, false, false)
+	require.NoError(t, err)
+	require.Equal(t, Tables{{"t1", "r"}, {"t2", "r"}}, tables)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetTablesEscaped(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	ctx := context.Background()
+	mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables where reldatabase = 'o\\'db' and relname in ('it\\'s')" + transientCond).
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).AddRow("it's", "r"))
+	tables, err := getTables(ctx, "o'db", Tables{{"it's", ""}}, nil, false, false, false)
+	require.NoError(t, err)
+	require.Equal(t, Tables{{"it's", "r"}}, tables)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDSN(t *testing.T) {
+	ctx := context.Background()
+	opt := Options{username: "dump", password: "111", host: "127.0.0.1", port: 6001}
+
+	dsn, err := opt.dsn(ctx, opt.host, "db1")
+	require.NoError(t, err)
+	require.Equal(t, "dump:111@tcp(127.0.0.1:6001)/db1", dsn)
+
+	opt.authPlugin = authCachingSha2Password
+	dsn, err = opt.dsn(ctx, opt.host, "db1")
+	require.NoError(t, err)
+	require.Equal(t, "dump:111@tcp(127.0.0.1:6001)/db1", dsn)
+
+	opt.authPlugin = ""
+	opt.authToken = "tok:en"
+	dsn, err = opt.dsn(ctx, opt.host, "")
+	require.NoError(t, err)
+	require.Equal(t, "dump:tok:en@tcp(127.0.0.1:6001)/?allowCleartextPasswords=true", dsn)
+	cfg, err := mysql.ParseDSN(dsn)
+	require.NoError(t, err)
+	require.Equal(t, "tok:en", cfg.Passwd)
+	require.True(t, cfg.AllowCleartextPasswords)
+
+	opt.authPlugin = authNativePassword
+	dsn, err = opt.dsn(ctx, opt.host, "")
+	require.NoError(t, err)
+	require.Equal(t, "dump:tok:en@tcp(127.0.0.1:6001)/", dsn)
+
+	opt.authPlugin = "authentication_ldap_sasl"
+	_, err = opt.dsn(ctx, opt.host, "")
+	require.Error(t, err)
+
+	opt = Options{username: "dump", password: "111", host: "127.0.0.1", port: 6001}
+	opt.connectionAttributes = "program=mo-dump, purpose=nightly backup"
+	dsn, err = opt.dsn(ctx, opt.host, "db1")
+	require.NoError(t, err)
+	require.Equal(t, "dump:111@tcp(127.0.0.1:6001)/db1?connectionAttributes=program%3Amo-dump%2Cpurpose%3Anightly+backup", dsn)
+	cfg, err = mysql.ParseDSN(dsn)
+	require.NoError(t, err)
+	require.Equal(t, "program:mo-dump,purpose:nightly backup", cfg.ConnectionAttributes)
+	require.Empty(t, cfg.Params)
+
+	opt.connectionAttributes = "program"
+	_, err = opt.dsn(ctx, opt.host, "db1")
+	require.Error(t, err)
+
+	opt = Options{username: "dump", password: "111", host: "127.0.0.1", port: 6001}
+	opt.dsnParams = "interpolateParams=true&readTimeout=30s&interpolateParams=false&maxAllowedPacket=0"
+	dsn, err = opt.dsn(ctx, opt.host, "db1")
+	require.NoError(t, err)
+	require.Equal(t, "dump:111@tcp(127.0.0.1:6001)/db1?interpolateParams=false&readTimeout=30s&maxAllowedPacket=0", dsn)
+	cfg, err = mysql.ParseDSN(dsn)
+	require.NoError(t, err)
+	require.False(t, cfg.InterpolateParams)
+	require.Equal(t, 30*time.Second, cfg.ReadTimeout)
+
+	// the parameters of other options are not overridden
+	opt.authToken = "token"
+	opt.dsnParams = "allowCleartextPasswords=false"
+	_, err = opt.dsn(ctx, opt.host, "db1")
+	require.Error(t, err)
+	opt.authToken = ""
+	dsn, err = opt.dsn(ctx, opt.host, "db1")
+	require.NoError(t, err)
+	require.Equal(t, "dump:111@tcp(127.0.0.1:6001)/db1?allowCleartextPasswords=false", dsn)
+}
+
+func TestParseDSNParams(t *testing.T) {
+	ctx := context.Background()
+	params, err := parseDSNParams(ctx, "collation=utf8mb4_bin&&timeout=5s&collation=utf8mb4_general_ci", nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"collation=utf8mb4_general_ci", "timeout=5s"}, params)
+
+	_, err = parseDSNParams(ctx, "tls=true&timeout=5s", []string{"tls"})
+	require.Error(t, err)
+	_, err = parseDSNParams(ctx, "interpolateParams", nil)
+	require.Error(t, err)
+	_, err = parseDSNParams(ctx, "=true", nil)
+	require.Error(t, err)
+}
+
+func TestParseConnectionAttributes(t *testing.T) {
+	ctx := context.Background()
+	attrs, err := parseConnectionAttributes(ctx, "a=1")
+	require.NoError(t, err)
+	require.Equal(t, "a:1", attrs)
+	attrs, err = parseConnectionAttributes(ctx, "a=,b=2")
+	require.NoError(t, err)
+	require.Equal(t, "a:,b:2", attrs)
+	for _, spec := range []string{"a", "=1", "a=1,", "a:b=1", "a=1:2"} {
+		_, err = parseConnectionAttributes(ctx, spec)
+		require.Error(t, err, spec)
+	}
+}
+
+func TestShowResult(t *testing.T) {
+	var buf bytes.Buffer
+	opt := Options{}
+	opt.showResult(&buf, time.Second)
+	require.Equal(t, "/* MODUMP: NOTHING TO DUMP, COST 1s */\n", buf.String())
+
+	buf.Reset()
+	opt.dumpedObjects = 1
+	opt.showResult(&buf, time.Second)
+	require.Equal(t, "/* MODUMP SUCCESS, COST 1s */\n", buf.String())
+}
+
+func TestDumpDataNothingToDump(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("empty database", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		conn = db
+
+		opt := Options{dbs: []string{"db1"}, emptyTables: true, format: formatSQL, consistency: consistencyNone}
+		mock.ExpectQuery("show create database").
+			WillReturnRows(sqlmock.NewRows([]string{"Database", "Create"}).AddRow("db1", "CREATE DATABASE db1"))
+		mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables").
+			WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}))
+		_ = captureStdout(t, func() {
+			err = opt.dumpData(ctx)
+		})
+		require.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+		require.Equal(t, 0, opt.dumpedObjects)
+	})
+
+	t.Run("all filtered out", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		conn = db
+
+		opt := Options{dbs: []string{"db1"}, emptyTables: true, format: formatSQL, consistency: consistencyNone}
+		mock.ExpectQuery("show create database").
+			WillReturnRows(sqlmock.NewRows([]string{"Database", "Create"}).AddRow("db1", "CREATE DATABASE db1"))
+		mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables").
+			WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).
+				AddRow("__mo_index_t1", "r").
+				AddRow("idx", "i").
+				AddRow("c1", "cluster"))
+		_ = captureStdout(t, func() {
+			err = opt.dumpData(ctx)
+		})
+		require.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+		require.Equal(t, 0, opt.dumpedObjects)
+	})
+}
+
+func TestKeepAlive(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectPing()
+	mock.ExpectPing()
+	stop := startKeepAlive(context.Background(), db.PingContext, 5*time.Millisecond)
+	require.Eventually(t, func() bool {
+		return mock.ExpectationsWereMet() == nil
+	}, time.Second, 5*time.Millisecond)
+	stop()
+}
+
+func TestDumpDataTruncate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	ctx := context.Background()
+	opt := Options{
+		dbs:             []string{"db1"},
+		emptyTables:     true,
+		truncate:        true,
+		netBufferLength: defaultNetBufferLength,
+		format:          formatSQL,
+		consistency:     consistencyNone,
+	}
+	mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables").
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).
+			AddRow("t1", "r").
+			AddRow("v1", "v"))
+	mock.ExpectQuery("show create table").
+		WillReturnRows(sqlmock.NewRows([]string{"Table", "Create"}).AddRow("t1", "create table t1 (a int)"))
+	mock.ExpectQuery("show create table").
+		WillReturnRows(sqlmock.NewRows([]string{"View", "Create"}).AddRow("v1", "create view v1 as select * from t1"))
+	mock.ExpectQuery("select \\* from `db1`.`t1`").
+		WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("1"))
+	out := captureStdout(t, func() {
+		err = opt.dumpData(ctx)
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Equal(t, "USE `db1`;\n\n\nTRUNCATE TABLE `t1`;\nINSERT INTO `t1` VALUES (1);\n\n\n\n", out)
+	require.NotContains(t, out, "DROP")
+	require.NotContains(t, out, "CREATE")
+	require.Equal(t, 1, opt.dumpedObjects)
+
+	// csv reloads truncate before LOAD DATA
+	opt = Options{
+		dbs:             []string{"db1"},
+		tables:          Tables{{"t1", ""}},
+		truncate:        true,
+		netBufferLength: defaultNetBufferLength,
+		format:          formatSQL,
+		consistency:     consistencyNone,
+		toCsv:           true,
+		csvConf:         csvConfig{enable: true, fieldDelimiter: defaultFieldDelimiter},
+	}
+	mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables").
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).AddRow("t1", "r"))
+	mock.ExpectQuery("show create table").
+		WillReturnRows(sqlmock.NewRows([]string{"Table", "Create"}).AddRow("t1", "create table t1 (a int)"))
+	mock.ExpectQuery("select \\* from `db1`.`t1`").
+		WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("1"))
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(t.TempDir()))
+	defer os.Chdir(wd)
+	out = captureStdout(t, func() {
+		err = opt.dumpData(ctx)
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.NotContains(t, out, "DROP")
+	require.Less(t, strings.Index(out, "TRUNCATE TABLE `t1`;"), strings.Index(out, "LOAD DATA"))
+}
+
+func TestParseHosts(t *testing.T) {
+	ctx := context.Background()
+	hosts, err := parseHosts(ctx, "127.0.0.1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"127.0.0.1"}, hosts)
+
+	hosts, err = parseHosts(ctx, "h1, h2,h3")
+	require.NoError(t, err)
+	require.Equal(t, []string{"h1", "h2", "h3"}, hosts)
+
+	_, err = parseHosts(ctx, "h1,h2:6001")
+	require.Error(t, err)
+	_, err = parseHosts(ctx, "h1,,h3")
+	require.Error(t, err)
+}
+
+func TestOpenDBConnectionFailover(t *testing.T) {
+	ctx := context.Background()
+	db, _, err := sqlmock.NewWithDSN("dump:111@tcp(h2:6001)/db1")
+	require.NoError(t, err)
+	defer db.Close()
+
+	var opened []string
+	sqlOpen = func(_ string, dsn string) (*sql.DB, error) {
+		opened = append(opened, dsn)
+		return sql.Open("sqlmock", dsn)
+	}
+	defer func() { sqlOpen = sql.Open }()
+
+	opt := Options{username: "dump", password: "111", port: 6001, hosts: []string{"h1", "h2", "h3"}}
+	c, err := opt.openDBConnection(ctx, "db1")
+	require.NoError(t, err)
+	require.NotNil(t, c)
+	require.Equal(t, []string{"dump:111@tcp(h1:6001)/db1", "dump:111@tcp(h2:6001)/db1"}, opened)
+
+	opened = nil
+	opt.hosts = []string{"h1", "h3"}
+	_, err = opt.openDBConnection(ctx, "db1")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "h1,h3")
+	require.Equal(t, []string{"dump:111@tcp(h1:6001)/db1", "dump:111@tcp(h3:6001)/db1"}, opened)
+}
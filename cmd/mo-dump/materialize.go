@@ -0,0 +1,99 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// viewTableSchema builds a CREATE TABLE statement from the result columns
+// of the view, so its rows can be restored without the view definition
+func viewTableSchema(ctx context.Context, db, view string) (string, error) {
+	r, err := session(ctx).QueryContext(ctx, "select * from "+quoteIdent(db)+"."+quoteIdent(view)+" limit 0")
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	colTypes, err := r.ColumnTypes()
+	if err != nil {
+		return "", err
+	}
+	defs := make([]string, 0, len(colTypes))
+	for _, ct := range colTypes {
+		defs = append(defs, "  "+columnDefinition(ct))
+	}
+	return "CREATE TABLE " + quoteIdent(view) + " (\n" + strings.Join(defs, ",\n") + "\n)", nil
+}
+
+// columnDefinition maps the column type reported by the driver to a column
+// definition. Lengths the driver does not report fall back to the types
+// without a length limit.
+func columnDefinition(ct *sql.ColumnType) string {
+	typ := strings.ToLower(ct.DatabaseTypeName())
+	if strings.HasPrefix(typ, "unsigned ") {
+		typ = strings.TrimPrefix(typ, "unsigned ") + " unsigned"
+	}
+	switch typ {
+	case "decimal":
+		if p, s, ok := ct.DecimalSize(); ok {
+			typ = fmt.Sprintf("decimal(%d,%d)", p, s)
+		}
+	case "char", "varchar":
+		if n, ok := ct.Length(); ok {
+			typ = fmt.Sprintf("%s(%d)", typ, n)
+		} else {
+			typ = "text"
+		}
+	case "binary", "varbinary":
+		if n, ok := ct.Length(); ok {
+			typ = fmt.Sprintf("%s(%d)", typ, n)
+		} else {
+			typ = "blob"
+		}
+	case "":
+		// the type is unknown, see convertValue
+		typ = "text"
+	}
+	def := quoteIdent(ct.Name()) + " " + typ
+	if nullable, ok := ct.Nullable(); ok && !nullable {
+		def += " NOT NULL"
+	}
+	return def
+}
+
+// materializeView dumps the view as a table holding the rows of the view.
+// The data is a snapshot taken at the time of the dump, it does not follow
+// later changes of the base tables.
+func (opt *Options) materializeView(ctx context.Context, db, view string, bufPool *sync.Pool) error {
+	if opt.truncate {
+		fmt.Fprintf(opt.stdout(), "TRUNCATE TABLE %s;\n", quoteIdent(view))
+	} else {
+		create, err := viewTableSchema(ctx, db, view)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(opt.schema(), "/* materialized view `%s` */\n", view)
+		fmt.Fprintf(opt.schema(), "DROP TABLE IF EXISTS %s;\n", quoteIdent(view))
+		showCreateTable(opt.schema(), create, opt.splitSchema())
+	}
+	if !opt.withData(db, view) {
+		return nil
+	}
+	return opt.dumpTableData(ctx, db, view, bufPool)
+}
@@ -0,0 +1,74 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaterializeViews(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	ctx := context.Background()
+	opt := Options{
+		dbs:              []string{"db1"},
+		tables:           Tables{{"t1", ""}, {"v1", ""}},
+		netBufferLength:  defaultNetBufferLength,
+		format:           formatSQL,
+		consistency:      consistencyNone,
+		materializeViews: true,
+	}
+	mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables").
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).
+			AddRow("t1", "r").
+			AddRow("v1", "v"))
+	mock.ExpectQuery("show create table `db1`.`t1`").
+		WillReturnRows(sqlmock.NewRows([]string{"Table", "Create"}).AddRow("t1", "create table t1 (a int, b varchar(10), c decimal(10,2))"))
+	mock.ExpectQuery("show create table `db1`.`v1`").
+		WillReturnRows(sqlmock.NewRows([]string{"View", "Create"}).AddRow("v1", "create view v1 as select a, b, c, a > 1 as d from t1"))
+	mock.ExpectQuery("select \\* from `db1`.`t1`").
+		WillReturnRows(sqlmock.NewRows([]string{"a", "b", "c"}).AddRow("1", "x", "1.50"))
+	mock.ExpectQuery("select \\* from `db1`.`v1` limit 0").
+		WillReturnRows(mock.NewRowsWithColumnDefinition(
+			sqlmock.NewColumn("a").OfType("INT", int64(0)).Nullable(false),
+			sqlmock.NewColumn("b").OfType("VARCHAR", "").WithLength(10).Nullable(true),
+			sqlmock.NewColumn("c").OfType("DECIMAL", "").WithPrecisionAndScale(10, 2).Nullable(true),
+			sqlmock.NewColumn("d").OfType("UNSIGNED TINYINT", int64(0)).Nullable(true),
+		))
+	mock.ExpectQuery("select \\* from `db1`.`v1`").
+		WillReturnRows(sqlmock.NewRows([]string{"a", "b", "c", "d"}).AddRow("1", "x", "1.50", "0"))
+	out := captureStdout(t, func() {
+		err = opt.dumpData(ctx)
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.NotContains(t, out, "create view")
+	require.Contains(t, out, "/* materialized view `v1` */\n"+
+		"DROP TABLE IF EXISTS `v1`;\n"+
+		"CREATE TABLE `v1` (\n"+
+		"  `a` int NOT NULL,\n"+
+		"  `b` varchar(10),\n"+
+		"  `c` decimal(10,2),\n"+
+		"  `d` tinyint unsigned\n"+
+		");\n"+
+		"INSERT INTO `v1` VALUES (1,'x',1.50,0);\n")
+	require.Equal(t, 2, opt.dumpedObjects)
+}
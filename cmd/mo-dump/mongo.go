@@ -0,0 +1,144 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// showMongoJSON writes the rows of the table to db_tbl.json as newline
+// delimited documents which can be imported by mongoimport
+func showMongoJSON(r rowIterator, out io.Writer, rowResults []any, cols []*Column, dir string, db string, tbl string) (string, error) {
+	fname := dataFile(dir, fmt.Sprintf("%s_%s.%s", db, tbl, "json"))
+	f, err := openFiles.create(fname)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	err = toJSONLines(r, w, rowResults, cols, convertMongoValue)
+	if err != nil {
+		return "", err
+	}
+	err = w.Flush()
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(out, "/* %s */\n", mongoImportHint(db, tbl, refPath(fname)))
+	return fname, nil
+}
+
+// mongoImportHint returns the mongoimport command of the file for the
+// comment after the data. The words are quoted with shellQuote, and a */
+// in them is split across two quoted words so it does not end the comment.
+func mongoImportHint(db string, tbl string, fname string) string {
+	word := func(s string) string {
+		return strings.ReplaceAll(shellQuote(s), "*/", "*'/'")
+	}
+	return fmt.Sprintf("mongoimport --db %s --collection %s --file %s", word(db), word(tbl), word(fname))
+}
+
+// jsonConverter maps a value of the column type to its json representation
+type jsonConverter func(v any, typ string) ([]byte, error)
+
+// toJSONLines converts the result from mo to one document per line
+func toJSONLines(r rowIterator, output io.Writer, rowResults []any, cols []*Column, convert jsonConverter) error {
+	var buf bytes.Buffer
+	for r.Next() {
+		err := r.Scan(rowResults...)
+		if err != nil {
+			return err
+		}
+		buf.Reset()
+		err = toJSONDoc(&buf, rowResults, cols, convert)
+		if err != nil {
+			return err
+		}
+		buf.WriteByte('\n')
+		_, err = output.Write(buf.Bytes())
+		if err != nil {
+			return err
+		}
+	}
+	return r.Err()
+}
+
+// toJSONDoc converts one row to a json document keyed by column names.
+// The column order of the table is kept.
+func toJSONDoc(buf *bytes.Buffer, rowResults []any, cols []*Column, convert jsonConverter) error {
+	buf.WriteByte('{')
+	for i, v := range rowResults {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(cols[i].Name)
+		if err != nil {
+			return err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		val, err := convert(v, cols[i].Type)
+		if err != nil {
+			return err
+		}
+		buf.Write(val)
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+// convertMongoValue maps the value to its json representation. Numbers keep
+// their text, decimals are strings to keep the precision, dates and times
+// are ISO 8601 strings and binary data is base64 encoded.
+func convertMongoValue(v any, typ string) ([]byte, error) {
+	ret := *(v.(*sql.RawBytes))
+	if ret == nil {
+		return []byte("null"), nil
+	}
+	typ = strings.ToLower(typ)
+	switch typ {
+	case "int", "tinyint", "smallint", "bigint", "unsigned bigint", "unsigned int", "unsigned tinyint", "unsigned smallint":
+		return ret, nil
+	case "float", "double":
+		if json.Valid(ret) {
+			return ret, nil
+		}
+		return json.Marshal(string(ret)) // NaN, +Inf, -Inf
+	case "bool", "boolean":
+		switch strings.ToLower(string(ret)) {
+		case "1", "true":
+			return []byte("true"), nil
+		default:
+			return []byte("false"), nil
+		}
+	case "json", "vecf32", "vecf64":
+		return ret, nil
+	case "datetime", "timestamp":
+		return json.Marshal(strings.Replace(string(ret), " ", "T", 1))
+	default:
+		if isBinaryType(typ) {
+			return json.Marshal(base64.StdEncoding.EncodeToString(ret))
+		}
+		return json.Marshal(string(ret))
+	}
+}
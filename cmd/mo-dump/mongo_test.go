@@ -0,0 +1,118 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"os/exec"
+	"strings"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertMongoValue(t *testing.T) {
+	kases := []struct {
+		val  any
+		typ  string
+		want string
+	}{
+		{makeValue("1"), "INT", "1"},
+		{makeValue("-12"), "BIGINT", "-12"},
+		{makeValue("1.5"), "DOUBLE", "1.5"},
+		{makeValue("NaN"), "FLOAT", `"NaN"`},
+		{makeValue("1"), "BOOL", "true"},
+		{makeValue("0"), "BOOL", "false"},
+		{makeValue("12.3400"), "DECIMAL", `"12.3400"`},
+		{makeValue("2023-01-02"), "DATE", `"2023-01-02"`},
+		{makeValue("2023-01-02 03:04:05"), "DATETIME", `"2023-01-02T03:04:05"`},
+		{makeValue("\x00\x01"), "BLOB", `"AAE="`},
+		{makeValue(`{"a": 1}`), "JSON", `{"a": 1}`},
+		{makeValue("[1,2,3]"), "VECF32", "[1,2,3]"},
+		{makeValue(`a"b`), "VARCHAR", `"a\"b"`},
+		{makeValue("a"), "", `"a"`},
+		{&sql.RawBytes{}, "VARCHAR", `""`},
+	}
+	for _, k := range kases {
+		v, err := convertMongoValue(k.val, k.typ)
+		require.NoError(t, err)
+		require.Equal(t, k.want, string(v))
+	}
+	var null sql.RawBytes
+	v, err := convertMongoValue(&null, "INT")
+	require.NoError(t, err)
+	require.Equal(t, "null", string(v))
+}
+
+func TestToMongoJSON(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "name", "created", "price", "data"}).
+		AddRow("1", "a'b\"c", "2023-01-02 03:04:05", "1.20", nil).
+		AddRow("2", "中文", "2023-01-03 00:00:00", "3.40", []byte{0xff, 0x00})
+	mock.ExpectQuery("select").WillReturnRows(rows)
+	r, err := db.Query("select")
+	require.NoError(t, err)
+	defer r.Close()
+
+	cols := []*Column{
+		{Name: "id", Type: "INT"},
+		{Name: "name", Type: "VARCHAR"},
+		{Name: "created", Type: "DATETIME"},
+		{Name: "price", Type: "DECIMAL"},
+		{Name: "data", Type: "BLOB"},
+	}
+	rowResults := make([]any, 0, len(cols))
+	for range cols {
+		var v sql.RawBytes
+		rowResults = append(rowResults, &v)
+	}
+	var out bytes.Buffer
+	require.NoError(t, toJSONLines(r, &out, rowResults, cols, convertMongoValue))
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	require.Equal(t, 2, len(lines))
+	for _, line := range lines {
+		require.True(t, json.Valid([]byte(line)), line)
+	}
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &doc))
+	require.Equal(t, float64(1), doc["id"])
+	require.Equal(t, "a'b\"c", doc["name"])
+	require.Equal(t, "2023-01-02T03:04:05", doc["created"])
+	require.Equal(t, "1.20", doc["price"])
+	require.Nil(t, doc["data"])
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &doc))
+	require.Equal(t, "/wA=", doc["data"])
+	require.True(t, strings.HasPrefix(lines[0], `{"id":1,"name":`))
+}
+
+func TestMongoImportHint(t *testing.T) {
+	require.Equal(t, "mongoimport --db 'db1' --collection 't1' --file 'db1_t1.json'",
+		mongoImportHint("db1", "t1", "db1_t1.json"))
+	require.Equal(t, `mongoimport --db 'it'\''s' --collection 'a*'/'b' --file 'it'\''s_a*'/'b.json'`,
+		mongoImportHint("it's", "a*/b", "it's_a*/b.json"))
+
+	// the command stays inside the comment and sh reads the names back
+	hint := mongoImportHint("x*/", "*/*/", "/*/")
+	require.NotContains(t, hint, "*/")
+	out, err := exec.Command("sh", "-c", "printf '%s\\n' "+strings.TrimPrefix(hint, "mongoimport ")).Output()
+	require.NoError(t, err)
+	require.Equal(t, "--db\nx*/\n--collection\n*/*/\n--file\n/*/\n", string(out))
+}
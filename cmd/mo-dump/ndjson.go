@@ -0,0 +1,87 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// showNDJSON writes the rows of the table to db_tbl.ndjson as one json
+// object per line, keyed by the column names
+func showNDJSON(r rowIterator, out io.Writer, rowResults []any, cols []*Column, dir string, db string, tbl string) (string, error) {
+	fname := dataFile(dir, fmt.Sprintf("%s_%s.%s", db, tbl, "ndjson"))
+	f, err := openFiles.create(fname)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	err = toJSONLines(r, w, rowResults, cols, convertNDJSONValue)
+	if err != nil {
+		return "", err
+	}
+	err = w.Flush()
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(out, "/*!NDJSON '%s' */\n", refPath(fname))
+	return fname, nil
+}
+
+// convertNDJSONValue maps the value to json the way convertValue2 maps it
+// to csv: numbers, decimals included, are kept unquoted, json columns are
+// embedded as they are, binary data is the hex string LOAD DATA decodes
+// and everything else is a string. A value of unknown type is unquoted if
+// it is a bool or a number.
+func convertNDJSONValue(v any, typ string) ([]byte, error) {
+	if *(v.(*sql.RawBytes)) == nil {
+		return []byte("null"), nil
+	}
+	ret, f := convertValue2(v, typ)
+	if f == jsonFmt {
+		return ret, nil
+	}
+	switch strings.ToLower(typ) {
+	case "int", "tinyint", "smallint", "bigint", "unsigned bigint", "unsigned int", "unsigned tinyint", "unsigned smallint", "double", "float",
+		"decimal", "decimal64", "decimal128", "numeric":
+		if json.Valid(ret) {
+			return ret, nil
+		}
+		// NaN, +Inf, -Inf
+	case "bool", "boolean":
+		switch strings.ToLower(string(ret)) {
+		case "1", "true":
+			return []byte("true"), nil
+		default:
+			return []byte("false"), nil
+		}
+	case "":
+		if b := strings.ToLower(string(ret)); b == "true" || b == "false" {
+			return []byte(b), nil
+		}
+		if isBoolOrNumber(ret) && json.Valid(ret) {
+			return ret, nil
+		}
+	case "vecf32", "vecf64":
+		return ret, nil
+	}
+	return json.Marshal(string(ret))
+}
@@ -0,0 +1,102 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertNDJSONValue(t *testing.T) {
+	kases := []struct {
+		val  any
+		typ  string
+		want string
+	}{
+		{makeValue("1"), "INT", "1"},
+		{makeValue("-12"), "UNSIGNED BIGINT", "-12"},
+		{makeValue("1.5"), "DOUBLE", "1.5"},
+		{makeValue("NaN"), "FLOAT", `"NaN"`},
+		{makeValue("1"), "BOOL", "true"},
+		{makeValue("false"), "BOOLEAN", "false"},
+		{makeValue("12.3400"), "DECIMAL", `12.3400`},
+		{makeValue("-0.5"), "NUMERIC", `-0.5`},
+		{makeValue("2023-01-02 03:04:05"), "DATETIME", `"2023-01-02 03:04:05"`},
+		{makeValue("\x00\x01"), "BLOB", `"0001"`},
+		{makeValue(`{"a": [1, 2]}`), "JSON", `{"a": [1, 2]}`},
+		{makeValue("[1,2,3]"), "VECF64", "[1,2,3]"},
+		{makeValue("a\n\"b"), "TEXT", `"a\n\"b"`},
+		// the type is unknown
+		{makeValue("TRUE"), "", "true"},
+		{makeValue("3"), "", "3"},
+		{makeValue("+3"), "", `"+3"`},
+		{makeValue("a"), "", `"a"`},
+		{&sql.RawBytes{}, "VARCHAR", `""`},
+	}
+	for _, k := range kases {
+		v, err := convertNDJSONValue(k.val, k.typ)
+		require.NoError(t, err)
+		require.Equal(t, k.want, string(v), k.typ)
+	}
+	var null sql.RawBytes
+	v, err := convertNDJSONValue(&null, "JSON")
+	require.NoError(t, err)
+	require.Equal(t, "null", string(v))
+}
+
+func TestGenOutputNDJSON(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	bufPool := &sync.Pool{
+		New: func() any {
+			return &bytes.Buffer{}
+		},
+	}
+	dir := t.TempDir()
+	opt := Options{
+		netBufferLength: defaultNetBufferLength,
+		format:          formatNDJSON,
+		dataDir:         dir,
+	}
+	mock.ExpectQuery("select \\* from `db1`.`t1`").
+		WillReturnRows(mock.NewRowsWithColumnDefinition(
+			mock.NewColumn("id").OfType("INT", int64(0)),
+			mock.NewColumn("name").OfType("VARCHAR", ""),
+			mock.NewColumn("attrs").OfType("JSON", "")).
+			AddRow("1", "a", `{"k":"v"}`).
+			AddRow("2", nil, nil))
+	queries, err := opt.selectQueries(context.Background(), "db1", "t1")
+	require.NoError(t, err)
+	out := captureStdout(t, func() {
+		require.NoError(t, opt.genOutput(context.Background(), queries, "db1", "t1", bufPool))
+	})
+	require.NoError(t, mock.ExpectationsWereMet())
+	fname := filepath.Join(dir, "db1_t1.ndjson")
+	require.Contains(t, out, "/*!NDJSON '"+fname+"' */")
+	data, err := os.ReadFile(fname)
+	require.NoError(t, err)
+	require.Equal(t, `{"id":1,"name":"a","attrs":{"k":"v"}}`+"\n"+
+		`{"id":2,"name":null,"attrs":null}`+"\n", string(data))
+}
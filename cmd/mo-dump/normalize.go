@@ -0,0 +1,239 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	spaceRun      = regexp.MustCompile(`\s+`)
+	spaceAround   = regexp.MustCompile(`\s*([,=(])\s*`)
+	spaceBefore   = regexp.MustCompile(`\s+\)`)
+	autoIncrement = regexp.MustCompile(`(?i)\s*\bAUTO_INCREMENT=\d+`)
+)
+
+// columnOptionRanks orders the options of a column definition. The words
+// following an option keyword belong to it, up to the next keyword.
+var columnOptionRanks = map[string]int{
+	"NOT":            1,
+	"NULL":           1,
+	"DEFAULT":        2,
+	"ON":             3,
+	"AUTO_INCREMENT": 4,
+	"PRIMARY":        5,
+	"KEY":            5,
+	"UNIQUE":         6,
+	"CHARACTER":      7,
+	"CHARSET":        7,
+	"COLLATE":        8,
+	"COMMENT":        9,
+}
+
+// optionFollowers are the keywords which continue the option before them
+// instead of starting a new one, keyed by the keyword they follow
+var optionFollowers = map[string]string{
+	"NOT":       "NULL",
+	"DEFAULT":   "NULL",
+	"PRIMARY":   "KEY",
+	"UNIQUE":    "KEY",
+	"CHARACTER": "SET",
+	"ON":        "UPDATE",
+}
+
+// constraintKeywords start the elements of CREATE TABLE which are not
+// column definitions
+var constraintKeywords = map[string]bool{
+	"PRIMARY": true, "KEY": true, "INDEX": true, "UNIQUE": true, "CONSTRAINT": true,
+	"FOREIGN": true, "FULLTEXT": true, "CHECK": true,
+}
+
+// normalizeDDL canonicalizes a CREATE TABLE statement, so that structurally
+// identical tables give identical DDL:
+//   - whitespace outside of quotes is collapsed, with no space around
+//     commas, '(' and '=' and before ')', and each column or constraint is
+//     put on its own line
+//   - the AUTO_INCREMENT=N table option is removed
+//   - the table and column names are quoted with backticks
+//   - the keywords of the statement head, the column types and the column
+//     options are upper case
+//   - the options of each column are sorted: NULL, DEFAULT, ON UPDATE,
+//     AUTO_INCREMENT, PRIMARY KEY, UNIQUE, CHARACTER SET, COLLATE, COMMENT
+//
+// Quoted strings, the table options and the constraints are kept as they
+// are apart from the whitespace.
+func normalizeDDL(ddl string) string {
+	ddl = strings.TrimSuffix(strings.TrimSpace(ddl), ";")
+	ddl = mapUnquoted(ddl, func(s string) string {
+		s = spaceRun.ReplaceAllString(s, " ")
+		s = spaceAround.ReplaceAllString(s, "$1")
+		s = spaceBefore.ReplaceAllString(s, ")")
+		return autoIncrement.ReplaceAllString(s, "")
+	})
+	ddl = strings.TrimSpace(ddl)
+	elems := splitUnquoted(ddl, '(')
+	if len(elems) < 2 {
+		return ddl
+	}
+	open := len(elems[0])
+	close := matchingParen(ddl, open)
+	if close < 0 {
+		return ddl
+	}
+	head := strings.Fields(ddl[:open])
+	for i := range head {
+		if i == len(head)-1 {
+			head[i] = quoteWord(head[i])
+		} else {
+			head[i] = strings.ToUpper(head[i])
+		}
+	}
+	defs := splitUnquoted(ddl[open+1:close], ',')
+	for i, def := range defs {
+		defs[i] = normalizeDefinition(def)
+	}
+	ret := strings.Join(head, " ") + " (\n  " + strings.Join(defs, ",\n  ") + "\n)"
+	if tail := strings.TrimSpace(ddl[close+1:]); tail != "" {
+		ret += " " + tail
+	}
+	return ret
+}
+
+// normalizeDefinition normalizes a column definition, a constraint is only
+// trimmed
+func normalizeDefinition(def string) string {
+	words := splitUnquoted(strings.TrimSpace(def), ' ')
+	if len(words) < 2 || constraintKeywords[strings.ToUpper(words[0])] {
+		return strings.TrimSpace(def)
+	}
+	var (
+		typ     []string
+		options [][]string
+	)
+	for i, w := range words[1:] {
+		upper := strings.ToUpper(w)
+		_, isOption := columnOptionRanks[upper]
+		prev := ""
+		if i > 0 {
+			prev = strings.ToUpper(words[i])
+		}
+		follows := optionFollowers[prev] == upper
+		switch {
+		case isOption && !follows:
+			options = append(options, []string{upper})
+		case len(options) == 0:
+			typ = append(typ, upperBeforeParen(w))
+		default:
+			if follows {
+				w = upper
+			}
+			options[len(options)-1] = append(options[len(options)-1], w)
+		}
+	}
+	sort.SliceStable(options, func(i, j int) bool {
+		return columnOptionRanks[options[i][0]] < columnOptionRanks[options[j][0]]
+	})
+	parts := []string{quoteWord(words[0]), strings.Join(typ, " ")}
+	for _, o := range options {
+		parts = append(parts, strings.Join(o, " "))
+	}
+	return strings.Join(parts, " ")
+}
+
+// splitUnquoted splits s by sep outside of quotes and parentheses
+func splitUnquoted(s string, sep byte) []string {
+	var (
+		parts []string
+		depth int
+		start int
+	)
+	i := 0
+	for i < len(s) {
+		switch ch := s[i]; {
+		case ch == '\'' || ch == '"' || ch == '`':
+			i = skipQuoted(s, i)
+			continue
+		case ch == sep && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		case ch == '(':
+			depth++
+		case ch == ')':
+			depth--
+		}
+		i++
+	}
+	return append(parts, s[start:])
+}
+
+// matchingParen returns the index of the parenthesis closing the one at
+// open, or -1
+func matchingParen(s string, open int) int {
+	depth := 0
+	i := open
+	for i < len(s) {
+		switch ch := s[i]; {
+		case ch == '\'' || ch == '"' || ch == '`':
+			i = skipQuoted(s, i)
+			continue
+		case ch == '(':
+			depth++
+		case ch == ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+		i++
+	}
+	return -1
+}
+
+// skipQuoted returns the index after the quoted string starting at i
+func skipQuoted(s string, i int) int {
+	quote := s[i]
+	for i++; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quote != '`':
+			i++
+		case s[i] == quote:
+			if i+1 < len(s) && s[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+// quoteWord quotes a name of the DDL unless it is quoted already
+func quoteWord(name string) string {
+	if strings.HasPrefix(name, "`") {
+		return name
+	}
+	return quoteIdent(name)
+}
+
+// upperBeforeParen upper cases a type name but not its arguments, such as
+// the values of an enum
+func upperBeforeParen(w string) string {
+	if i := strings.IndexByte(w, '('); i >= 0 {
+		return strings.ToUpper(w[:i]) + w[i:]
+	}
+	return strings.ToUpper(w)
+}
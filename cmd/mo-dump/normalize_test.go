@@ -0,0 +1,54 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeDDL(t *testing.T) {
+	want := "CREATE TABLE `t1` (\n" +
+		"  `id` INT NOT NULL AUTO_INCREMENT PRIMARY KEY,\n" +
+		"  `name` VARCHAR(20) DEFAULT 'a  b' COMMENT 'the name, or ''none''',\n" +
+		"  `kind` ENUM('x','Y') NULL DEFAULT NULL UNIQUE KEY,\n" +
+		"  `price` DECIMAL(10,2) NOT NULL DEFAULT 0,\n" +
+		"  `updated` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,\n" +
+		"  KEY `idx_name`(`name`,`kind`)\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4"
+	equivalent := []string{
+		"CREATE TABLE `t1` (\n" +
+			"  `id` int NOT NULL AUTO_INCREMENT PRIMARY KEY,\n" +
+			"  `name` varchar(20) DEFAULT 'a  b' COMMENT 'the name, or ''none''',\n" +
+			"  `kind` enum('x','Y') NULL DEFAULT NULL UNIQUE KEY,\n" +
+			"  `price` decimal(10,2) NOT NULL DEFAULT 0,\n" +
+			"  `updated` timestamp DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,\n" +
+			"  KEY `idx_name` (`name`,`kind`)\n" +
+			") ENGINE=InnoDB AUTO_INCREMENT=42 DEFAULT CHARSET=utf8mb4;",
+		"create table t1 ( id INT auto_increment primary key not null, name VARCHAR( 20 ) comment 'the name, or ''none''' default 'a  b' ,\n" +
+			"\tkind Enum('x', 'Y') unique key default null null,\n" +
+			"price DECIMAL(10, 2) DEFAULT 0 NOT NULL, updated TIMESTAMP on update CURRENT_TIMESTAMP default CURRENT_TIMESTAMP,\n" +
+			"KEY `idx_name`  ( `name` , `kind` ) )   ENGINE = InnoDB   AUTO_INCREMENT = 7 DEFAULT CHARSET = utf8mb4",
+	}
+	for _, ddl := range equivalent {
+		require.Equal(t, want, normalizeDDL(ddl))
+	}
+	require.Equal(t, want, normalizeDDL(want))
+
+	// a different default is kept apart
+	require.NotEqual(t, normalizeDDL("create table t1 (a int default 1)"), normalizeDDL("create table t1 (a int default 2)"))
+	// statements without a definition list are left alone
+	require.Equal(t, "CREATE VIEW `v1` AS select 1", normalizeDDL("CREATE VIEW `v1` AS select 1"))
+}
@@ -0,0 +1,109 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"sync"
+	"syscall"
+)
+
+const (
+	// reservedFiles are the descriptors left to the connections, the
+	// standard streams and the post-file commands
+	reservedFiles = 64
+	// maxDefaultOpenFiles caps the default when the soft limit is huge
+	maxDefaultOpenFiles = 4096
+	// minOpenFiles are the files a table may have open at once, its data
+	// file and its row checksums
+	minOpenFiles = 2
+)
+
+// openFiles bounds the data files open at the same time
+var openFiles = newFileLimiter(defaultMaxOpenFiles())
+
+// defaultMaxOpenFiles stays below the soft limit of open files of the
+// process
+func defaultMaxOpenFiles() int {
+	var lim syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &lim); err != nil || lim.Cur >= maxDefaultOpenFiles+reservedFiles {
+		return maxDefaultOpenFiles
+	}
+	n := int(lim.Cur) - reservedFiles
+	if n < int(lim.Cur)/2 {
+		n = int(lim.Cur) / 2
+	}
+	if n < minOpenFiles {
+		n = minOpenFiles
+	}
+	return n
+}
+
+// fileLimiter lets at most n files be open at once, opening a file blocks
+// until another one is closed
+type fileLimiter struct {
+	sem chan struct{}
+}
+
+func newFileLimiter(n int) *fileLimiter {
+	return &fileLimiter{sem: make(chan struct{}, n)}
+}
+
+func (l *fileLimiter) limit() int {
+	return cap(l.sem)
+}
+
+// limitedFile frees its slot when it is closed
+type limitedFile struct {
+	*os.File
+	l    *fileLimiter
+	once sync.Once
+}
+
+func (f *limitedFile) Close() error {
+	err := f.File.Close()
+	f.once.Do(func() {
+		<-f.l.sem
+	})
+	return err
+}
+
+func (l *fileLimiter) open(open func() (*os.File, error)) (*limitedFile, error) {
+	l.sem <- struct{}{}
+	f, err := open()
+	if err != nil {
+		<-l.sem
+		return nil, err
+	}
+	return &limitedFile{File: f, l: l}, nil
+}
+
+func (l *fileLimiter) create(name string) (*limitedFile, error) {
+	return l.open(func() (*os.File, error) {
+		return os.Create(name)
+	})
+}
+
+func (l *fileLimiter) createTemp(pattern string) (*limitedFile, error) {
+	return l.open(func() (*os.File, error) {
+		return os.CreateTemp("", pattern)
+	})
+}
+
+func (l *fileLimiter) openFile(name string) (*limitedFile, error) {
+	return l.open(func() (*os.File, error) {
+		return os.Open(name)
+	})
+}
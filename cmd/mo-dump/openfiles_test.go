@@ -0,0 +1,124 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultMaxOpenFiles(t *testing.T) {
+	var lim syscall.Rlimit
+	require.NoError(t, syscall.Getrlimit(syscall.RLIMIT_NOFILE, &lim))
+	n := defaultMaxOpenFiles()
+	require.GreaterOrEqual(t, n, minOpenFiles)
+	require.LessOrEqual(t, n, maxDefaultOpenFiles)
+	require.Less(t, uint64(n), lim.Cur)
+}
+
+func TestFileLimiter(t *testing.T) {
+	l := newFileLimiter(2)
+	dir := t.TempDir()
+	var open, peak int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			f, err := l.create(filepath.Join(dir, fmt.Sprintf("db1_t%d.csv", i)))
+			require.NoError(t, err)
+			n := atomic.AddInt32(&open, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&open, -1)
+			require.NoError(t, f.Close())
+			// closing twice frees the slot once
+			require.Error(t, f.Close())
+		}(i)
+	}
+	wg.Wait()
+	require.LessOrEqual(t, peak, int32(2))
+	require.Equal(t, 0, len(l.sem))
+	names, err := filepath.Glob(filepath.Join(dir, "*.csv"))
+	require.NoError(t, err)
+	require.Len(t, names, 20)
+
+	// a failed open frees its slot
+	_, err = l.create(filepath.Join(dir, "missing", "t.csv"))
+	require.Error(t, err)
+	require.Equal(t, 0, len(l.sem))
+}
+
+func TestDumpTableChunksMaxOpenFiles(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+	mock.MatchExpectationsInOrder(false)
+
+	saved := openFiles
+	defer func() { openFiles = saved }()
+	openFiles = newFileLimiter(minOpenFiles)
+
+	bufPool := &sync.Pool{
+		New: func() any {
+			return &bytes.Buffer{}
+		},
+	}
+	// more chunks than open files
+	opt := Options{
+		netBufferLength: defaultNetBufferLength,
+		format:          formatSQL,
+		chunkTable:      &chunkTable{"big", "id", 16},
+	}
+	mock.ExpectQuery("att_constraint_type = 'p'").WillReturnRows(sqlmock.NewRows([]string{"cnt"}).AddRow(1))
+	mock.ExpectQuery("select min\\(`id`\\), max\\(`id`\\) from `db1`.`big`").
+		WillReturnRows(sqlmock.NewRows([]string{"min", "max"}).AddRow(1, 16))
+	var want strings.Builder
+	for i := 1; i <= 16; i++ {
+		pred := fmt.Sprintf("`id` >= %d AND `id` < %d$", i, i+1)
+		switch i {
+		case 1:
+			pred = "where `id` < 2$"
+		case 16:
+			pred = "`id` >= 16$"
+		}
+		mock.ExpectQuery(pred).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(fmt.Sprint(i)))
+		fmt.Fprintf(&want, "INSERT INTO `big` VALUES (%d);\n", i)
+	}
+	out := captureStdout(t, func() {
+		err = opt.dumpTableChunks(context.Background(), "db1", "big", bufPool)
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Equal(t, want.String(), out)
+	require.Equal(t, 0, len(openFiles.sem))
+}
@@ -0,0 +1,102 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"sort"
+
+	"github.com/matrixorigin/matrixone/pkg/catalog"
+	"github.com/matrixorigin/matrixone/pkg/common/moerr"
+)
+
+const (
+	// dumpOrderCatalog keeps the order of mo_catalog.mo_tables
+	dumpOrderCatalog      = ""
+	dumpOrderAlphabetical = "alphabetical"
+	dumpOrderSizeAsc      = "size-asc"
+	dumpOrderSizeDesc     = "size-desc"
+)
+
+func checkDumpOrder(ctx context.Context, order string) error {
+	switch order {
+	case dumpOrderCatalog, dumpOrderAlphabetical, dumpOrderSizeAsc, dumpOrderSizeDesc:
+		return nil
+	default:
+		return moerr.NewInvalidInput(ctx, "unsupported dump order %s", order)
+	}
+}
+
+// sortTables sorts the tables by the order. Tables of the same size are
+// sorted by name so the output is stable between runs.
+func sortTables(tables Tables, order string, sizes map[string]int64) {
+	switch order {
+	case dumpOrderAlphabetical:
+		sort.SliceStable(tables, func(i, j int) bool {
+			return tables[i].Name < tables[j].Name
+		})
+	case dumpOrderSizeAsc, dumpOrderSizeDesc:
+		sort.SliceStable(tables, func(i, j int) bool {
+			si, sj := sizes[tables[i].Name], sizes[tables[j].Name]
+			if si == sj {
+				return tables[i].Name < tables[j].Name
+			}
+			if order == dumpOrderSizeAsc {
+				return si < sj
+			}
+			return si > sj
+		})
+	}
+}
+
+// moveViewsLast moves the views behind all other tables, keeping the order
+// inside both groups, and returns the position of the first view
+func moveViewsLast(tables Tables) int {
+	sort.SliceStable(tables, func(i, j int) bool {
+		return tables[i].Kind != catalog.SystemViewRel && tables[j].Kind == catalog.SystemViewRel
+	})
+	for i, tbl := range tables {
+		if tbl.Kind == catalog.SystemViewRel {
+			return i
+		}
+	}
+	return len(tables)
+}
+
+// getTableSizes returns the size in bytes of the ordinary tables of the database
+func getTableSizes(ctx context.Context, db string) (map[string]int64, error) {
+	r, err := session(ctx).QueryContext(ctx, "select relname, mo_table_size(reldatabase, relname) from mo_catalog.mo_tables where reldatabase = '"+escapeString(db)+"' and relkind = '"+catalog.SystemOrdinaryRel+"'")
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	sizes := make(map[string]int64)
+	for r.Next() {
+		var (
+			name string
+			size int64
+		)
+		err = r.Scan(&name, &size)
+		if err != nil {
+			return nil, err
+		}
+		sizes[name] = size
+	}
+	if err = r.Err(); err != nil {
+		return nil, err
+	}
+	return sizes, nil
+}
@@ -0,0 +1,126 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func tableNames(tables Tables) []string {
+	names := make([]string, len(tables))
+	for i, tbl := range tables {
+		names[i] = tbl.Name
+	}
+	return names
+}
+
+func TestSortTables(t *testing.T) {
+	sizes := map[string]int64{"c": 10, "a": 300, "b": 20, "d": 20}
+	newTables := func() Tables {
+		return Tables{{"c", "r"}, {"v2", "v"}, {"a", "r"}, {"v1", "v"}, {"b", "r"}, {"d", "r"}}
+	}
+	kases := []struct {
+		order string
+		want  []string
+	}{
+		{dumpOrderCatalog, []string{"c", "a", "b", "d", "v2", "v1"}},
+		{dumpOrderAlphabetical, []string{"a", "b", "c", "d", "v1", "v2"}},
+		{dumpOrderSizeAsc, []string{"c", "b", "d", "a", "v1", "v2"}},
+		{dumpOrderSizeDesc, []string{"a", "b", "d", "c", "v1", "v2"}},
+	}
+	for _, k := range kases {
+		tables := newTables()
+		sortTables(tables, k.order, sizes)
+		left := moveViewsLast(tables)
+		require.Equal(t, 4, left)
+		require.Equal(t, k.want, tableNames(tables), k.order)
+	}
+}
+
+func TestSortTablesKeepsViewDependencies(t *testing.T) {
+	tables := Tables{{"t1", "r"}, {"a_view", "v"}, {"b_view", "v"}}
+	createTable := []string{
+		"create table t1 (a int)",
+		"create view a_view as select * from b_view",
+		"create view b_view as select * from t1",
+	}
+	sortTables(tables, dumpOrderAlphabetical, nil)
+	left := moveViewsLast(tables)
+	adjustViewOrder(createTable, tables, left)
+	require.Equal(t, []string{"t1", "b_view", "a_view"}, tableNames(tables))
+}
+
+func TestSQLIdentifiers(t *testing.T) {
+	idents := sqlIdentifiers("create view `V1` as select `a``b`, c from `db1`.abc where d = 'v2' and e = \"it\\\"s v3\" -- x")
+	for _, name := range []string{"create", "view", "v1", "a`b", "c", "db1", "abc", "d", "e", "x"} {
+		require.True(t, idents[name], name)
+	}
+	for _, name := range []string{"a", "b", "v2", "v3", "s", "it"} {
+		require.False(t, idents[name], name)
+	}
+}
+
+func TestAdjustViewOrderWholeNames(t *testing.T) {
+	// view a is not a dependency of a view selecting from abc
+	tables := Tables{{"abc", "r"}, {"v_ab", "v"}, {"a", "v"}}
+	createTable := []string{
+		"create table abc (x int)",
+		"create view `v_ab` as select * from `abc` where x in (select x from `a`)",
+		"create view `a` as select 'v_ab' as name from abc",
+	}
+	adjustViewOrder(createTable, tables, 1)
+	require.Equal(t, []string{"abc", "a", "v_ab"}, tableNames(tables))
+	require.Equal(t, "create view `a` as select 'v_ab' as name from abc", createTable[1])
+}
+
+func TestAdjustViewOrderCycle(t *testing.T) {
+	tables := Tables{{"v1", "v"}, {"v2", "v"}, {"v3", "v"}, {"v4", "v"}}
+	createTable := []string{
+		"create view v1 as select * from v2",
+		"create view v2 as select * from v1",
+		"create view v3 as select * from v3_base, v3",
+		"create view v4 as select * from v3",
+	}
+	stderr := captureStderr(t, func() {
+		adjustViewOrder(createTable, tables, 0)
+	})
+	// the views outside the cycle are still ordered
+	require.Equal(t, []string{"v3", "v4", "v1", "v2"}, tableNames(tables))
+	require.Equal(t, "views `v1`, `v2` can not be ordered, their dependencies form a cycle. they are dumped in their current order\n", stderr)
+}
+
+func TestCheckDumpOrder(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, checkDumpOrder(ctx, ""))
+	require.NoError(t, checkDumpOrder(ctx, dumpOrderSizeDesc))
+	require.Error(t, checkDumpOrder(ctx, "random"))
+}
+
+func TestGetTableSizes(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	mock.ExpectQuery("select relname, mo_table_size\\(reldatabase, relname\\) from mo_catalog.mo_tables where reldatabase = 'db1' and relkind = 'r'").
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "size"}).AddRow("t1", 100).AddRow("t2", 5))
+	sizes, err := getTableSizes(context.Background(), "db1")
+	require.NoError(t, err)
+	require.Equal(t, map[string]int64{"t1": 100, "t2": 5}, sizes)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
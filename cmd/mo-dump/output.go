@@ -0,0 +1,137 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// stdout returns where the dump is written, the result file if it is set,
+// otherwise the standard output
+func (opt *Options) stdout() io.Writer {
+	if opt.out != nil {
+		return opt.out
+	}
+	return os.Stdout
+}
+
+// resultFile is the file -result-file writes the dump to
+type resultFile struct {
+	f *os.File
+	*bufio.Writer
+}
+
+// openResultFile creates the result file. The data files of the dump are
+// put in its directory, which is returned as an absolute path.
+func openResultFile(path string) (*resultFile, string, error) {
+	dir, err := absDir(path)
+	if err != nil {
+		return nil, "", err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, "", err
+	}
+	return &resultFile{f: f, Writer: bufio.NewWriter(f)}, dir, nil
+}
+
+func (r *resultFile) Close() error {
+	err := r.Flush()
+	if e := r.f.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+// absDir returns the absolute directory of the file
+func absDir(path string) (string, error) {
+	return filepath.Abs(filepath.Dir(path))
+}
+
+// dataFile returns the path a data file is created at, in dir or in the
+// working directory if dir is empty
+func dataFile(dir, name string) string {
+	return filepath.Join(dir, name)
+}
+
+// refPath returns the path the dump refers to a data file by, which is
+// absolute so that the statements work from any directory
+func refPath(fname string) string {
+	if filepath.IsAbs(fname) {
+		return fname
+	}
+	return os.Getenv("PWD") + "/" + fname
+}
+
+// gzipMembers compresses the dump as a series of gzip members, one for each
+// table. Concatenated members are still one valid gzip file, and a member is
+// flushed once its table is written, so a crash loses the table in progress
+// only and the members before it can still be decompressed.
+type gzipMembers struct {
+	w       io.Writer
+	gz      *gzip.Writer
+	open    bool // data is written to the current member
+	members int
+}
+
+func newGzipMembers(w io.Writer) *gzipMembers {
+	return &gzipMembers{w: w, gz: gzip.NewWriter(w)}
+}
+
+func (g *gzipMembers) Write(p []byte) (int, error) {
+	g.open = true
+	return g.gz.Write(p)
+}
+
+// endMember finishes the current member and flushes it to the underlying
+// writer. The next write starts a new member.
+func (g *gzipMembers) endMember() error {
+	if !g.open {
+		return nil
+	}
+	err := g.gz.Close()
+	if err != nil {
+		return err
+	}
+	g.gz.Reset(g.w)
+	g.open = false
+	g.members++
+	if f, ok := g.w.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// Close finishes the last member. A dump without any data is still written
+// as one empty member, so the output is a valid gzip file.
+func (g *gzipMembers) Close() error {
+	if g.members == 0 {
+		g.open = true
+	}
+	return g.endMember()
+}
+
+// endTableOutput ends the output of a table, which is the end of a gzip
+// member if the dump is compressed
+func (opt *Options) endTableOutput() error {
+	if g, ok := unwrapLines(opt.out).(*gzipMembers); ok {
+		return g.endMember()
+	}
+	return nil
+}
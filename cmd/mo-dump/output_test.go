@@ -0,0 +1,225 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpDataResultFile(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	dir := t.TempDir()
+	out, dataDir, err := openResultFile(filepath.Join(dir, "dump.sql"))
+	require.NoError(t, err)
+	require.Equal(t, dir, dataDir)
+
+	ctx := context.Background()
+	opt := Options{
+		dbs:             []string{"db1"},
+		tables:          Tables{{"t1", ""}},
+		toCsv:           true,
+		netBufferLength: defaultNetBufferLength,
+		format:          formatSQL,
+		consistency:     consistencyNone,
+		out:             out,
+		dataDir:         dataDir,
+		csvConf:         csvConfig{enable: true, fieldDelimiter: ',', dir: dataDir},
+	}
+	mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables").
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).AddRow("t1", "r"))
+	mock.ExpectQuery("show create table").
+		WillReturnRows(sqlmock.NewRows([]string{"Table", "Create"}).AddRow("t1", "create table t1 (a int)"))
+	mock.ExpectQuery("select \\* from `db1`.`t1`").
+		WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("1"))
+	stdout := captureStdout(t, func() {
+		err = opt.dumpData(ctx)
+	})
+	require.NoError(t, err)
+	require.NoError(t, out.Close())
+	require.Empty(t, stdout)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// the csv file is next to the result file and loaded by its absolute path
+	csv, err := os.ReadFile(filepath.Join(dir, "db1_t1.csv"))
+	require.NoError(t, err)
+	require.Equal(t, "1\n", string(csv))
+	dump, err := os.ReadFile(filepath.Join(dir, "dump.sql"))
+	require.NoError(t, err)
+	require.Contains(t, string(dump), "create table t1 (a int)")
+	require.Contains(t, string(dump), "'"+filepath.Join(dir, "db1_t1.csv")+"'")
+}
+
+func TestDumpDataCompress(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	dir := t.TempDir()
+	out, dataDir, err := openResultFile(filepath.Join(dir, "dump.sql.gz"))
+	require.NoError(t, err)
+	gz := gzip.NewWriter(out)
+
+	ctx := context.Background()
+	opt := Options{
+		dbs:             []string{"db1"},
+		tables:          Tables{{"t1", ""}},
+		toCsv:           true,
+		netBufferLength: defaultNetBufferLength,
+		format:          formatSQL,
+		consistency:     consistencyNone,
+		out:             gz,
+		dataDir:         dataDir,
+		csvConf:         csvConfig{enable: true, fieldDelimiter: ',', compress: csvCompressGzip, dir: dataDir},
+	}
+	mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables").
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).AddRow("t1", "r"))
+	mock.ExpectQuery("show create table").
+		WillReturnRows(sqlmock.NewRows([]string{"Table", "Create"}).AddRow("t1", "create table t1 (a int)"))
+	mock.ExpectQuery("select \\* from `db1`.`t1`").
+		WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("1"))
+	err = opt.dumpData(ctx)
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	require.NoError(t, out.Close())
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	dump := gunzipFile(t, filepath.Join(dir, "dump.sql.gz"))
+	require.Contains(t, dump, "create table t1 (a int)")
+	require.Contains(t, dump, "'filepath'='"+filepath.Join(dir, "db1_t1.csv.gz")+"'")
+	require.Equal(t, "1\n", gunzipFile(t, filepath.Join(dir, "db1_t1.csv.gz")))
+}
+
+func gunzipFile(t *testing.T, name string) string {
+	f, err := os.Open(name)
+	require.NoError(t, err)
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	b, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestGzipMembers(t *testing.T) {
+	var buf bytes.Buffer
+	g := newGzipMembers(&buf)
+	_, err := g.Write([]byte("table 1\n"))
+	require.NoError(t, err)
+	require.NoError(t, g.endMember())
+	complete := buf.Len()
+	// nothing is written between the tables
+	require.NoError(t, g.endMember())
+	require.Equal(t, complete, buf.Len())
+	_, err = g.Write([]byte("table 2\n"))
+	require.NoError(t, err)
+	require.NoError(t, g.Close())
+	require.Equal(t, 2, g.members)
+
+	r, err := gzip.NewReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	b, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, "table 1\ntable 2\n", string(b))
+
+	// a crash in the middle of the second member loses that table only
+	truncated := buf.Bytes()[:complete+(buf.Len()-complete)/2]
+	r, err = gzip.NewReader(bytes.NewReader(truncated))
+	require.NoError(t, err)
+	r.Multistream(false)
+	b, err = io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, "table 1\n", string(b))
+	require.NoError(t, r.Reset(bytes.NewReader(truncated[complete:])))
+	_, err = io.ReadAll(r)
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	// an empty dump is an empty member
+	buf.Reset()
+	require.NoError(t, newGzipMembers(&buf).Close())
+	r, err = gzip.NewReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	b, err = io.ReadAll(r)
+	require.NoError(t, err)
+	require.Empty(t, b)
+}
+
+func TestDumpDataGzipMembers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	var buf bytes.Buffer
+	g := newGzipMembers(&buf)
+	ctx := context.Background()
+	opt := Options{
+		dbs:             []string{"db1"},
+		netBufferLength: defaultNetBufferLength,
+		format:          formatSQL,
+		consistency:     consistencyNone,
+		out:             g,
+	}
+	mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables").
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).AddRow("t1", "r").AddRow("t2", "r"))
+	for _, tbl := range []string{"t1", "t2"} {
+		mock.ExpectQuery("show create table").
+			WillReturnRows(sqlmock.NewRows([]string{"Table", "Create"}).AddRow(tbl, "create table "+tbl+" (a int)"))
+	}
+	mock.ExpectQuery("select \\* from `db1`.`t1`").
+		WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("1"))
+	mock.ExpectQuery("select \\* from `db1`.`t2`").
+		WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("2"))
+	err = opt.dumpData(ctx)
+	require.NoError(t, err)
+	require.NoError(t, g.Close())
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// each table is a member of its own
+	br := bytes.NewReader(buf.Bytes())
+	r, err := gzip.NewReader(br)
+	require.NoError(t, err)
+	var members []string
+	for {
+		r.Multistream(false)
+		b, err := io.ReadAll(r)
+		require.NoError(t, err)
+		members = append(members, string(b))
+		if err = r.Reset(br); err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+	}
+	require.Len(t, members, 2)
+	require.Contains(t, members[0], "INSERT INTO `t1` VALUES (1);")
+	require.Contains(t, members[1], "INSERT INTO `t2` VALUES (2);")
+}
+
+func TestRefPath(t *testing.T) {
+	require.Equal(t, "/tmp/db1_t1.csv", refPath("/tmp/db1_t1.csv"))
+	require.Equal(t, os.Getenv("PWD")+"/db1_t1.csv", refPath("db1_t1.csv"))
+}
@@ -0,0 +1,200 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/matrixorigin/matrixone/pkg/catalog"
+)
+
+// tableOutput is the data of a table dumped by a worker of -parallel
+type tableOutput struct {
+	name   string
+	buf    bytes.Buffer
+	script *loadScript
+	// empty is set instead of reading the data when the table is found
+	// empty under -skip-empty-tables or -skip-empty-data-only
+	empty bool
+	err   error
+	done  chan struct{}
+}
+
+// parallelData dumps the data of the ordinary tables of a database with the
+// workers of -parallel, each on a connection of its own. A table is handed
+// to the workers only when the dump comes within opt.parallel tables of it,
+// so at most that many tables are buffered at once and no table is read
+// after the deadline. Each table is written to a buffer of its own, which
+// is copied to the dump in the order of the tables by writeTableOutput.
+type parallelData struct {
+	opt     *Options
+	ctx     context.Context
+	db      string
+	tables  Tables
+	next    int
+	outputs map[string]*tableOutput
+	work    chan *tableOutput
+	conns   []*sql.DB
+	wg      sync.WaitGroup
+	closed  bool
+}
+
+// startParallelData opens the connections of the workers and starts them
+// on the tables of db which are dumped with data
+func (opt *Options) startParallelData(ctx context.Context, db string, tables Tables, bufPool *sync.Pool) (*parallelData, error) {
+	p := &parallelData{
+		opt:     opt,
+		ctx:     ctx,
+		db:      db,
+		outputs: make(map[string]*tableOutput, opt.parallel),
+		work:    make(chan *tableOutput, opt.parallel),
+	}
+	for _, tbl := range opt.dataTablesOf(db, opt.checkpoint.pending(db, tables)) {
+		if tbl.Kind == catalog.SystemOrdinaryRel {
+			p.tables = append(p.tables, tbl)
+		}
+	}
+	for i := 0; i < opt.parallel; i++ {
+		c, err := opt.openDBConnection(ctx, db)
+		if err != nil {
+			p.close()
+			return nil, err
+		}
+		p.conns = append(p.conns, c)
+	}
+	for _, c := range p.conns {
+		p.wg.Add(1)
+		go p.worker(withSession(ctx, c), bufPool)
+	}
+	p.handOut()
+	return p, nil
+}
+
+func (p *parallelData) worker(ctx context.Context, bufPool *sync.Pool) {
+	defer p.wg.Done()
+	for o := range p.work {
+		o.err = p.dump(ctx, o, bufPool)
+		close(o.done)
+	}
+}
+
+// dump writes the data of the table to the buffer of the worker, unless the
+// table is empty and would not be written anyway
+func (p *parallelData) dump(ctx context.Context, o *tableOutput, bufPool *sync.Pool) error {
+	if p.opt.skipEmptyTables || p.opt.skipEmptyData {
+		empty, err := isEmptyTable(ctx, p.db, o.name)
+		if err != nil || empty {
+			o.empty = empty
+			return err
+		}
+	}
+	w := *p.opt
+	w.out = &o.buf
+	if p.opt.loadScript != nil {
+		// the statements are added to the script in table order
+		o.script = &loadScript{db: p.db}
+		w.loadScript = o.script
+	}
+	return w.dumpTableData(ctx, p.db, o.name, bufPool)
+}
+
+// handOut hands the next tables to the workers until opt.parallel tables
+// are in progress or waiting to be written
+func (p *parallelData) handOut() {
+	for len(p.outputs) < p.opt.parallel && p.next < len(p.tables) && p.ctx.Err() == nil {
+		o := &tableOutput{name: p.tables[p.next].Name, done: make(chan struct{})}
+		p.next++
+		p.outputs[o.name] = o
+		p.work <- o
+	}
+}
+
+// wait returns the output of the table once its worker is done with it, or
+// nil if the table was not handed out, such as after the deadline
+func (p *parallelData) wait(name string) *tableOutput {
+	o := p.outputs[name]
+	if o == nil {
+		return nil
+	}
+	<-o.done
+	delete(p.outputs, name)
+	p.handOut()
+	return o
+}
+
+// close stops the workers and closes their connections. The tables handed
+// out and not written yet are dumped to the end, their output is dropped.
+func (p *parallelData) close() {
+	if p.closed {
+		return
+	}
+	p.closed = true
+	close(p.work)
+	p.wg.Wait()
+	for _, c := range p.conns {
+		c.Close()
+	}
+}
+
+// writeTableOutput copies the data of a table dumped by a worker to the dump
+// and returns the error the worker got
+func (opt *Options) writeTableOutput(db string, o *tableOutput) error {
+	_, err := o.buf.WriteTo(opt.stdout())
+	if err != nil {
+		return err
+	}
+	if o.script != nil {
+		for _, stmt := range o.script.stmts {
+			opt.loadScript.add(db, stmt)
+		}
+	}
+	return o.err
+}
+
+// getCreateTables reads the DDL of the tables with n workers and returns it
+// in the order of the tables. Each query runs on a connection of its own
+// from the pool.
+func getCreateTables(db string, tables Tables, n int) ([]string, error) {
+	if n < 1 {
+		n = 1
+	}
+	creates := make([]string, len(tables))
+	errs := make([]error, len(tables))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				creates[i], errs[i] = getCreateTable(context.Background(), db, tables[i].Name)
+			}
+		}()
+	}
+	for i := range tables {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return creates, nil
+}
@@ -0,0 +1,194 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+// workerConnections makes openDBConnection open the connections of the
+// workers of -parallel on a mock of their own and counts them
+func workerConnections(t *testing.T) (sqlmock.Sqlmock, *int) {
+	dsn := "workers of " + t.Name()
+	db, mock, err := sqlmock.NewWithDSN(dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	// the workers query in any order
+	mock.MatchExpectationsInOrder(false)
+
+	opened := new(int)
+	sqlOpen = func(string, string) (*sql.DB, error) {
+		*opened++
+		return sql.Open("sqlmock", dsn)
+	}
+	t.Cleanup(func() { sqlOpen = sql.Open })
+	return mock, opened
+}
+
+func TestDumpDataParallel(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+	// the DDL is read in any order
+	mock.MatchExpectationsInOrder(false)
+	workers, opened := workerConnections(t)
+
+	ctx := context.Background()
+	opt := Options{
+		username:        "dump",
+		host:            "127.0.0.1",
+		port:            6001,
+		dbs:             []string{"db1"},
+		netBufferLength: defaultNetBufferLength,
+		format:          formatSQL,
+		consistency:     consistencyNone,
+		parallel:        3,
+	}
+	mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables").
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).
+			AddRow("t1", "r").AddRow("v1", "v").AddRow("t2", "r").AddRow("t3", "r").AddRow("t4", "r"))
+	mock.ExpectQuery("show create table `db1`.`t1`").
+		WillReturnRows(sqlmock.NewRows([]string{"Table", "Create"}).AddRow("t1", "create table t1 (a int)"))
+	mock.ExpectQuery("show create table `db1`.`v1`").
+		WillReturnRows(sqlmock.NewRows([]string{"View", "Create"}).AddRow("v1", "create view v1 as select a from t4"))
+	for _, tbl := range []string{"t2", "t3", "t4"} {
+		mock.ExpectQuery("show create table `db1`.`" + tbl + "`").
+			WillReturnRows(sqlmock.NewRows([]string{"Table", "Create"}).AddRow(tbl, "create table "+tbl+" (a int)"))
+	}
+	// the data is read by the workers on their own connections, the first
+	// tables are the slowest, they are still written first
+	for i, tbl := range []string{"t1", "t2", "t3", "t4"} {
+		workers.ExpectQuery("select \\* from `db1`.`" + tbl + "`").
+			WillDelayFor(time.Duration(4-i) * 20 * time.Millisecond).
+			WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow(fmt.Sprint(i + 1)))
+	}
+	out := captureStdout(t, func() {
+		err = opt.dumpData(ctx)
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.NoError(t, workers.ExpectationsWereMet())
+	require.Equal(t, 3, *opened)
+	var want string
+	for i, tbl := range []string{"t1", "t2", "t3", "t4"} {
+		want += fmt.Sprintf("DROP TABLE IF EXISTS `%s`;\ncreate table %s (a int);\nINSERT INTO `%s` VALUES (%d);\n\n\n\n", tbl, tbl, tbl, i+1)
+	}
+	want += "DROP VIEW IF EXISTS `v1`;\ncreate view v1 as select a from t4;\n\n\n"
+	require.Equal(t, want, out)
+	require.Equal(t, 5, opt.dumpedObjects)
+}
+
+func TestParallelDataHandOut(t *testing.T) {
+	workers, opened := workerConnections(t)
+
+	opt := Options{
+		username:         "dump",
+		host:             "127.0.0.1",
+		port:             6001,
+		netBufferLength:  defaultNetBufferLength,
+		format:           formatSQL,
+		parallel:         2,
+		skipEmptyTables:  true,
+		schemaOnlyTables: ignoreTables{"t5": true},
+	}
+	tables := Tables{{"t1", "r"}, {"v1", "v"}, {"t2", "r"}, {"t3", "r"}, {"t4", "r"}, {"t5", "r"}}
+	bufPool := &sync.Pool{
+		New: func() any {
+			return &bytes.Buffer{}
+		},
+	}
+	// the empty table is checked before its data would be read
+	workers.ExpectQuery("select 1 from `db1`.`t1` limit 1").WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+	workers.ExpectQuery("select 1 from `db1`.`t2` limit 1").WillReturnRows(sqlmock.NewRows([]string{"1"}))
+	workers.ExpectQuery("select 1 from `db1`.`t3` limit 1").WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+	workers.ExpectQuery("select \\* from `db1`.`t1`").WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow(1))
+	workers.ExpectQuery("select 1 from `db1`.`t4` limit 1").WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+	workers.ExpectQuery("select \\* from `db1`.`t3`").WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow(3))
+	workers.ExpectQuery("select \\* from `db1`.`t4`").WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow(4))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p, err := opt.startParallelData(ctx, "db1", tables, bufPool)
+	require.NoError(t, err)
+	require.Equal(t, 2, *opened)
+	// only as many tables as workers are handed out ahead of the dump,
+	// views and tables without data are not
+	require.Equal(t, Tables{{"t1", "r"}, {"t2", "r"}, {"t3", "r"}, {"t4", "r"}}, p.tables)
+	require.Equal(t, 2, p.next)
+
+	o := p.wait("t1")
+	require.NoError(t, o.err)
+	require.Equal(t, "INSERT INTO `t1` VALUES (1);\n\n\n\n", o.buf.String())
+	require.Equal(t, 3, p.next)
+	o = p.wait("t2")
+	require.NoError(t, o.err)
+	require.True(t, o.empty)
+	require.Zero(t, o.buf.Len())
+	for i, tbl := range []string{"t3", "t4"} {
+		o = p.wait(tbl)
+		require.NoError(t, o.err)
+		require.Equal(t, fmt.Sprintf("INSERT INTO `%s` VALUES (%d);\n\n\n\n", tbl, i+3), o.buf.String())
+	}
+	p.close()
+	require.NoError(t, workers.ExpectationsWereMet())
+
+	// nothing is handed out after the deadline
+	cancel()
+	p, err = opt.startParallelData(ctx, "db1", tables, bufPool)
+	require.NoError(t, err)
+	require.Zero(t, p.next)
+	require.Nil(t, p.wait("t1"))
+	p.close()
+}
+
+func TestGetCreateTables(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	// the workers read the DDL in any order
+	mock.MatchExpectationsInOrder(false)
+	var tables Tables
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("t%d", i)
+		tables = append(tables, Table{name, "r"})
+		mock.ExpectQuery("show create table `db1`.`" + name + "`").
+			WillReturnRows(sqlmock.NewRows([]string{"Table", "Create Table"}).AddRow(name, "create table "+name+" (a int)"))
+	}
+	creates, err := getCreateTables("db1", tables, 4)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Len(t, creates, len(tables))
+	for i, tbl := range tables {
+		require.Equal(t, "create table "+tbl.Name+" (a int)", creates[i])
+	}
+
+	mock.ExpectQuery("show create table `db1`.`t0`").
+		WillReturnRows(sqlmock.NewRows([]string{"Table", "Create Table"}).AddRow("t0", "create table t0 (a int)"))
+	mock.ExpectQuery("show create table `db1`.`t1`").WillReturnError(errors.New("gone"))
+	_, err = getCreateTables("db1", tables[:2], 2)
+	require.Error(t, err)
+}
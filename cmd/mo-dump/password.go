@@ -0,0 +1,70 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/matrixorigin/matrixone/pkg/common/moerr"
+)
+
+const (
+	// passwordEnv is read for the password if -p is not given, which keeps
+	// the password out of the shell history and the process list
+	passwordEnv = "MO_PWD"
+	// passwordStdinArg as -p reads the password from stdin
+	passwordStdinArg = "-"
+)
+
+// resolvePassword returns the password to connect with. An explicit -p
+// comes first, -p- reading one line of stdin included, then MO_PWD, then
+// one line of stdin if -password-stdin is given, and the default password
+// at last.
+func resolvePassword(ctx context.Context, password string, explicit, fromStdin bool, stdin io.Reader) (string, error) {
+	switch {
+	case password == passwordStdinArg:
+		// read below
+	case explicit:
+		return password, nil
+	default:
+		if v, ok := os.LookupEnv(passwordEnv); ok {
+			return v, nil
+		}
+		if !fromStdin {
+			return password, nil
+		}
+	}
+	line, err := bufio.NewReader(stdin).ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", moerr.NewInvalidInput(ctx, "can not read the password from stdin: %v", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// flagSet reports if the flag is given on the command line
+func flagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
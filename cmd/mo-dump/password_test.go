@@ -0,0 +1,64 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolvePassword(t *testing.T) {
+	ctx := context.Background()
+	stdin := func() *strings.Reader { return strings.NewReader("from stdin\nnext line\n") }
+
+	// without MO_PWD
+	t.Setenv(passwordEnv, "")
+	os.Unsetenv(passwordEnv)
+	got, err := resolvePassword(ctx, defaultPassword, false, false, stdin())
+	require.NoError(t, err)
+	require.Equal(t, defaultPassword, got)
+	got, err = resolvePassword(ctx, "secret", true, true, stdin())
+	require.NoError(t, err)
+	require.Equal(t, "secret", got)
+	got, err = resolvePassword(ctx, passwordStdinArg, true, false, stdin())
+	require.NoError(t, err)
+	require.Equal(t, "from stdin", got)
+	got, err = resolvePassword(ctx, defaultPassword, false, true, strings.NewReader("no newline\r\n"))
+	require.NoError(t, err)
+	require.Equal(t, "no newline", got)
+	got, err = resolvePassword(ctx, defaultPassword, false, true, strings.NewReader("last"))
+	require.NoError(t, err)
+	require.Equal(t, "last", got)
+	_, err = resolvePassword(ctx, defaultPassword, false, true, strings.NewReader(""))
+	require.Error(t, err)
+
+	// explicit -p, -p- included, beats MO_PWD beats -password-stdin
+	t.Setenv(passwordEnv, "from env")
+	got, err = resolvePassword(ctx, "secret", true, false, stdin())
+	require.NoError(t, err)
+	require.Equal(t, "secret", got)
+	got, err = resolvePassword(ctx, defaultPassword, false, false, stdin())
+	require.NoError(t, err)
+	require.Equal(t, "from env", got)
+	got, err = resolvePassword(ctx, passwordStdinArg, true, false, stdin())
+	require.NoError(t, err)
+	require.Equal(t, "from stdin", got)
+	got, err = resolvePassword(ctx, defaultPassword, false, true, stdin())
+	require.NoError(t, err)
+	require.Equal(t, "from env", got)
+}
@@ -0,0 +1,67 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// getPrimaryKey returns the primary key columns of the table in the order
+// of their definition, none if the table has no primary key
+func getPrimaryKey(ctx context.Context, db, tbl string) ([]string, error) {
+	r, err := session(ctx).QueryContext(ctx, "select attname from mo_catalog.mo_columns where att_database = '"+escapeString(db)+
+		"' and att_relname = '"+escapeString(tbl)+"' and att_constraint_type = 'p' and att_is_hidden = 0 order by attnum")
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var cols []string
+	for r.Next() {
+		var col string
+		if err = r.Scan(&col); err != nil {
+			return nil, err
+		}
+		cols = append(cols, col)
+	}
+	if err = r.Err(); err != nil {
+		return nil, err
+	}
+	return cols, nil
+}
+
+// primaryKeyOrder adds the primary key of the table to the ORDER BY clause
+// of the rows for order-by-primary-key, so two dumps of the same data write
+// the rows in the same order. A table without primary key stays unordered.
+func (opt *Options) primaryKeyOrder(ctx context.Context, db, tbl, order string) (string, error) {
+	if !opt.orderByPrimaryKey {
+		return order, nil
+	}
+	pk, err := getPrimaryKey(ctx, db, tbl)
+	if err != nil {
+		return "", err
+	}
+	if len(pk) == 0 {
+		fmt.Fprintf(os.Stderr, "table `%s`.`%s` has no primary key, its rows are dumped unordered\n", db, tbl)
+		return order, nil
+	}
+	list := quoteIdents(pk)
+	if order == "" {
+		return " order by " + list, nil
+	}
+	return order + "," + list, nil
+}
@@ -0,0 +1,60 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectQueriesOrderByPrimaryKey(t *testing.T) {
+	ctx := context.Background()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	pkQuery := func(tbl string) string {
+		return "select attname from mo_catalog.mo_columns where att_database = 'db1' and att_relname = '" + tbl +
+			"' and att_constraint_type = 'p' and att_is_hidden = 0 order by attnum"
+	}
+	mock.ExpectQuery(pkQuery("t1")).WillReturnRows(sqlmock.NewRows([]string{"attname"}).AddRow("a").AddRow("b"))
+	mock.ExpectQuery(pkQuery("t2")).WillReturnRows(sqlmock.NewRows([]string{"attname"}).AddRow("id"))
+	mock.ExpectQuery(pkQuery("t3")).WillReturnRows(sqlmock.NewRows([]string{"attname"}))
+
+	opt := Options{
+		netBufferLength:    defaultNetBufferLength,
+		orderByPrimaryKey:  true,
+		sortForCompression: map[string][]string{"t2": {"status"}},
+	}
+	queries, err := opt.selectQueries(ctx, "db1", "t1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"select * from `db1`.`t1` order by `a`,`b`"}, queries)
+
+	// the primary key breaks the ties of the sort columns
+	queries, err = opt.selectQueries(ctx, "db1", "t2")
+	require.NoError(t, err)
+	require.Equal(t, []string{"select * from `db1`.`t2` order by `status`,`id`"}, queries)
+
+	stderr := captureStderr(t, func() {
+		queries, err = opt.selectQueries(ctx, "db1", "t3")
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"select * from `db1`.`t3`"}, queries)
+	require.Equal(t, "table `db1`.`t3` has no primary key, its rows are dumped unordered\n", stderr)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,117 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// showPrepared writes the rows of the table to db_tbl.tuples and emits the
+// statement template they are bound to. Each line of the file is a json
+// array holding the parameters of one EXECUTE of the template.
+func showPrepared(r rowIterator, out io.Writer, rowResults []any, cols []*Column, dir string, db string, tbl string) (string, error) {
+	fname := dataFile(dir, fmt.Sprintf("%s_%s.%s", db, tbl, "tuples"))
+	f, err := openFiles.create(fname)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	err = toPreparedTuples(r, w, rowResults, cols)
+	if err != nil {
+		return "", err
+	}
+	err = w.Flush()
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(out, "/*!PREPARED '%s' %s */\n", refPath(fname), preparedTemplate(tbl, cols))
+	return fname, nil
+}
+
+// preparedTemplate returns the INSERT statement with one placeholder per
+// column. Binary columns are sent hex encoded and decoded by the server.
+func preparedTemplate(tbl string, cols []*Column) string {
+	names := make([]string, len(cols))
+	params := make([]string, len(cols))
+	for i, col := range cols {
+		names[i] = quoteIdent(col.Name)
+		params[i] = "?"
+		if isBinaryType(col.Type) {
+			params[i] = "decode(?, 'hex')"
+		}
+	}
+	return "INSERT INTO " + quoteIdent(tbl) + " (" + strings.Join(names, ",") + ") VALUES (" + strings.Join(params, ",") + ")"
+}
+
+// toPreparedTuples converts the result from mo to one json array per line
+func toPreparedTuples(r rowIterator, output io.Writer, rowResults []any, cols []*Column) error {
+	var buf bytes.Buffer
+	for r.Next() {
+		err := r.Scan(rowResults...)
+		if err != nil {
+			return err
+		}
+		buf.Reset()
+		buf.WriteByte('[')
+		for i, v := range rowResults {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			param, err := convertPreparedParam(v, cols[i].Type)
+			if err != nil {
+				return err
+			}
+			buf.Write(param)
+		}
+		buf.WriteString("]\n")
+		_, err = output.Write(buf.Bytes())
+		if err != nil {
+			return err
+		}
+	}
+	return r.Err()
+}
+
+// convertPreparedParam returns the parameter as json. All values are bound
+// as strings in their text form and converted by the server, so nothing is
+// lost on the way.
+func convertPreparedParam(v any, typ string) ([]byte, error) {
+	ret := *(v.(*sql.RawBytes))
+	if ret == nil {
+		return []byte("null"), nil
+	}
+	if isBinaryType(typ) {
+		return json.Marshal(hex.EncodeToString(ret))
+	}
+	return json.Marshal(string(ret))
+}
+
+func isBinaryType(typ string) bool {
+	switch strings.ToLower(typ) {
+	case "blob", "binary", "varbinary":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreparedTemplate(t *testing.T) {
+	cols := []*Column{
+		{Name: "id", Type: "INT"},
+		{Name: "name", Type: "VARCHAR"},
+		{Name: "data", Type: "BLOB"},
+	}
+	require.Equal(t, "INSERT INTO `t1` (`id`,`name`,`data`) VALUES (?,?,decode(?, 'hex'))", preparedTemplate("t1", cols))
+	require.Equal(t, "INSERT INTO `t1` (`id`) VALUES (?)", preparedTemplate("t1", cols[:1]))
+}
+
+func TestToPreparedTuples(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "name", "data"}).
+		AddRow("1", "a'b\"c\n", []byte{0xff, 0x00}).
+		AddRow("2", nil, nil)
+	mock.ExpectQuery("select").WillReturnRows(rows)
+	r, err := db.Query("select")
+	require.NoError(t, err)
+	defer r.Close()
+
+	cols := []*Column{
+		{Name: "id", Type: "INT"},
+		{Name: "name", Type: "VARCHAR"},
+		{Name: "data", Type: "VARBINARY"},
+	}
+	rowResults := make([]any, 0, len(cols))
+	for range cols {
+		var v sql.RawBytes
+		rowResults = append(rowResults, &v)
+	}
+	var out bytes.Buffer
+	require.NoError(t, toPreparedTuples(r, &out, rowResults, cols))
+	require.Equal(t, "[\"1\",\"a'b\\\"c\\n\",\"ff00\"]\n[\"2\",null,null]\n", out.String())
+
+	lines := bytes.Split(bytes.TrimSuffix(out.Bytes(), []byte("\n")), []byte("\n"))
+	require.Equal(t, 2, len(lines))
+	var params []*string
+	require.NoError(t, json.Unmarshal(lines[0], &params))
+	require.Equal(t, 3, len(params))
+	require.Equal(t, "a'b\"c\n", *params[1])
+}
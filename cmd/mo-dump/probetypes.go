@@ -0,0 +1,135 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// probeSampleRows is how many rows -probe-types reads of each table
+const probeSampleRows = 10
+
+// how -probe-types reports the handling of the values of a column whose
+// type the driver reports empty, see convertValue
+const (
+	probeUnquoted = "unquoted, as bool or number"
+	probeQuoted   = "quoted, as string"
+	probeMixed    = "mixed, unquoted if bool or number, quoted otherwise"
+	probeNoValues = "unknown, no non-NULL value sampled"
+)
+
+// probeHandling tells how convertValue would write the sampled values of a
+// column of unknown type
+func probeHandling(samples [][]byte) string {
+	var unquoted, quoted int
+	for _, v := range samples {
+		if isBoolOrNumber(v) {
+			unquoted++
+		} else {
+			quoted++
+		}
+	}
+	switch {
+	case unquoted > 0 && quoted > 0:
+		return probeMixed
+	case unquoted > 0:
+		return probeUnquoted
+	case quoted > 0:
+		return probeQuoted
+	default:
+		return probeNoValues
+	}
+}
+
+// showProbeTypes samples the rows of every dumped table and lists the
+// columns whose type the driver reports empty, with some of their values and
+// how they would be written, without dumping anything. Columns given to
+// -cast are not listed.
+func (opt *Options) showProbeTypes(ctx context.Context, w io.Writer) (err error) {
+	if conn == nil {
+		conn, err = opt.openDBConnection(ctx, opt.dbs[0])
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "DATABASE\tTABLE\tCOLUMN\tSAMPLES\tHANDLING\n")
+	requested := opt.tables
+	for _, db := range opt.dbs {
+		tables := append(Tables(nil), requested...)
+		tables, err = getTables(ctx, db, tables, opt.ignoreTables, opt.skipMissingTables, opt.allowEmptyMatch, opt.includeTemporary)
+		if err != nil {
+			return err
+		}
+		for _, tbl := range tables {
+			if opt.kindHandling(tbl.Kind) != handlingData {
+				continue
+			}
+			err = opt.probeTable(ctx, tw, db, tbl.Name)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return tw.Flush()
+}
+
+func (opt *Options) probeTable(ctx context.Context, w io.Writer, db, tbl string) error {
+	list, err := opt.selectList(ctx, db, tbl)
+	if err != nil {
+		return err
+	}
+	query := fmt.Sprintf("select %s from %s.%s limit %d", list, quoteIdent(db), quoteIdent(tbl), probeSampleRows)
+	r, cols, rowResults, err := opt.openRows(ctx, []string{query}, tbl)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	var unknown []int
+	for i, col := range cols {
+		if col.Type == "" {
+			unknown = append(unknown, i)
+		}
+	}
+	samples := make([][][]byte, len(cols))
+	for r.Next() {
+		if err = r.Scan(rowResults...); err != nil {
+			return err
+		}
+		for _, i := range unknown {
+			if v := *(rowResults[i].(*sql.RawBytes)); v != nil {
+				samples[i] = append(samples[i], append([]byte(nil), v...))
+			}
+		}
+	}
+	if err = r.Err(); err != nil {
+		return err
+	}
+	for _, i := range unknown {
+		values := make([]string, 0, len(samples[i]))
+		for _, v := range samples[i] {
+			values = append(values, string(v))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", db, tbl, cols[i].Name, strings.Join(values, ","), probeHandling(samples[i]))
+	}
+	return nil
+}
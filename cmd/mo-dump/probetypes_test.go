@@ -0,0 +1,67 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShowProbeTypes(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	ctx := context.Background()
+	opt := Options{
+		dbs:   []string{"db1"},
+		casts: map[string]map[string]string{"t1": {"id2": "uuid"}},
+	}
+	mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables").
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).
+			AddRow("t1", "r").
+			AddRow("v1", "v"))
+	// flag and code are reported empty-typed by the driver
+	mock.ExpectQuery("select \\* from `db1`.`t1` limit 10").
+		WillReturnRows(mock.NewRowsWithColumnDefinition(
+			mock.NewColumn("id").OfType("INT", int64(0)),
+			mock.NewColumn("flag").OfType("", ""),
+			mock.NewColumn("code").OfType("", ""),
+			mock.NewColumn("note").OfType("", ""),
+			mock.NewColumn("id2").OfType("", "")).
+			AddRow(1, "true", "007", nil, "a-b").
+			AddRow(2, "false", "x1", nil, "c-d"))
+
+	var buf bytes.Buffer
+	err = opt.showProbeTypes(ctx, &buf)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Equal(t, ""+
+		"DATABASE  TABLE  COLUMN  SAMPLES     HANDLING\n"+
+		"db1       t1     flag    true,false  "+probeUnquoted+"\n"+
+		"db1       t1     code    007,x1      "+probeMixed+"\n"+
+		"db1       t1     note                "+probeNoValues+"\n", buf.String())
+}
+
+func TestProbeHandling(t *testing.T) {
+	require.Equal(t, probeUnquoted, probeHandling([][]byte{[]byte("1.5"), []byte("TRUE")}))
+	require.Equal(t, probeQuoted, probeHandling([][]byte{[]byte("abc")}))
+	require.Equal(t, probeMixed, probeHandling([][]byte{[]byte("1"), []byte("abc")}))
+	require.Equal(t, probeNoValues, probeHandling(nil))
+}
@@ -0,0 +1,121 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// progressInterval is how often the progress of a table is reported
+var progressInterval = 5 * time.Second
+
+// progress reports the rows of a table written so far against the rows
+// selected by the queries of the table
+type progress struct {
+	w     io.Writer
+	name  string
+	total int64
+	rows  atomic.Int64
+	start time.Time
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// startProgress reports the progress of the table every progressInterval
+// until finish is called
+func startProgress(w io.Writer, db, tbl string, total int64) *progress {
+	p := &progress{
+		w:     w,
+		name:  quoteIdent(db) + "." + quoteIdent(tbl),
+		total: total,
+		start: time.Now(),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go func() {
+		defer close(p.done)
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+				p.show()
+			}
+		}
+	}()
+	return p
+}
+
+func (p *progress) show() {
+	rows := p.rows.Load()
+	percent := 100.0
+	if p.total > 0 {
+		percent = float64(rows) * 100 / float64(p.total)
+	}
+	fmt.Fprintf(p.w, "progress %s: %d/%d rows (%.1f%%), %v elapsed\n",
+		p.name, rows, p.total, percent, time.Since(p.start).Round(time.Second))
+}
+
+// finish stops the periodic reports and reports the final count
+func (p *progress) finish() {
+	if p == nil {
+		return
+	}
+	close(p.stop)
+	<-p.done
+	p.show()
+}
+
+// wrap counts the rows read from r
+func (p *progress) wrap(r rowIterator) rowIterator {
+	if p == nil {
+		return r
+	}
+	return &progressRows{rowIterator: r, p: p}
+}
+
+type progressRows struct {
+	rowIterator
+	p *progress
+}
+
+func (r *progressRows) Next() bool {
+	if !r.rowIterator.Next() {
+		return false
+	}
+	r.p.rows.Add(1)
+	return true
+}
+
+// startTableProgress starts reporting the progress of the table on stderr
+// if -progress is set. The total is counted with the queries of the table,
+// so it follows -where and the other row filters.
+func (opt *Options) startTableProgress(ctx context.Context, queries []string, db, tbl string) (*progress, error) {
+	if !opt.progress {
+		return nil, nil
+	}
+	total, err := countRows(ctx, queries)
+	if err != nil {
+		return nil, err
+	}
+	return startProgress(os.Stderr, db, tbl, total), nil
+}
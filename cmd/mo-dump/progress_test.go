@@ -0,0 +1,63 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenOutputProgress(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	defer func(d time.Duration) { progressInterval = d }(progressInterval)
+	progressInterval = 10 * time.Millisecond
+
+	opt := Options{
+		netBufferLength: defaultNetBufferLength,
+		format:          formatSQL,
+		progress:        true,
+	}
+	bufPool := &sync.Pool{New: func() any { return &bytes.Buffer{} }}
+	query := "select * from `db1`.`t1` where (a > 0)"
+	mock.ExpectQuery("select count\\(\\*\\) from \\(select \\* from `db1`.`t1` where \\(a > 0\\)\\) as t").
+		WillReturnRows(sqlmock.NewRows([]string{"count(*)"}).AddRow(4))
+	mock.ExpectQuery("select \\* from `db1`.`t1` where \\(a > 0\\)").
+		WillDelayFor(30 * time.Millisecond).
+		WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("1").AddRow("2").AddRow("3").AddRow("4"))
+	var out string
+	warn := captureStderr(t, func() {
+		out = captureStdout(t, func() {
+			err = opt.genOutput(context.Background(), []string{query}, "db1", "t1", bufPool)
+		})
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	// the progress never goes to the dump
+	require.Equal(t, "INSERT INTO `t1` VALUES (1),(2),(3),(4);\n", out)
+	lines := strings.Split(strings.TrimSuffix(warn, "\n"), "\n")
+	require.Greater(t, len(lines), 1)
+	require.True(t, strings.HasPrefix(lines[0], "progress `db1`.`t1`: 0/4 rows (0.0%), "), lines[0])
+	require.True(t, strings.HasPrefix(lines[len(lines)-1], "progress `db1`.`t1`: 4/4 rows (100.0%), "), lines[len(lines)-1])
+}
@@ -0,0 +1,46 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/matrixorigin/matrixone/pkg/common/moerr"
+)
+
+// queryTimeout bounds each query of the dump, from its start to its last
+// row, set by -query-timeout. Zero leaves the queries unbounded.
+var queryTimeout time.Duration
+
+// queryContext returns the context of one query, which ends after
+// queryTimeout
+func queryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, queryTimeout)
+}
+
+// queryTimeoutError replaces the error of a query whose own context ran out
+// of queryTimeout by an error naming the query. The end of the parent, such
+// as the deadline of the dump, is left as it is.
+func queryTimeoutError(parent, ctx context.Context, err error, what string) error {
+	if err == nil || parent.Err() != nil || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	return moerr.NewInternalError(parent, "%s exceeded query-timeout %v", what, queryTimeout)
+}
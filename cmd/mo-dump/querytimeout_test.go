@@ -0,0 +1,108 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryTimeout(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+	defer func(d time.Duration) { queryTimeout = d }(queryTimeout)
+	queryTimeout = 50 * time.Millisecond
+
+	ctx := context.Background()
+	opt := Options{netBufferLength: defaultNetBufferLength, format: formatSQL}
+	mock.ExpectQuery("select \\* from `db1`.`t1`").
+		WillDelayFor(time.Minute).
+		WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("1"))
+	_, _, _, err = opt.openRows(ctx, []string{"select * from `db1`.`t1`"}, "t1")
+	require.ErrorContains(t, err, "read table `t1` exceeded query-timeout 50ms")
+
+	mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables").
+		WillDelayFor(time.Minute).
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}))
+	_, err = getTables(ctx, "db1", nil, nil, false, false, false)
+	require.ErrorContains(t, err, "list tables of `db1` exceeded query-timeout 50ms")
+
+	mock.ExpectQuery("show create table").
+		WillDelayFor(time.Minute).
+		WillReturnRows(sqlmock.NewRows([]string{"Table", "Create Table"}))
+	_, err = getCreateTable(context.Background(), "db1", "t1")
+	require.ErrorContains(t, err, "show create table `db1`.`t1` exceeded query-timeout 50ms")
+
+	mock.ExpectQuery("select distinct table_name, refer_db_name, refer_table_name from mo_catalog.mo_foreign_keys").
+		WillDelayFor(time.Minute).
+		WillReturnRows(sqlmock.NewRows([]string{"table_name", "refer_db_name", "refer_table_name"}))
+	_, err = getForeignKeys(ctx, "db1")
+	require.ErrorContains(t, err, "read foreign keys of `db1` exceeded query-timeout 50ms")
+
+	mock.ExpectQuery("select name, args, body from mo_catalog.mo_stored_procedure").
+		WillDelayFor(time.Minute).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "args", "body"}))
+	_, err = getProcedures(ctx, "db1")
+	require.ErrorContains(t, err, "read procedures of `db1` exceeded query-timeout 50ms")
+
+	mock.ExpectQuery("select last_seq_num, is_called from `db1`.`s1`").
+		WillDelayFor(time.Minute).
+		WillReturnRows(sqlmock.NewRows([]string{"last_seq_num", "is_called"}))
+	err = showSequenceValues(ctx, io.Discard, "db1", []string{"s1"})
+	require.ErrorContains(t, err, "select last_seq_num, is_called from `db1`.`s1` exceeded query-timeout 50ms")
+
+	mock.ExpectQuery("select partition_name, (.+) from information_schema.partitions").
+		WillDelayFor(time.Minute).
+		WillReturnRows(sqlmock.NewRows([]string{"partition_name", "partition_method", "partition_expression", "partition_description"}))
+	_, err = getPartitions(ctx, "db1", "t1")
+	require.ErrorContains(t, err, "read partitions of `db1`.`t1` exceeded query-timeout 50ms")
+
+	mock.ExpectQuery("select count\\(\\*\\) from mo_catalog.mo_columns").
+		WillDelayFor(time.Minute).
+		WillReturnRows(sqlmock.NewRows([]string{"count(*)"}))
+	err = checkCommitTSColumn(ctx, "db1", "t1")
+	require.ErrorContains(t, err, "read the commit timestamp column of `db1`.`t1` exceeded query-timeout 50ms")
+
+	// the end of the parent is not a timeout of the query
+	parent, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	mock.ExpectQuery("select \\* from `db1`.`t1`").
+		WillDelayFor(time.Minute).
+		WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("1"))
+	_, _, _, err = opt.openRows(parent, []string{"select * from `db1`.`t1`"}, "t1")
+	require.Error(t, err)
+	require.NotContains(t, err.Error(), "query-timeout")
+
+	// a query within the timeout is read in full
+	mock.ExpectQuery("select \\* from `db1`.`t1`").
+		WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("1").AddRow("2"))
+	r, _, rowResults, err := opt.openRows(ctx, []string{"select * from `db1`.`t1`"}, "t1")
+	require.NoError(t, err)
+	var rows int
+	for r.Next() {
+		require.NoError(t, r.Scan(rowResults...))
+		rows++
+	}
+	require.NoError(t, r.Err())
+	require.NoError(t, r.Close())
+	require.Equal(t, 2, rows)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
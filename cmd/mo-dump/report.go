@@ -0,0 +1,115 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/matrixorigin/matrixone/pkg/catalog"
+)
+
+// kindNames are the names of the dumped relation kinds in the report
+var kindNames = map[string]string{
+	catalog.SystemOrdinaryRel: "table",
+	catalog.SystemExternalRel: "external",
+	catalog.SystemViewRel:     "view",
+}
+
+// tableCount is the row count and size of a table from its metadata
+type tableCount struct {
+	rows int64
+	size int64
+}
+
+// inspectOnly reports if the options list information about the dump
+// instead of dumping
+func (opt *Options) inspectOnly() bool {
+	return opt.reportOnly || opt.listKinds || opt.probeTypes || opt.estimateSize
+}
+
+// showReport lists the objects which would be dumped with the row count
+// and size of each table, without dumping anything
+func (opt *Options) showReport(ctx context.Context, w io.Writer) (err error) {
+	if conn == nil {
+		conn, err = opt.openDBConnection(ctx, opt.dbs[0])
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "DATABASE\tTABLE\tKIND\tROWS\tBYTES\n")
+	var totalRows, totalSize int64
+	requested := opt.tables
+	for _, db := range opt.dbs {
+		tables := append(Tables(nil), requested...)
+		tables, err = getTables(ctx, db, tables, opt.ignoreTables, opt.skipMissingTables, opt.allowEmptyMatch, opt.includeTemporary)
+		if err != nil {
+			return err
+		}
+		tables, err = opt.filterTableKinds(ctx, db, tables)
+		if err != nil {
+			return err
+		}
+		var counts map[string]tableCount
+		counts, err = getTableCounts(ctx, db)
+		if err != nil {
+			return err
+		}
+		for _, tbl := range tables {
+			if tbl.Kind != catalog.SystemOrdinaryRel {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t-\t-\n", db, tbl.Name, kindNames[tbl.Kind])
+				continue
+			}
+			c := counts[tbl.Name]
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\n", db, tbl.Name, kindNames[tbl.Kind], c.rows, c.size)
+			totalRows += c.rows
+			totalSize += c.size
+		}
+	}
+	fmt.Fprintf(tw, "TOTAL\t\t\t%d\t%d\n", totalRows, totalSize)
+	return tw.Flush()
+}
+
+// getTableCounts reads the row count and size of all ordinary tables of the
+// database in one statement
+func getTableCounts(ctx context.Context, db string) (map[string]tableCount, error) {
+	r, err := session(ctx).QueryContext(ctx, "select relname, mo_table_rows(reldatabase, relname), mo_table_size(reldatabase, relname) from mo_catalog.mo_tables where reldatabase = '"+escapeString(db)+"' and relkind = '"+catalog.SystemOrdinaryRel+"'")
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	counts := make(map[string]tableCount)
+	for r.Next() {
+		var (
+			name string
+			c    tableCount
+		)
+		err = r.Scan(&name, &c.rows, &c.size)
+		if err != nil {
+			return nil, err
+		}
+		counts[name] = c
+	}
+	if err = r.Err(); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
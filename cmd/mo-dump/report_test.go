@@ -0,0 +1,59 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShowReport(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	ctx := context.Background()
+	opt := Options{dbs: []string{"db1", "db2"}, emptyTables: true}
+	mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables").
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).
+			AddRow("t1", "r").
+			AddRow("idx", "i").
+			AddRow("v1", "v"))
+	mock.ExpectQuery("mo_table_rows").
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "rows", "size"}).AddRow("t1", 1000, 65536))
+	mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables").
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).
+			AddRow("orders", "r").
+			AddRow("ext", "e"))
+	mock.ExpectQuery("mo_table_rows").
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "rows", "size"}).AddRow("orders", 20, 4096))
+
+	var buf bytes.Buffer
+	err = opt.showReport(ctx, &buf)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Equal(t, ""+
+		"DATABASE  TABLE   KIND      ROWS  BYTES\n"+
+		"db1       t1      table     1000  65536\n"+
+		"db1       v1      view      -     -\n"+
+		"db2       orders  table     20    4096\n"+
+		"db2       ext     external  -     -\n"+
+		"TOTAL                       1020  69632\n", buf.String())
+	require.Equal(t, 0, opt.dumpedObjects)
+}
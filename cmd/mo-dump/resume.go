@@ -0,0 +1,198 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/matrixorigin/matrixone/pkg/common/moerr"
+)
+
+// checkpointEntry is one line of the checkpoint file of resume. An entry
+// without table marks the whole database as done. Offset is the size of
+// the result file once the output of the entry is flushed.
+type checkpointEntry struct {
+	DB     string `json:"db"`
+	Table  string `json:"table,omitempty"`
+	Offset int64  `json:"offset"`
+}
+
+// checkpoint records the tables whose output is complete in the result
+// file, so an interrupted dump run again with the same resume file skips
+// them and continues the result file from the last complete table
+type checkpoint struct {
+	f      *os.File
+	result *resultFile
+	// offset is the size of the result file at the last entry, -1 without
+	// any entry
+	offset int64
+	tables map[[2]string]bool
+	// count is the number of completed tables of each database
+	count map[string]int
+	dbs   map[string]bool
+}
+
+// loadCheckpoint reads the entries of the checkpoint file, if it exists, and
+// opens it to append the next ones. A line cut by the interruption is
+// ignored.
+func loadCheckpoint(path string) (*checkpoint, error) {
+	cp := &checkpoint{
+		offset: -1,
+		tables: make(map[[2]string]bool),
+		count:  make(map[string]int),
+		dbs:    make(map[string]bool),
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		var e checkpointEntry
+		if !bytes.HasSuffix(line, []byte("\n")) || json.Unmarshal(line, &e) != nil {
+			break
+		}
+		cp.add(e)
+	}
+	cp.f, err = os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+func (cp *checkpoint) add(e checkpointEntry) {
+	if e.Table == "" {
+		cp.dbs[e.DB] = true
+	} else {
+		cp.tables[[2]string{e.DB, e.Table}] = true
+		cp.count[e.DB]++
+	}
+	cp.offset = e.Offset
+}
+
+// openResult opens the result file of the dump. A resumed result file is cut
+// back to the last complete table, dropping the output of the interrupted
+// one, and written on from there.
+func (cp *checkpoint) openResult(path string) (*resultFile, string, error) {
+	if cp.offset < 0 {
+		r, dir, err := openResultFile(path)
+		cp.result = r
+		return r, dir, err
+	}
+	dir, err := absDir(path)
+	if err != nil {
+		return nil, "", err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return nil, "", moerr.NewInvalidInputNoCtx("can not resume the result file %s: %v", path, err)
+	}
+	if err = f.Truncate(cp.offset); err == nil {
+		_, err = f.Seek(cp.offset, io.SeekStart)
+	}
+	if err != nil {
+		f.Close()
+		return nil, "", err
+	}
+	cp.result = &resultFile{f: f, Writer: bufio.NewWriter(f)}
+	return cp.result, dir, nil
+}
+
+// done reports whether the output of the table is complete
+func (cp *checkpoint) done(db, tbl string) bool {
+	return cp != nil && cp.tables[[2]string{db, tbl}]
+}
+
+// doneDatabase reports whether the output of the whole database is complete
+func (cp *checkpoint) doneDatabase(db string) bool {
+	return cp != nil && cp.dbs[db]
+}
+
+// started reports whether some table of the database is complete, so its
+// DDL is already in the result file
+func (cp *checkpoint) started(db string) bool {
+	return cp != nil && cp.count[db] > 0
+}
+
+// pending returns the tables of the database which are not complete
+func (cp *checkpoint) pending(db string, tables Tables) Tables {
+	if cp == nil {
+		return tables
+	}
+	var ret Tables
+	for _, tbl := range tables {
+		if !cp.done(db, tbl.Name) {
+			ret = append(ret, tbl)
+		}
+	}
+	return ret
+}
+
+// record appends the entry once the output before it is flushed to the
+// result file
+func (cp *checkpoint) record(e checkpointEntry) error {
+	err := cp.result.Flush()
+	if err != nil {
+		return err
+	}
+	e.Offset, err = cp.result.f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err = cp.f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	if err = cp.f.Sync(); err != nil {
+		return err
+	}
+	cp.add(e)
+	return nil
+}
+
+func (cp *checkpoint) Close() error {
+	return cp.f.Close()
+}
+
+// tableDone records the table in the checkpoint of resume
+func (opt *Options) tableDone(db, tbl string) error {
+	if opt.checkpoint == nil {
+		return nil
+	}
+	err := opt.endTableOutput()
+	if err != nil {
+		return err
+	}
+	return opt.checkpoint.record(checkpointEntry{DB: db, Table: tbl})
+}
+
+// databaseDone records the database in the checkpoint of resume
+func (opt *Options) databaseDone(db string) error {
+	if opt.checkpoint == nil {
+		return nil
+	}
+	err := opt.endTableOutput()
+	if err != nil {
+		return err
+	}
+	return opt.checkpoint.record(checkpointEntry{DB: db})
+}
@@ -0,0 +1,168 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dump.ckpt")
+	result := filepath.Join(dir, "dump.sql")
+
+	// a missing checkpoint starts a new result file
+	cp, err := loadCheckpoint(path)
+	require.NoError(t, err)
+	require.EqualValues(t, -1, cp.offset)
+	require.NoError(t, os.WriteFile(result, []byte("old dump"), 0644))
+	r, _, err := cp.openResult(result)
+	require.NoError(t, err)
+	_, err = r.WriteString("t1 data;")
+	require.NoError(t, err)
+	require.NoError(t, cp.record(checkpointEntry{DB: "db1", Table: "t1"}))
+	_, err = r.WriteString("t2 da")
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	require.NoError(t, cp.Close())
+
+	// the line cut by the interruption is ignored
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"db":"db1","table":"t2","off`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	cp, err = loadCheckpoint(path)
+	require.NoError(t, err)
+	defer cp.Close()
+	require.EqualValues(t, len("t1 data;"), cp.offset)
+	require.True(t, cp.done("db1", "t1"))
+	require.False(t, cp.done("db1", "t2"))
+	require.True(t, cp.started("db1"))
+	require.False(t, cp.started("db2"))
+	require.False(t, cp.doneDatabase("db1"))
+	require.Equal(t, Tables{{"t2", "r"}}, cp.pending("db1", Tables{{"t1", "r"}, {"t2", "r"}}))
+
+	// the output of the interrupted table is dropped
+	r, _, err = cp.openResult(result)
+	require.NoError(t, err)
+	_, err = r.WriteString("t2 data;")
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	data, err := os.ReadFile(result)
+	require.NoError(t, err)
+	require.Equal(t, "t1 data;t2 data;", string(data))
+
+	// a checkpoint without its result file can not be resumed
+	_, _, err = cp.openResult(filepath.Join(dir, "missing.sql"))
+	require.Error(t, err)
+
+	var nilCheckpoint *checkpoint
+	require.False(t, nilCheckpoint.done("db1", "t1"))
+	require.False(t, nilCheckpoint.started("db1"))
+	require.Len(t, nilCheckpoint.pending("db1", Tables{{"t1", "r"}}), 1)
+}
+
+func TestDumpDataResume(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dump.ckpt")
+	result := filepath.Join(dir, "dump.sql.gz")
+
+	run := func(resumed bool, expect func()) error {
+		cp, err := loadCheckpoint(path)
+		require.NoError(t, err)
+		defer cp.Close()
+		out, _, err := cp.openResult(result)
+		require.NoError(t, err)
+		defer out.Close()
+		gz := newGzipMembers(out)
+		defer gz.Close()
+		opt := Options{
+			dbs:             []string{"db1"},
+			emptyTables:     true,
+			netBufferLength: defaultNetBufferLength,
+			format:          formatSQL,
+			consistency:     consistencyNone,
+			out:             gz,
+			checkpoint:      cp,
+		}
+		if !resumed {
+			mock.ExpectQuery("show create database").
+				WillReturnRows(sqlmock.NewRows([]string{"Database", "Create Database"}).AddRow("db1", "CREATE DATABASE `db1`"))
+		}
+		mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables").
+			WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).AddRow("t1", "r").AddRow("t2", "r"))
+		for _, tbl := range []string{"t1", "t2"} {
+			mock.ExpectQuery("show create table").
+				WillReturnRows(sqlmock.NewRows([]string{"Table", "Create Table"}).AddRow(tbl, "CREATE TABLE `"+tbl+"` (`a` INT)"))
+		}
+		expect()
+		return opt.dumpData(ctx)
+	}
+
+	// the dump is interrupted in the data of t2
+	err = run(false, func() {
+		mock.ExpectQuery("select \\* from `db1`.`t1`").
+			WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("1"))
+		mock.ExpectQuery("select \\* from `db1`.`t2`").
+			WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("2").RowError(0, fmt.Errorf("lost")))
+	})
+	require.Error(t, err)
+
+	// the database is not recreated and t1 is not read again
+	err = run(true, func() {
+		mock.ExpectQuery("select \\* from `db1`.`t2`").
+			WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("2"))
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// the appended members make one gzip stream
+	f, err := os.Open(result)
+	require.NoError(t, err)
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	data, err := io.ReadAll(gr)
+	require.NoError(t, err)
+	require.Equal(t, "DROP DATABASE IF EXISTS `db1`;\nCREATE DATABASE `db1` ;\nUSE `db1`;\n\n\n"+
+		"DROP TABLE IF EXISTS `t1`;\nCREATE TABLE `t1` (`a` INT);\nINSERT INTO `t1` VALUES (1);\n\n\n\n"+
+		"USE `db1`;\n\n\n"+
+		"DROP TABLE IF EXISTS `t2`;\nCREATE TABLE `t2` (`a` INT);\nINSERT INTO `t2` VALUES (2);\n\n\n\n", string(data))
+
+	ckpt, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSuffix(string(ckpt), "\n"), "\n")
+	require.Len(t, lines, 3)
+	require.Contains(t, lines[0], `"table":"t1"`)
+	require.Contains(t, lines[1], `"table":"t2"`)
+	require.NotContains(t, lines[2], `"table"`)
+}
@@ -0,0 +1,77 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// retryBackoff is the wait before the first retry pass, doubled for every
+// next pass
+var retryBackoff = time.Second
+
+// failedTable is a table whose data could not be dumped under ignore-errors
+type failedTable struct {
+	db  string
+	tbl string
+	err error
+}
+
+// skipFailedTable records the table for the retry passes
+func (opt *Options) skipFailedTable(db, tbl string, err error) {
+	fmt.Fprintf(os.Stderr, "skip data of table `%s`.`%s`: %v\n", db, tbl, err)
+	opt.failedTables = append(opt.failedTables, failedTable{db: db, tbl: tbl, err: err})
+}
+
+// retryFailedTables dumps the data of the failed tables again, up to
+// retryFailed passes, since the cause may be transient. The INSERTs written
+// before the failure are cleared by TRUNCATE TABLE before the retried data.
+// The tables still failing are reported at last.
+func (opt *Options) retryFailedTables(ctx context.Context, lastDb string) {
+	bufPool := &sync.Pool{
+		New: func() any {
+			return &bytes.Buffer{}
+		},
+	}
+	retried := false
+	for pass := 0; pass < opt.retryFailed && len(opt.failedTables) > 0; pass++ {
+		time.Sleep(retryBackoff << pass)
+		failed := opt.failedTables
+		opt.failedTables = nil
+		for _, f := range failed {
+			fmt.Fprintf(os.Stderr, "retry data of table `%s`.`%s`, pass %d\n", f.db, f.tbl, pass+1)
+			fmt.Fprintf(opt.stdout(), "USE %s;\n", quoteIdent(f.db))
+			if opt.format == formatSQL && !opt.csvConf.enable {
+				fmt.Fprintf(opt.stdout(), "TRUNCATE TABLE %s;\n", quoteIdent(f.tbl))
+			}
+			retried = true
+			err := opt.dumpTableData(ctx, f.db, f.tbl, bufPool)
+			if err != nil {
+				opt.skipFailedTable(f.db, f.tbl, err)
+			}
+		}
+	}
+	if retried {
+		fmt.Fprintf(opt.stdout(), "USE %s;\n", quoteIdent(lastDb))
+	}
+	for _, f := range opt.failedTables {
+		fmt.Fprintf(os.Stderr, "data of table `%s`.`%s` is not dumped: %v\n", f.db, f.tbl, f.err)
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpDataRetryFailed(t *testing.T) {
+	backoff := retryBackoff
+	retryBackoff = 0
+	defer func() { retryBackoff = backoff }()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	ctx := context.Background()
+	newOpt := func(retry int) Options {
+		return Options{
+			dbs:             []string{"db1"},
+			netBufferLength: defaultNetBufferLength,
+			format:          formatSQL,
+			consistency:     consistencyNone,
+			ignoreErrors:    true,
+			retryFailed:     retry,
+		}
+	}
+	expectSchema := func() {
+		mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables").
+			WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).
+				AddRow("t1", "r").
+				AddRow("t2", "r"))
+		mock.ExpectQuery("show create table").
+			WillReturnRows(sqlmock.NewRows([]string{"Table", "Create"}).AddRow("t1", "create table t1 (a int)"))
+		mock.ExpectQuery("show create table").
+			WillReturnRows(sqlmock.NewRows([]string{"Table", "Create"}).AddRow("t2", "create table t2 (a int)"))
+	}
+
+	// t1 fails once and succeeds on the retry
+	opt := newOpt(2)
+	expectSchema()
+	mock.ExpectQuery("select \\* from `db1`.`t1`").WillReturnError(errors.New("connection reset"))
+	mock.ExpectQuery("select \\* from `db1`.`t2`").
+		WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("2"))
+	mock.ExpectQuery("select \\* from `db1`.`t1`").
+		WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("1"))
+	var out string
+	stderr := captureStderr(t, func() {
+		out = captureStdout(t, func() {
+			err = opt.dumpData(ctx)
+		})
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Empty(t, opt.failedTables)
+	require.Contains(t, stderr, "skip data of table `db1`.`t1`: connection reset")
+	require.Contains(t, stderr, "retry data of table `db1`.`t1`, pass 1")
+	require.NotContains(t, stderr, "is not dumped")
+	require.Contains(t, out, "INSERT INTO `t2` VALUES (2);\n\n\n\nUSE `db1`;\nTRUNCATE TABLE `t1`;\nINSERT INTO `t1` VALUES (1);\n\n\n\nUSE `db1`;\n")
+
+	// t1 keeps failing and is reported after the retries
+	opt = newOpt(1)
+	expectSchema()
+	mock.ExpectQuery("select \\* from `db1`.`t1`").WillReturnError(errors.New("connection reset"))
+	mock.ExpectQuery("select \\* from `db1`.`t2`").
+		WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("2"))
+	mock.ExpectQuery("select \\* from `db1`.`t1`").WillReturnError(errors.New("connection reset"))
+	stderr = captureStderr(t, func() {
+		captureStdout(t, func() {
+			err = opt.dumpData(ctx)
+		})
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Len(t, opt.failedTables, 1)
+	require.Contains(t, stderr, "data of table `db1`.`t1` is not dumped: connection reset")
+
+	// without ignore-errors the failure ends the dump
+	opt = newOpt(0)
+	opt.ignoreErrors = false
+	expectSchema()
+	mock.ExpectQuery("select \\* from `db1`.`t1`").WillReturnError(errors.New("connection reset"))
+	captureStdout(t, func() {
+		err = opt.dumpData(ctx)
+	})
+	require.Error(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
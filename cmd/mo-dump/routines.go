@@ -0,0 +1,211 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/matrixorigin/matrixone/pkg/common/moerr"
+)
+
+// routineDelimiter ends the CREATE PROCEDURE statements, whose bodies hold
+// statements ending with ';'
+const routineDelimiter = ";;"
+
+// dumpRoutines reports if the functions and procedures of the databases are
+// dumped. They belong to the database, not to a list of its tables.
+func (opt *Options) dumpRoutines() bool {
+	return opt.routines && opt.emptyTables && !opt.truncate
+}
+
+type function struct {
+	name     string
+	args     string
+	retType  string
+	body     string
+	language string
+}
+
+func getFunctions(ctx context.Context, db string) ([]function, error) {
+	qctx, cancel := queryContext(ctx)
+	defer cancel()
+	what := "read functions of " + quoteIdent(db)
+	r, err := connRetry.query(qctx, what, "select name, args, retType, body, language from mo_catalog.mo_user_defined_function where db = '"+escapeString(db)+"' order by name")
+	if err != nil {
+		return nil, queryTimeoutError(ctx, qctx, err, what)
+	}
+	defer r.Close()
+	var funcs []function
+	for r.Next() {
+		var f function
+		if err = r.Scan(&f.name, &f.args, &f.retType, &f.body, &f.language); err != nil {
+			return nil, err
+		}
+		funcs = append(funcs, f)
+	}
+	return funcs, queryTimeoutError(ctx, qctx, r.Err(), what)
+}
+
+// functionArgs rebuilds the argument list of a function and the argument
+// types which, with the name, identify the function in DROP FUNCTION.
+// MatrixOne records the arguments as a json object of their types keyed by
+// their names, so they are listed in the order of their names.
+func functionArgs(ctx context.Context, fn, args string) (string, string, error) {
+	var decoded map[string]string
+	if err := json.Unmarshal([]byte(args), &decoded); err != nil {
+		return "", "", moerr.NewInternalError(ctx, "invalid arguments of function `%s`: %v", fn, err)
+	}
+	names := make([]string, 0, len(decoded))
+	for name := range decoded {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	decls := make([]string, 0, len(names))
+	types := make([]string, 0, len(names))
+	for _, name := range names {
+		typ := decoded[name]
+		if name == "" {
+			decls = append(decls, typ)
+		} else {
+			decls = append(decls, quoteIdent(name)+" "+typ)
+		}
+		types = append(types, typ)
+	}
+	return strings.Join(decls, ", "), strings.Join(types, ", "), nil
+}
+
+// showFunctions writes the user-defined functions of the database. They
+// come before the tables, so that the views and the procedures calling them
+// are restored after them. The body is a string literal, the mysql client
+// does not end the statement at a ';' in it, so no DELIMITER is needed.
+func showFunctions(ctx context.Context, w io.Writer, db string) error {
+	funcs, err := getFunctions(ctx, db)
+	if err != nil {
+		return err
+	}
+	for _, f := range funcs {
+		decls, types, err := functionArgs(ctx, f.name, f.args)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "DROP FUNCTION IF EXISTS %s (%s);\n", quoteIdent(f.name), types)
+		fmt.Fprintf(w, "CREATE FUNCTION %s (%s) RETURNS %s LANGUAGE %s AS %s;\n\n\n", quoteIdent(f.name), decls, f.retType, f.language, quoteValue([]byte(f.body)))
+	}
+	return nil
+}
+
+type procedure struct {
+	name string
+	args string
+	body string
+}
+
+// procedureArg is an argument of a procedure as MatrixOne records it in
+// mo_stored_procedure, a json object keyed by the argument name
+type procedureArg struct {
+	Type struct {
+		InternalType struct {
+			FamilyString string
+			DisplayWith  int32
+			Scale        int32
+			Unsigned     bool
+		}
+	}
+	InOutType int
+}
+
+// the InOutType of a procedureArg
+var inOutTypes = []string{"IN", "OUT", "INOUT"}
+
+func getProcedures(ctx context.Context, db string) ([]procedure, error) {
+	qctx, cancel := queryContext(ctx)
+	defer cancel()
+	what := "read procedures of " + quoteIdent(db)
+	r, err := connRetry.query(qctx, what, "select name, args, body from mo_catalog.mo_stored_procedure where db = '"+escapeString(db)+"' and type = 'PROCEDURE' order by name")
+	if err != nil {
+		return nil, queryTimeoutError(ctx, qctx, err, what)
+	}
+	defer r.Close()
+	var procs []procedure
+	for r.Next() {
+		var p procedure
+		if err = r.Scan(&p.name, &p.args, &p.body); err != nil {
+			return nil, err
+		}
+		procs = append(procs, p)
+	}
+	return procs, queryTimeoutError(ctx, qctx, r.Err(), what)
+}
+
+// procedureArgs rebuilds the argument list of a procedure. MatrixOne keeps
+// the arguments by name, so they are listed in the order of their names.
+func procedureArgs(ctx context.Context, proc, args string) (string, error) {
+	var decoded map[string]procedureArg
+	if err := json.Unmarshal([]byte(args), &decoded); err != nil {
+		return "", moerr.NewInternalError(ctx, "invalid arguments of procedure `%s`: %v", proc, err)
+	}
+	names := make([]string, 0, len(decoded))
+	for name := range decoded {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	list := make([]string, 0, len(names))
+	for _, name := range names {
+		arg := decoded[name]
+		if arg.InOutType < 0 || arg.InOutType >= len(inOutTypes) {
+			return "", moerr.NewInternalError(ctx, "invalid argument %s of procedure `%s`", name, proc)
+		}
+		typ := arg.Type.InternalType
+		decl := inOutTypes[arg.InOutType] + " " + quoteIdent(name) + " " + strings.ToUpper(typ.FamilyString)
+		if typ.DisplayWith > 0 {
+			if typ.Scale > 0 {
+				decl += fmt.Sprintf("(%d,%d)", typ.DisplayWith, typ.Scale)
+			} else {
+				decl += fmt.Sprintf("(%d)", typ.DisplayWith)
+			}
+		}
+		if typ.Unsigned {
+			decl += " UNSIGNED"
+		}
+		list = append(list, decl)
+	}
+	return strings.Join(list, ", "), nil
+}
+
+// showProcedures writes the stored procedures of the database. The CREATE
+// PROCEDURE statements are wrapped in DELIMITER changes for the mysql
+// client, which would otherwise end them at the first ';' of the body.
+func showProcedures(ctx context.Context, w io.Writer, db string) error {
+	procs, err := getProcedures(ctx, db)
+	if err != nil {
+		return err
+	}
+	for _, p := range procs {
+		args, err := procedureArgs(ctx, p.name, p.args)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "DROP PROCEDURE IF EXISTS %s;\n", quoteIdent(p.name))
+		fmt.Fprintf(w, "DELIMITER %s\n", routineDelimiter)
+		fmt.Fprintf(w, "CREATE PROCEDURE %s (%s) %s%s\n", quoteIdent(p.name), args, strings.TrimSuffix(strings.TrimSpace(p.body), ";"), routineDelimiter)
+		fmt.Fprintf(w, "DELIMITER ;\n\n\n")
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcedureArgs(t *testing.T) {
+	ctx := context.Background()
+	// as MatrixOne records the arguments of
+	// create procedure p (in id int, out total decimal(10,2), inout name varchar(20), in n bigint unsigned)
+	args := `{"id":{"Name":{"NumParts":1,"Star":false,"Parts":["id","","",""]},"Type":{"InternalType":{"Family":1,"FamilyString":"int","Width":32,"DisplayWith":-1,"Scale":0,"Unsigned":false}},"InOutType":0},` +
+		`"n":{"Name":{"NumParts":1,"Star":false,"Parts":["n","","",""]},"Type":{"InternalType":{"Family":1,"FamilyString":"bigint","Width":64,"DisplayWith":-1,"Scale":0,"Unsigned":true}},"InOutType":0},` +
+		`"name":{"Name":{"NumParts":1,"Star":false,"Parts":["name","","",""]},"Type":{"InternalType":{"Family":6,"FamilyString":"varchar","Width":0,"DisplayWith":20,"Scale":0,"Unsigned":false}},"InOutType":2},` +
+		`"total":{"Name":{"NumParts":1,"Star":false,"Parts":["total","","",""]},"Type":{"InternalType":{"Family":4,"FamilyString":"decimal","Width":64,"DisplayWith":10,"Scale":2,"Unsigned":false}},"InOutType":1}}`
+	list, err := procedureArgs(ctx, "p", args)
+	require.NoError(t, err)
+	require.Equal(t, "IN `id` INT, IN `n` BIGINT UNSIGNED, INOUT `name` VARCHAR(20), OUT `total` DECIMAL(10,2)", list)
+
+	list, err = procedureArgs(ctx, "p", "{}")
+	require.NoError(t, err)
+	require.Equal(t, "", list)
+
+	_, err = procedureArgs(ctx, "p", "not json")
+	require.Error(t, err)
+	_, err = procedureArgs(ctx, "p", `{"a":{"InOutType":3}}`)
+	require.Error(t, err)
+}
+
+func TestShowProcedures(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	mock.ExpectQuery("select name, args, body from mo_catalog.mo_stored_procedure where db = 'db1' and type = 'PROCEDURE' order by name").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "args", "body"}).
+			AddRow("p1", "{}", "begin select 1; select 2; end").
+			AddRow("p2", `{"a":{"Type":{"InternalType":{"FamilyString":"int","DisplayWith":-1}},"InOutType":0}}`, "begin\n  select a;\nend;\n"))
+
+	var buf bytes.Buffer
+	require.NoError(t, showProcedures(context.Background(), &buf, "db1"))
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Equal(t, ""+
+		"DROP PROCEDURE IF EXISTS `p1`;\n"+
+		"DELIMITER ;;\n"+
+		"CREATE PROCEDURE `p1` () begin select 1; select 2; end;;\n"+
+		"DELIMITER ;\n\n\n"+
+		"DROP PROCEDURE IF EXISTS `p2`;\n"+
+		"DELIMITER ;;\n"+
+		"CREATE PROCEDURE `p2` (IN `a` INT) begin\n  select a;\nend;;\n"+
+		"DELIMITER ;\n\n\n", buf.String())
+
+	// the body stays in one statement in single-line mode
+	mock.ExpectQuery("select name, args, body from mo_catalog.mo_stored_procedure").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "args", "body"}).
+			AddRow("p2", "{}", "begin\n  select 1;\n  select 2;\nend"))
+	buf.Reset()
+	require.NoError(t, showProcedures(context.Background(), newLineWriter(&buf), "db1"))
+	require.Equal(t, ""+
+		"DROP PROCEDURE IF EXISTS `p2`;\n"+
+		"DELIMITER ;;\n"+
+		"CREATE PROCEDURE `p2` () begin select 1; select 2; end;;\n"+
+		"DELIMITER ;\n", buf.String())
+}
+
+func TestFunctionArgs(t *testing.T) {
+	ctx := context.Background()
+	decls, types, err := functionArgs(ctx, "f", `{"a":"INT","b":"VARCHAR(10)"}`)
+	require.NoError(t, err)
+	require.Equal(t, "`a` INT, `b` VARCHAR(10)", decls)
+	require.Equal(t, "INT, VARCHAR(10)", types)
+
+	decls, types, err = functionArgs(ctx, "f", `{}`)
+	require.NoError(t, err)
+	require.Equal(t, "", decls)
+	require.Equal(t, "", types)
+
+	_, _, err = functionArgs(ctx, "f", "[1]")
+	require.Error(t, err)
+}
+
+func TestDumpDataRoutines(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	var buf bytes.Buffer
+	ctx := context.Background()
+	opt := Options{
+		dbs:             []string{"db1"},
+		emptyTables:     true,
+		routines:        true,
+		netBufferLength: defaultNetBufferLength,
+		format:          formatSQL,
+		consistency:     consistencyNone,
+		out:             &buf,
+	}
+	mock.ExpectQuery("show create database `db1`").
+		WillReturnRows(sqlmock.NewRows([]string{"Database", "Create Database"}).AddRow("db1", "create database `db1`"))
+	mock.ExpectQuery("select name, args, retType, body, language from mo_catalog.mo_user_defined_function where db = 'db1' order by name").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "args", "retType", "body", "language"}).
+			AddRow("twice", `{"x":"INT"}`, "INT", "select $1 * 2; -- it's doubled", "SQL"))
+	mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables").
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).AddRow("t1", "r").AddRow("v1", "v"))
+	mock.ExpectQuery("show create table `db1`.`t1`").
+		WillReturnRows(sqlmock.NewRows([]string{"Table", "Create"}).AddRow("t1", "create table t1 (a int)"))
+	mock.ExpectQuery("show create table `db1`.`v1`").
+		WillReturnRows(sqlmock.NewRows([]string{"View", "Create"}).AddRow("v1", "create view v1 as select twice(a) from t1"))
+	mock.ExpectQuery("select \\* from `db1`.`t1`").
+		WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("1"))
+	mock.ExpectQuery("select name, args, body from mo_catalog.mo_stored_procedure where db = 'db1'").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "args", "body"}).AddRow("p1", "{}", "begin select twice(1); end"))
+	require.NoError(t, opt.dumpData(ctx))
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	out := buf.String()
+	require.Contains(t, out, "DROP FUNCTION IF EXISTS `twice` (INT);\n"+
+		"CREATE FUNCTION `twice` (`x` INT) RETURNS INT LANGUAGE SQL AS 'select $1 * 2; -- it\\'s doubled';\n")
+	// the function comes before the view and the procedure calling it
+	fn := strings.Index(out, "CREATE FUNCTION")
+	require.Less(t, fn, strings.Index(out, "create view v1"))
+	require.Less(t, fn, strings.Index(out, "CREATE PROCEDURE `p1`"))
+}
@@ -0,0 +1,74 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/matrixorigin/matrixone/pkg/common/moerr"
+)
+
+const (
+	// rowCountEstimate takes the row count from the table metadata, which
+	// ignores where, time-column and other row filters
+	rowCountEstimate = "estimate"
+	// rowCountExact counts the rows which are dumped
+	rowCountExact = "exact"
+)
+
+func checkRowCountComments(ctx context.Context, mode string) error {
+	switch mode {
+	case "", rowCountEstimate, rowCountExact:
+		return nil
+	default:
+		return moerr.NewInvalidInput(ctx, "row-count-comments must be one of %s, %s, got %s", rowCountEstimate, rowCountExact, mode)
+	}
+}
+
+// showRowCount writes the row count of the table as a comment before its
+// data if row-count-comments is set
+func (opt *Options) showRowCount(ctx context.Context, queries []string, db, tbl string) error {
+	switch opt.rowCountComments {
+	case rowCountEstimate:
+		var n int64
+		err := session(ctx).QueryRowContext(ctx, "select mo_table_rows('"+escapeString(db)+"', '"+escapeString(tbl)+"')").Scan(&n)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(opt.stdout(), "/* table `%s`: about %d rows */\n", tbl, n)
+	case rowCountExact:
+		n, err := countRows(ctx, queries)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(opt.stdout(), "/* table `%s`: %d rows */\n", tbl, n)
+	}
+	return nil
+}
+
+// countRows sums the row counts of the select queries of a table
+func countRows(ctx context.Context, queries []string) (int64, error) {
+	var total int64
+	for _, q := range queries {
+		var n int64
+		err := session(ctx).QueryRowContext(ctx, "select count(*) from ("+q+") as t").Scan(&n)
+		if err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, nil
+}
@@ -0,0 +1,77 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckRowCountComments(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, checkRowCountComments(ctx, ""))
+	require.NoError(t, checkRowCountComments(ctx, rowCountEstimate))
+	require.NoError(t, checkRowCountComments(ctx, rowCountExact))
+	require.Error(t, checkRowCountComments(ctx, "approx"))
+}
+
+func TestGenOutputRowCount(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	bufPool := &sync.Pool{
+		New: func() any {
+			return &bytes.Buffer{}
+		},
+	}
+	opt := Options{netBufferLength: defaultNetBufferLength, rowCountComments: rowCountExact}
+	mock.ExpectQuery("select count\\(\\*\\) from \\(select \\* from `db1`.`t1` where a > 1\\) as t").
+		WillReturnRows(sqlmock.NewRows([]string{"count(*)"}).AddRow(2))
+	mock.ExpectQuery("select \\* from `db1`.`t1` where a > 1").
+		WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("2").AddRow("3"))
+	out := captureStdout(t, func() {
+		err = opt.genOutput(context.Background(), []string{"select * from `db1`.`t1` where a > 1"}, "db1", "t1", bufPool)
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Equal(t, "/* table `t1`: 2 rows */\nINSERT INTO `t1` VALUES (2),(3);\n", out)
+
+	opt.rowCountComments = rowCountEstimate
+	mock.ExpectQuery("select mo_table_rows\\('db1', 't1'\\)").
+		WillReturnRows(sqlmock.NewRows([]string{"rows"}).AddRow(5))
+	mock.ExpectQuery("select \\* from `db1`.`t1`").
+		WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("1"))
+	out = captureStdout(t, func() {
+		err = opt.genOutput(context.Background(), []string{"select * from `db1`.`t1`"}, "db1", "t1", bufPool)
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Equal(t, "/* table `t1`: about 5 rows */\nINSERT INTO `t1` VALUES (1);\n", out)
+
+	// exact counts add up the queries of a table
+	opt.rowCountComments = rowCountExact
+	mock.ExpectQuery("select count").WillReturnRows(sqlmock.NewRows([]string{"count(*)"}).AddRow(1))
+	mock.ExpectQuery("select count").WillReturnRows(sqlmock.NewRows([]string{"count(*)"}).AddRow(2))
+	n, err := countRows(context.Background(), []string{"q1", "q2"})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), n)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,53 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// completeInsert reports if the statements of the data name their columns,
+// as asked by -complete-insert. Positional values are wrong as soon as the
+// columns are in another order or some of them are missing.
+func (opt *Options) completeInsert() bool {
+	return opt.completeInserts || opt.safeColumns || opt.excludeColumns != nil
+}
+
+// checkColumnOrder warns if the columns of the result of the table are not
+// in the order of its definition. The dump is still right as the columns are
+// named, but the difference points at a driver or server quirk.
+func checkColumnOrder(db, tbl string, defined []string, cols []*Column) {
+	got := make(map[string]bool, len(cols))
+	for _, col := range cols {
+		got[col.Name] = true
+	}
+	// the excluded columns are not in the result
+	want := make([]string, 0, len(defined))
+	for _, name := range defined {
+		if got[name] {
+			want = append(want, name)
+		}
+	}
+	names := make([]string, 0, len(cols))
+	for _, col := range cols {
+		names = append(names, col.Name)
+	}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		fmt.Fprintf(os.Stderr, "columns of table `%s`.`%s` are read in the order (%s) which differs from the definition (%s)\n",
+			db, tbl, strings.Join(names, ","), strings.Join(defined, ","))
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenOutputSafeColumns(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	opt := Options{
+		netBufferLength: defaultNetBufferLength,
+		format:          formatSQL,
+		safeColumns:     true,
+	}
+	bufPool := &sync.Pool{New: func() any { return &bytes.Buffer{} }}
+
+	// select * returns the columns in another order than the DDL
+	mock.ExpectQuery("att_relname = 't1'").
+		WillReturnRows(sqlmock.NewRows([]string{"attname"}).AddRow("id").AddRow("name"))
+	mock.ExpectQuery("select \\* from `db1`.`t1`").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "id"}).AddRow("a", "1"))
+	var out string
+	warn := captureStderr(t, func() {
+		out = captureStdout(t, func() {
+			err = opt.genOutput(context.Background(), []string{"select * from `db1`.`t1`"}, "db1", "t1", bufPool)
+		})
+	})
+	require.NoError(t, err)
+	require.Equal(t, "INSERT INTO `t1` (`name`,`id`) VALUES ('a',1);\n", out)
+	require.Equal(t, "columns of table `db1`.`t1` are read in the order (name,id) which differs from the definition (id,name)\n", warn)
+
+	// the same order is silent
+	mock.ExpectQuery("att_relname = 't1'").
+		WillReturnRows(sqlmock.NewRows([]string{"attname"}).AddRow("id").AddRow("name"))
+	mock.ExpectQuery("select \\* from `db1`.`t1`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow("1", "a"))
+	warn = captureStderr(t, func() {
+		out = captureStdout(t, func() {
+			err = opt.genOutput(context.Background(), []string{"select * from `db1`.`t1`"}, "db1", "t1", bufPool)
+		})
+	})
+	require.NoError(t, err)
+	require.Equal(t, "INSERT INTO `t1` (`id`,`name`) VALUES (1,'a');\n", out)
+	require.Empty(t, warn)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGenOutputCompleteInsert(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	// the small buffer splits the rows into two statements
+	opt := Options{
+		netBufferLength: 16,
+		format:          formatSQL,
+		completeInserts: true,
+	}
+	bufPool := &sync.Pool{New: func() any { return &bytes.Buffer{} }}
+	mock.ExpectQuery("select \\* from `db1`.`t1`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow("1", "a").AddRow("2", "b").AddRow("3", "c"))
+	out := captureStdout(t, func() {
+		err = opt.genOutput(context.Background(), []string{"select * from `db1`.`t1`"}, "db1", "t1", bufPool)
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Equal(t, "INSERT INTO `t1` (`id`,`name`) VALUES (1,'a');\n"+
+		"INSERT INTO `t1` (`id`,`name`) VALUES (2,'b');\n"+
+		"INSERT INTO `t1` (`id`,`name`) VALUES (3,'c');\n", out)
+}
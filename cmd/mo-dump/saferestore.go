@@ -0,0 +1,26 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import "regexp"
+
+var createDatabase = regexp.MustCompile(`(?i)^\s*CREATE\s+DATABASE\s+(IF\s+NOT\s+EXISTS\s+)?`)
+
+// createDBIfNotExists makes CREATE DATABASE keep an existing database, for
+// -safe-restore which drops the dumped tables one by one instead of the
+// whole database
+func createDBIfNotExists(ddl string) string {
+	return createDatabase.ReplaceAllLiteralString(ddl, "CREATE DATABASE IF NOT EXISTS ")
+}
@@ -0,0 +1,71 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateDBIfNotExists(t *testing.T) {
+	require.Equal(t, "CREATE DATABASE IF NOT EXISTS `db1`", createDBIfNotExists("CREATE DATABASE `db1`"))
+	require.Equal(t, "CREATE DATABASE IF NOT EXISTS `db1` DEFAULT CHARACTER SET utf8mb4", createDBIfNotExists("create  database if not exists `db1` DEFAULT CHARACTER SET utf8mb4"))
+	// only the head is rewritten
+	require.Equal(t, "CREATE DATABASE IF NOT EXISTS `create database x`", createDBIfNotExists("CREATE DATABASE `create database x`"))
+}
+
+func TestDumpDataSafeRestore(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	ctx := context.Background()
+	opt := Options{
+		dbs:             []string{"db1"},
+		emptyTables:     true,
+		safeRestore:     true,
+		netBufferLength: defaultNetBufferLength,
+		format:          formatSQL,
+		consistency:     consistencyNone,
+	}
+	mock.ExpectQuery("show create database `db1`").
+		WillReturnRows(sqlmock.NewRows([]string{"Database", "Create Database"}).AddRow("db1", "CREATE DATABASE `db1`"))
+	mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables").
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).
+			AddRow("t1", "r").
+			AddRow("v1", "v"))
+	mock.ExpectQuery("show create table").
+		WillReturnRows(sqlmock.NewRows([]string{"Table", "Create"}).AddRow("t1", "create table t1 (a int)"))
+	mock.ExpectQuery("show create table").
+		WillReturnRows(sqlmock.NewRows([]string{"View", "Create"}).AddRow("v1", "create view v1 as select * from t1"))
+	mock.ExpectQuery("select \\* from `db1`.`t1`").
+		WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("1"))
+	out := captureStdout(t, func() {
+		err = opt.dumpData(ctx)
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.NotContains(t, out, "DROP DATABASE")
+	require.Equal(t, "CREATE DATABASE IF NOT EXISTS `db1` ;\n"+
+		"USE `db1`;\n\n\n"+
+		"DROP TABLE IF EXISTS `t1`;\n"+
+		"create table t1 (a int);\n"+
+		"INSERT INTO `t1` VALUES (1);\n\n\n\n"+
+		"DROP VIEW IF EXISTS `v1`;\n"+
+		"create view v1 as select * from t1;\n\n\n", out)
+}
@@ -0,0 +1,80 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/matrixorigin/matrixone/pkg/catalog"
+	"github.com/matrixorigin/matrixone/pkg/common/moerr"
+)
+
+// schemaHash hashes the DDL of the tables and views of the databases in
+// name order. AUTO_INCREMENT counters change with the data and are left
+// out.
+func (opt *Options) schemaHash(ctx context.Context) (string, error) {
+	h := sha256.New()
+	for _, db := range opt.dbs {
+		tables, err := getTables(ctx, db, append(Tables(nil), opt.tables...), opt.ignoreTables, opt.skipMissingTables, opt.allowEmptyMatch, opt.includeTemporary)
+		if err != nil {
+			return "", err
+		}
+		sort.Slice(tables, func(i, j int) bool {
+			return tables[i].Name < tables[j].Name
+		})
+		for _, tbl := range tables {
+			switch tbl.Kind {
+			case catalog.SystemOrdinaryRel, catalog.SystemExternalRel, catalog.SystemViewRel:
+			default:
+				continue
+			}
+			create, err := getCreateTable(ctx, db, tbl.Name)
+			if err != nil {
+				return "", err
+			}
+			create = autoIncrement.ReplaceAllString(create, "")
+			fmt.Fprintf(h, "%s.%s\n%s\n", db, tbl.Name, create)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// checkSchemaHash records the schema hash in the file on the first run. On
+// later runs the dump fails if the schema differs from the recorded one.
+func (opt *Options) checkSchemaHash(ctx context.Context) error {
+	sum, err := opt.schemaHash(ctx)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(opt.schemaHashFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return os.WriteFile(opt.schemaHashFile, []byte(sum+"\n"), 0644)
+	}
+	if err != nil {
+		return err
+	}
+	if recorded := strings.TrimSpace(string(data)); recorded != sum {
+		return moerr.NewInvalidInput(ctx, "schema changed since the hash in %s was recorded (%s, now %s), remove the file to dump the new schema", opt.schemaHashFile, recorded, sum)
+	}
+	return nil
+}
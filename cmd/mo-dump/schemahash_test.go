@@ -0,0 +1,66 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckSchemaHash(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	ctx := context.Background()
+	opt := Options{dbs: []string{"db1"}, schemaHashFile: filepath.Join(t.TempDir(), "schema.sha256")}
+	expectSchema := func(createT1 string) {
+		// the catalog order does not matter, index tables are left out
+		mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables").
+			WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).
+				AddRow("v1", "v").
+				AddRow("idx", "i").
+				AddRow("t1", "r"))
+		mock.ExpectQuery("show create table `db1`.`t1`").
+			WillReturnRows(sqlmock.NewRows([]string{"Table", "Create Table"}).AddRow("t1", createT1))
+		mock.ExpectQuery("show create table `db1`.`v1`").
+			WillReturnRows(sqlmock.NewRows([]string{"View", "Create View"}).AddRow("v1", "create view v1 as select a from t1"))
+	}
+
+	// the first run records the hash
+	expectSchema("create table t1 (a int) AUTO_INCREMENT=5")
+	require.NoError(t, opt.checkSchemaHash(ctx))
+	recorded, err := os.ReadFile(opt.schemaHashFile)
+	require.NoError(t, err)
+	require.Len(t, recorded, 65)
+
+	// the same schema with more rows passes
+	expectSchema("create table t1 (a int) AUTO_INCREMENT=9")
+	require.NoError(t, opt.checkSchemaHash(ctx))
+
+	// a changed table is detected and the hash is kept
+	expectSchema("create table t1 (a int, b int)")
+	err = opt.checkSchemaHash(ctx)
+	require.ErrorContains(t, err, "schema changed since the hash in "+opt.schemaHashFile+" was recorded")
+	data, err := os.ReadFile(opt.schemaHashFile)
+	require.NoError(t, err)
+	require.Equal(t, recorded, data)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,71 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"sort"
+
+	"github.com/matrixorigin/matrixone/pkg/common/moerr"
+)
+
+// withData reports if the data of the table is dumped. The tables of
+// -data-tables are dumped with data and those of -schema-only-tables
+// without, the others follow -no-data.
+func (opt *Options) withData(db, tbl string) bool {
+	switch {
+	case opt.dataTables.has(db, tbl):
+		return true
+	case opt.schemaOnlyTables.has(db, tbl):
+		return false
+	}
+	return !opt.noData
+}
+
+// checkWithData fails if the table is named by both -data-tables and
+// -schema-only-tables, such as db.t1 by one and t1 by the other
+func (opt *Options) checkWithData(ctx context.Context, db, tbl string) error {
+	if opt.dataTables.has(db, tbl) && opt.schemaOnlyTables.has(db, tbl) {
+		return moerr.NewInvalidInput(ctx, "table `%s`.`%s` is named by both data-tables and schema-only-tables", db, tbl)
+	}
+	return nil
+}
+
+// dataTablesOf returns the tables whose data is dumped
+func (opt *Options) dataTablesOf(db string, tables Tables) Tables {
+	var ret Tables
+	for _, tbl := range tables {
+		if opt.withData(db, tbl.Name) {
+			ret = append(ret, tbl)
+		}
+	}
+	return ret
+}
+
+// checkDataTables fails if a name is given to both -data-tables and
+// -schema-only-tables
+func checkDataTables(ctx context.Context, data, schemaOnly ignoreTables) error {
+	var both []string
+	for name := range data {
+		if schemaOnly[name] {
+			both = append(both, name)
+		}
+	}
+	if len(both) > 0 {
+		sort.Strings(both)
+		return moerr.NewInvalidInput(ctx, "table %s is named by both data-tables and schema-only-tables", both[0])
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithData(t *testing.T) {
+	ctx := context.Background()
+	opt := Options{}
+	require.NoError(t, opt.dataTables.Set("db1.t1,t2"))
+	require.NoError(t, opt.schemaOnlyTables.Set("t3,db2.t1"))
+	require.NoError(t, checkDataTables(ctx, opt.dataTables, opt.schemaOnlyTables))
+
+	require.True(t, opt.withData("db1", "t1"))
+	require.True(t, opt.withData("db2", "t2"))
+	require.False(t, opt.withData("db1", "t3"))
+	require.False(t, opt.withData("db2", "t1"))
+	require.True(t, opt.withData("db1", "t4"))
+	opt.noData = true
+	require.False(t, opt.withData("db1", "t4"))
+	require.True(t, opt.withData("db1", "t2"))
+	require.Equal(t, Tables{{"t1", "r"}, {"t2", "r"}}, opt.dataTablesOf("db1", Tables{{"t1", "r"}, {"t2", "r"}, {"t3", "r"}, {"t4", "r"}}))
+
+	// db2.t1 is named by one list as t1 of db2 and by the other as t1
+	require.NoError(t, opt.checkWithData(ctx, "db2", "t1"))
+	require.NoError(t, opt.dataTables.Set("t1"))
+	require.Error(t, opt.checkWithData(ctx, "db2", "t1"))
+	require.NoError(t, opt.checkWithData(ctx, "db1", "t2"))
+
+	require.NoError(t, opt.schemaOnlyTables.Set("t2"))
+	require.Error(t, checkDataTables(ctx, opt.dataTables, opt.schemaOnlyTables))
+}
+
+func TestDumpDataSchemaOnlyTables(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	ctx := context.Background()
+	opt := Options{
+		dbs:             []string{"db1"},
+		netBufferLength: defaultNetBufferLength,
+		format:          formatSQL,
+		consistency:     consistencyNone,
+		noData:          true,
+		dataTables:      ignoreTables{"t2": true},
+	}
+	mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables").
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).AddRow("t1", "r").AddRow("t2", "r"))
+	for _, tbl := range []string{"t1", "t2"} {
+		mock.ExpectQuery("show create table").
+			WillReturnRows(sqlmock.NewRows([]string{"Table", "Create Table"}).AddRow(tbl, "CREATE TABLE `"+tbl+"` (`a` INT)"))
+	}
+	mock.ExpectQuery("select \\* from `db1`.`t2`").
+		WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("2"))
+	out := captureStdout(t, func() {
+		err = opt.dumpData(ctx)
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Equal(t, "DROP TABLE IF EXISTS `t1`;\nCREATE TABLE `t1` (`a` INT);\n"+
+		"DROP TABLE IF EXISTS `t2`;\nCREATE TABLE `t2` (`a` INT);\nINSERT INTO `t2` VALUES (2);\n\n\n\n", out)
+
+	// a reload leaves the tables without data alone
+	opt = Options{
+		dbs:              []string{"db1"},
+		netBufferLength:  defaultNetBufferLength,
+		format:           formatSQL,
+		consistency:      consistencyNone,
+		truncate:         true,
+		schemaOnlyTables: ignoreTables{"db1.t1": true},
+	}
+	mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables").
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).AddRow("t1", "r").AddRow("t2", "r"))
+	for _, tbl := range []string{"t1", "t2"} {
+		mock.ExpectQuery("show create table").
+			WillReturnRows(sqlmock.NewRows([]string{"Table", "Create Table"}).AddRow(tbl, "CREATE TABLE `"+tbl+"` (`a` INT)"))
+	}
+	mock.ExpectQuery("select \\* from `db1`.`t2`").
+		WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("2"))
+	out = captureStdout(t, func() {
+		err = opt.dumpData(ctx)
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Equal(t, "TRUNCATE TABLE `t2`;\nINSERT INTO `t2` VALUES (2);\n\n\n\n", out)
+}
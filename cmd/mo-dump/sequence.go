@@ -0,0 +1,117 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/matrixorigin/matrixone/pkg/catalog"
+	"github.com/matrixorigin/matrixone/pkg/common/moerr"
+)
+
+// sequenceNames returns the sequences among the tables of a database
+func sequenceNames(tables Tables) []string {
+	var names []string
+	for _, tbl := range tables {
+		if tbl.Kind == catalog.SystemSequenceRel {
+			names = append(names, tbl.Name)
+		}
+	}
+	return names
+}
+
+// showCreateSequences writes the DDL of each sequence, read from the
+// sequence table itself. It comes before the tables of the database, whose
+// defaults may call nextval of the sequences.
+func showCreateSequences(ctx context.Context, out io.Writer, db string, sequences []string) error {
+	for _, seq := range sequences {
+		create, err := getCreateSequence(ctx, db, seq)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "DROP SEQUENCE IF EXISTS %s;\n", quoteIdent(seq))
+		fmt.Fprintf(out, "%s;\n\n\n", create)
+	}
+	return nil
+}
+
+func getCreateSequence(ctx context.Context, db, seq string) (string, error) {
+	qctx, cancel := queryContext(ctx)
+	defer cancel()
+	what := "read sequence " + quoteIdent(db) + "." + quoteIdent(seq)
+	r, err := connRetry.query(qctx, what, "select min_value, max_value, start_value, increment_value, cycle from "+quoteIdent(db)+"."+quoteIdent(seq))
+	if err != nil {
+		return "", queryTimeoutError(ctx, qctx, err, what)
+	}
+	defer r.Close()
+	colTypes, err := r.ColumnTypes()
+	if err != nil {
+		return "", err
+	}
+	if !r.Next() {
+		if err = r.Err(); err != nil {
+			return "", queryTimeoutError(ctx, qctx, err, what)
+		}
+		return "", moerr.NewInternalError(ctx, "sequence `%s`.`%s` has no row", db, seq)
+	}
+	var (
+		minValue, maxValue, start, increment string
+		cycle                                bool
+	)
+	err = r.Scan(&minValue, &maxValue, &start, &increment, &cycle)
+	if err != nil {
+		return "", err
+	}
+	create := "CREATE SEQUENCE " + quoteIdent(seq)
+	// the columns of the values have the type of the sequence
+	if typ := strings.ToUpper(colTypes[0].DatabaseTypeName()); typ != "" {
+		if t, ok := strings.CutPrefix(typ, "UNSIGNED "); ok {
+			typ = t + " UNSIGNED"
+		}
+		create += " AS " + typ
+	}
+	create += fmt.Sprintf(" INCREMENT BY %s MINVALUE %s MAXVALUE %s START WITH %s", increment, minValue, maxValue, start)
+	if cycle {
+		create += " CYCLE"
+	} else {
+		create += " NO CYCLE"
+	}
+	return create, queryTimeoutError(ctx, qctx, r.Err(), what)
+}
+
+// showSequenceValues writes a setval for each sequence, which restores the
+// last value given out by the sequence. It follows the data of the database
+// so that the loaded rows do not consume the sequence.
+func showSequenceValues(ctx context.Context, out io.Writer, db string, sequences []string) error {
+	for _, seq := range sequences {
+		var (
+			last     string
+			isCalled bool
+		)
+		query := "select last_seq_num, is_called from " + quoteIdent(db) + "." + quoteIdent(seq)
+		err := connRetry.queryRow(ctx, query, query, &last, &isCalled)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "select setval('%s', '%s', %t);\n", escapeString(seq), last, isCalled)
+	}
+	if len(sequences) > 0 {
+		fmt.Fprintf(out, "\n\n")
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSequenceNames(t *testing.T) {
+	require.Nil(t, sequenceNames(Tables{{"t1", "r"}, {"v1", "v"}}))
+	require.Equal(t, []string{"s1", "s2"}, sequenceNames(Tables{{"s1", "S"}, {"t1", "r"}, {"s2", "S"}}))
+}
+
+func TestDumpDataSequences(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	ctx := context.Background()
+	opt := Options{
+		dbs:             []string{"db1"},
+		netBufferLength: defaultNetBufferLength,
+		format:          formatSQL,
+		consistency:     consistencyNone,
+		sequences:       true,
+	}
+	// the primary key of t1 is given out by s1
+	mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables").
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).
+			AddRow("s1", "S").
+			AddRow("t1", "r"))
+	mock.ExpectQuery("select min_value, max_value, start_value, increment_value, cycle from `db1`.`s1`").
+		WillReturnRows(mock.NewRowsWithColumnDefinition(
+			mock.NewColumn("min_value").OfType("BIGINT", int64(0)),
+			mock.NewColumn("max_value").OfType("BIGINT", int64(0)),
+			mock.NewColumn("start_value").OfType("BIGINT", int64(0)),
+			mock.NewColumn("increment_value").OfType("BIGINT", int64(0)),
+			mock.NewColumn("cycle").OfType("BOOL", false)).
+			AddRow("1", "9223372036854775807", "1", "1", false))
+	mock.ExpectQuery("show create table").
+		WillReturnRows(sqlmock.NewRows([]string{"Table", "Create"}).AddRow("t1", "create table t1 (id int primary key default nextval('s1'))"))
+	mock.ExpectQuery("select \\* from `db1`.`t1`").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("1").AddRow("2").AddRow("3"))
+	mock.ExpectQuery("select last_seq_num, is_called from `db1`.`s1`").
+		WillReturnRows(sqlmock.NewRows([]string{"last_seq_num", "is_called"}).AddRow("3", true))
+	var out string
+	stderr := captureStderr(t, func() {
+		out = captureStdout(t, func() {
+			err = opt.dumpData(ctx)
+		})
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.NotContains(t, stderr, "skip table `db1`.`s1`")
+	require.Contains(t, out, "INSERT INTO `t1` VALUES (1),(2),(3);\n\n\n\nselect setval('s1', '3', true);\n")
+	// the sequence is created before the table using it
+	create := "DROP SEQUENCE IF EXISTS `s1`;\nCREATE SEQUENCE `s1` AS BIGINT INCREMENT BY 1 MINVALUE 1 MAXVALUE 9223372036854775807 START WITH 1 NO CYCLE;\n"
+	require.Contains(t, out, create)
+	require.Less(t, strings.Index(out, create), strings.Index(out, "create table t1"))
+
+	// without the option the sequence is skipped
+	opt = Options{
+		dbs:             []string{"db1"},
+		netBufferLength: defaultNetBufferLength,
+		format:          formatSQL,
+		consistency:     consistencyNone,
+	}
+	mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables").
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).AddRow("s1", "S"))
+	stderr = captureStderr(t, func() {
+		out = captureStdout(t, func() {
+			err = opt.dumpData(ctx)
+		})
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Contains(t, stderr, "skip table `db1`.`s1` of kind S")
+	require.NotContains(t, out, "setval")
+}
+
+func TestGetCreateSequence(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	ctx := context.Background()
+	mock.ExpectQuery("select min_value, max_value, start_value, increment_value, cycle from `db1`.`s1`").
+		WillReturnRows(mock.NewRowsWithColumnDefinition(
+			mock.NewColumn("min_value").OfType("UNSIGNED INT", int64(0)),
+			mock.NewColumn("max_value").OfType("UNSIGNED INT", int64(0)),
+			mock.NewColumn("start_value").OfType("UNSIGNED INT", int64(0)),
+			mock.NewColumn("increment_value").OfType("BIGINT", int64(0)),
+			mock.NewColumn("cycle").OfType("BOOL", false)).
+			AddRow("10", "100", "100", "-5", true))
+	create, err := getCreateSequence(ctx, "db1", "s1")
+	require.NoError(t, err)
+	require.Equal(t, "CREATE SEQUENCE `s1` AS INT UNSIGNED INCREMENT BY -5 MINVALUE 10 MAXVALUE 100 START WITH 100 CYCLE", create)
+
+	mock.ExpectQuery("select min_value").
+		WillReturnRows(sqlmock.NewRows([]string{"min_value", "max_value", "start_value", "increment_value", "cycle"}))
+	_, err = getCreateSequence(ctx, "db1", "s1")
+	require.Error(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,154 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/matrixorigin/matrixone/pkg/common/moerr"
+)
+
+const (
+	signaturePrefix = "/* MODUMP SHA256 "
+	signatureSuffix = " */\n"
+	sidecarSuffix   = ".sha256"
+)
+
+// signer hashes everything written to a SQL file so that -sign-files can
+// end the file with the SHA-256 of its content
+type signer struct {
+	w io.Writer
+	h hash.Hash
+}
+
+func newSigner(w io.Writer) *signer {
+	return &signer{w: w, h: sha256.New()}
+}
+
+func (s *signer) Write(p []byte) (int, error) {
+	n, err := s.w.Write(p)
+	s.h.Write(p[:n])
+	return n, err
+}
+
+// sign writes the footer. Nothing may be written after it.
+func (s *signer) sign() error {
+	_, err := io.WriteString(s.w, signature(s.h.Sum(nil)))
+	return err
+}
+
+func signature(sum []byte) string {
+	return signaturePrefix + hex.EncodeToString(sum) + signatureSuffix
+}
+
+// signFile appends the footer to a complete SQL file
+func signFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(f, signature(sum[:]))
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+// writeSidecar writes the SHA-256 of a complete data file to <path>.sha256
+// in the format of sha256sum, as a footer would not be valid csv or gzip
+func writeSidecar(path string) error {
+	sum, err := fileSum(path)
+	if err != nil {
+		return err
+	}
+	line := fmt.Sprintf("%s  %s\n", sum, filepath.Base(path))
+	return os.WriteFile(path+sidecarSuffix, []byte(line), 0644)
+}
+
+func fileSum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// verifyFile checks a file written with -sign-files against its sidecar if
+// it has one, otherwise against its footer
+func verifyFile(path string) error {
+	sidecar, err := os.ReadFile(path + sidecarSuffix)
+	if err == nil {
+		fields := strings.Fields(string(sidecar))
+		if len(fields) == 0 {
+			return moerr.NewInternalErrorNoCtx("empty checksum file %s", path+sidecarSuffix)
+		}
+		sum, err := fileSum(path)
+		if err != nil {
+			return err
+		}
+		return compareSum(path, fields[0], sum)
+	}
+	if !os.IsNotExist(err) {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	i := bytes.LastIndex(data, []byte(signaturePrefix))
+	if i < 0 || (i > 0 && data[i-1] != '\n') || !bytes.HasSuffix(data, []byte(signatureSuffix)) {
+		return moerr.NewInternalErrorNoCtx("file %s is not signed", path)
+	}
+	signed := string(data[i+len(signaturePrefix) : len(data)-len(signatureSuffix)])
+	sum := sha256.Sum256(data[:i])
+	return compareSum(path, signed, hex.EncodeToString(sum[:]))
+}
+
+func compareSum(path, signed, computed string) error {
+	if !strings.EqualFold(signed, computed) {
+		return moerr.NewInternalErrorNoCtx("checksum mismatch of %s: signed %s, computed %s", path, signed, computed)
+	}
+	return nil
+}
+
+// dataFileDone signs a complete data file and hands it to the post-file
+// command
+func (opt *Options) dataFileDone(fname string) error {
+	if opt.signFiles {
+		if err := writeSidecar(fname); err != nil {
+			return err
+		}
+	}
+	opt.fileHook.run(fname)
+	return nil
+}
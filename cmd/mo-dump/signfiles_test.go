@@ -0,0 +1,73 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dump.sql")
+	body := "CREATE TABLE `t` (`a` INT);\n\n\nINSERT INTO `t` VALUES (1);\n"
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	s := newSigner(f)
+	_, err = s.Write([]byte(body))
+	require.NoError(t, err)
+	require.NoError(t, s.sign())
+	require.NoError(t, f.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(data), body))
+	require.True(t, strings.HasPrefix(string(data[len(body):]), "/* MODUMP SHA256 "))
+	require.NoError(t, verifyFile(path))
+
+	// a corrupted body no longer matches the footer
+	corrupted := strings.Replace(string(data), "VALUES (1)", "VALUES (2)", 1)
+	require.NoError(t, os.WriteFile(path, []byte(corrupted), 0644))
+	err = verifyFile(path)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "checksum mismatch")
+
+	require.NoError(t, os.WriteFile(path, []byte(body), 0644))
+	err = verifyFile(path)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "not signed")
+}
+
+func TestSignFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "load.sql")
+	require.NoError(t, os.WriteFile(path, []byte("SET FOREIGN_KEY_CHECKS = 0;\n"), 0644))
+	require.NoError(t, signFile(path))
+	require.NoError(t, verifyFile(path))
+}
+
+func TestSidecar(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db_t.csv")
+	require.NoError(t, os.WriteFile(path, []byte("1,\"a\"\n2,\"b\"\n"), 0644))
+	require.NoError(t, writeSidecar(path))
+	sidecar, err := os.ReadFile(path + ".sha256")
+	require.NoError(t, err)
+	require.True(t, strings.HasSuffix(string(sidecar), "  db_t.csv\n"))
+	require.NoError(t, verifyFile(path))
+
+	require.NoError(t, os.WriteFile(path, []byte("1,\"a\"\n2,\"c\"\n"), 0644))
+	require.Error(t, verifyFile(path))
+}
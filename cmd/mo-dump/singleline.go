@@ -0,0 +1,256 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+)
+
+// lineWriter rewrites the dump so that every statement takes exactly one
+// line, for consumers reading the dump line by line. A line ends after a
+// statement ending with the delimiter, ';' unless a DELIMITER line changes
+// it, or after a comment on its own line. Other line
+// breaks and runs of blanks become one space, blank lines and indentation
+// are dropped, and line breaks in quoted strings are escaped as \n and \r.
+// Line comments, -- and #, are rewritten as /* */ comments, as they would
+// comment out the rest of the joined line.
+type lineWriter struct {
+	w   io.Writer
+	buf []byte
+
+	quote   byte // the quote of the string being written, or 0
+	escaped bool // the last byte in the string is an unescaped backslash
+	comment bool // in a /* */ comment
+	// in a -- or # comment, written as a /* */ comment up to the line break
+	lineComment bool
+	// the line ends with --, which starts a comment if a blank follows
+	dashes bool
+	// trailing dashes held back from the writer until it is known if they
+	// start a comment
+	held int
+	// the comment started the line, so the line may end with it
+	commentLine bool
+	// a statement or a comment line ended, so a line break ends the line
+	ended bool
+	// nothing but blanks were written since the last line break
+	lineStart bool
+	// blanks or a replaced line break wait for the next byte of the line
+	space   bool
+	lineLen int
+	prev    byte
+	// the statement delimiter, the bytes of it the line ends with and the
+	// first bytes of the line, which tell a DELIMITER line
+	delim   string
+	matched int
+	head    []byte
+}
+
+// delimiterCommand is the mysql client command changing the delimiter
+const delimiterCommand = "DELIMITER "
+
+func newLineWriter(w io.Writer) *lineWriter {
+	return &lineWriter{w: w, lineStart: true, delim: ";"}
+}
+
+func (l *lineWriter) Write(p []byte) (int, error) {
+	l.buf = l.buf[:0]
+	for i := 0; i < l.held; i++ {
+		l.buf = append(l.buf, '-')
+	}
+	for _, c := range p {
+		switch {
+		case l.quote != 0:
+			l.writeQuoted(c)
+		case l.comment:
+			l.writeComment(c)
+		case l.lineComment:
+			l.writeLineComment(c)
+		case l.dashes:
+			l.dashes = false
+			if c == ' ' || c == '\t' || c == '\n' || c == '\r' {
+				l.buf = l.buf[:len(l.buf)-2]
+				l.startLineComment(l.lineLen == 2)
+				l.writeLineComment(c)
+				continue
+			}
+			l.writePlain(c)
+		default:
+			l.writePlain(c)
+		}
+	}
+	l.held = 0
+	if l.quote == 0 && !l.comment && !l.lineComment {
+		for l.held < 2 && l.held < len(l.buf) && l.buf[len(l.buf)-1-l.held] == '-' {
+			l.held++
+		}
+	}
+	if _, err := l.w.Write(l.buf[:len(l.buf)-l.held]); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (l *lineWriter) writeQuoted(c byte) {
+	switch {
+	case l.escaped:
+		// an escaped line break is still a line break
+		l.escaped = false
+		switch c {
+		case '\n':
+			c = 'n'
+		case '\r':
+			c = 'r'
+		}
+		l.buf = append(l.buf, c)
+	case c == '\\' && l.quote != '`':
+		l.escaped = true
+		l.buf = append(l.buf, c)
+	case c == l.quote:
+		l.quote = 0
+		l.buf = append(l.buf, c)
+	case c == '\n' && l.quote != '`':
+		l.buf = append(l.buf, '\\', 'n')
+	case c == '\r' && l.quote != '`':
+		l.buf = append(l.buf, '\\', 'r')
+	default:
+		l.buf = append(l.buf, c)
+	}
+}
+
+func (l *lineWriter) writeComment(c byte) {
+	if c == '/' && l.prev == '*' {
+		l.comment = false
+		l.ended = l.commentLine
+		l.prev = 0
+		l.buf = append(l.buf, c)
+		return
+	}
+	l.prev = c
+	if c == '\n' || c == '\r' {
+		c = ' '
+	}
+	l.buf = append(l.buf, c)
+}
+
+// startLineComment opens the /* */ comment replacing a line comment
+func (l *lineWriter) startLineComment(lineStart bool) {
+	l.lineComment = true
+	l.commentLine = lineStart
+	l.prev = 0
+	l.buf = append(l.buf, '/', '*')
+}
+
+func (l *lineWriter) writeLineComment(c byte) {
+	switch c {
+	case '\n':
+		// the line break is written as usual after the comment is closed
+		l.lineComment = false
+		l.buf = append(l.buf, ' ', '*', '/')
+		l.ended, l.matched, l.prev = l.commentLine, 0, 0
+		l.writePlain(c)
+		return
+	case '\r':
+		return
+	case '/':
+		// the comment must not end before the line does
+		if l.prev == '*' {
+			l.buf = append(l.buf, ' ')
+		}
+	}
+	l.prev = c
+	l.buf = append(l.buf, c)
+}
+
+func (l *lineWriter) writePlain(c byte) {
+	switch c {
+	case '\n':
+		if h := string(l.head); len(h) > len(delimiterCommand) && strings.EqualFold(h[:len(delimiterCommand)], delimiterCommand) {
+			l.delim, l.ended = strings.TrimSpace(h[len(delimiterCommand):]), true
+		}
+		if l.ended {
+			l.buf = append(l.buf, '\n')
+			l.lineStart, l.ended, l.space, l.lineLen = true, false, false, 0
+			l.matched, l.head = 0, l.head[:0]
+		} else if !l.lineStart {
+			l.space = true
+		}
+		l.prev = 0
+		return
+	case ' ', '\t', '\r':
+		if !l.lineStart {
+			l.space = true
+		}
+		l.prev = 0
+		return
+	}
+	if l.space {
+		l.buf = append(l.buf, ' ')
+		l.head = l.appendHead(' ')
+		l.space = false
+	}
+	l.head = l.appendHead(c)
+	l.lineStart = false
+	l.lineLen++
+	switch {
+	case l.matched < len(l.delim) && c == l.delim[l.matched]:
+		l.matched++
+	case c == l.delim[0]:
+		l.matched = 1
+	default:
+		l.matched = 0
+	}
+	l.ended = l.matched == len(l.delim)
+	switch {
+	case c == '#':
+		l.startLineComment(l.lineLen == 1)
+		return
+	case c == '-' && l.prev == '-':
+		l.dashes = true
+	case c == '\'' || c == '"' || c == '`':
+		l.quote = c
+	case c == '*' && l.prev == '/':
+		l.comment = true
+		l.commentLine = l.lineLen == 2
+		l.buf = append(l.buf, c)
+		l.prev = 0
+		return
+	}
+	l.prev = c
+	l.buf = append(l.buf, c)
+}
+
+func (l *lineWriter) appendHead(c byte) []byte {
+	if len(l.head) < 2*len(delimiterCommand) {
+		return append(l.head, c)
+	}
+	return l.head
+}
+
+// unwrapLines returns the writer under the single-line rewriting, if any
+func unwrapLines(w io.Writer) io.Writer {
+	if l, ok := w.(*lineWriter); ok {
+		return l.w
+	}
+	return w
+}
+
+// toStdout reports if the dump is written to the standard output, whether
+// it is rewritten to single lines or not
+func (opt *Options) toStdout() bool {
+	out := unwrapLines(opt.out)
+	return out == nil || out == io.Writer(os.Stdout)
+}
@@ -0,0 +1,113 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/matrixorigin/matrixone/pkg/sql/parsers/dialect/mysql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLineWriter(t *testing.T) {
+	var buf bytes.Buffer
+	l := newLineWriter(&buf)
+	for _, s := range []string{
+		"/* MODUMP\nPOSITION: 1 */\n\n",
+		"CREATE TABLE `t1` (\n  `a` int DEFAULT NULL COMMENT 'x\ny',\n  `b` varchar(10)\n);\n\n\n",
+		// a write may end anywhere, even in an escape
+		"INSERT INTO `t1` VALUES (1,'it\\'s\n", "a\\", "\n\rb'),(2,\"c\nd\");\n",
+		"/*!EXTERNAL TABLE `e`*/\nDROP TABLE IF EXISTS `e`;\n",
+	} {
+		n, err := l.Write([]byte(s))
+		require.NoError(t, err)
+		require.Equal(t, len(s), n)
+	}
+	require.Equal(t, ""+
+		"/* MODUMP POSITION: 1 */\n"+
+		"CREATE TABLE `t1` ( `a` int DEFAULT NULL COMMENT 'x\\ny', `b` varchar(10) );\n"+
+		"INSERT INTO `t1` VALUES (1,'it\\'s\\na\\n\\rb'),(2,\"c\\nd\");\n"+
+		"/*!EXTERNAL TABLE `e`*/\n"+
+		"DROP TABLE IF EXISTS `e`;\n", buf.String())
+}
+
+func TestLineWriterLineComments(t *testing.T) {
+	var buf bytes.Buffer
+	l := newLineWriter(&buf)
+	for _, s := range []string{
+		"-- dump of db1\n",
+		"DELIMITER ;;\n",
+		"CREATE PROCEDURE `p` () begin -- note */ here\n  select 1; # the first\r\n  select 2-",
+		// a write may end between the dashes and the blank
+		"-", " the second\n  select 3--1;\nend;;\n",
+		"DELIMITER ;\n",
+		"SELECT '-- kept', \"# kept\";\n",
+	} {
+		_, err := l.Write([]byte(s))
+		require.NoError(t, err)
+	}
+	require.Equal(t, ""+
+		"/* dump of db1 */\n"+
+		"DELIMITER ;;\n"+
+		"CREATE PROCEDURE `p` () begin /* note * / here */ select 1; /* the first */ select 2/* the second */ select 3--1; end;;\n"+
+		"DELIMITER ;\n"+
+		"SELECT '-- kept', \"# kept\";\n", buf.String())
+}
+
+func TestSingleLineStatements(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	bufPool := &sync.Pool{
+		New: func() any {
+			return &bytes.Buffer{}
+		},
+	}
+	var buf bytes.Buffer
+	opt := Options{
+		netBufferLength: defaultNetBufferLength,
+		insertBatchRows: 2,
+		format:          formatSQL,
+		out:             newLineWriter(&buf),
+	}
+	ctx := context.Background()
+	opt.useDatabase("db1")
+	showCreateTable(opt.schema(), "CREATE TABLE `t1` (\n  `id` int NOT NULL,\n  `note` text DEFAULT NULL,\n  PRIMARY KEY (`id`)\n)", true)
+	mock.ExpectQuery("select \\* from `db1`.`t1`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "note"}).
+			AddRow(1, "first\nsecond").
+			AddRow(2, "a;\r\nb").
+			AddRow(3, "it's \\ done"))
+	queries, err := opt.selectQueries(ctx, "db1", "t1")
+	require.NoError(t, err)
+	require.NoError(t, opt.genOutput(ctx, queries, "db1", "t1", bufPool))
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	out := buf.String()
+	require.True(t, strings.HasSuffix(out, "\n"))
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	require.Len(t, lines, 4)
+	for _, line := range lines {
+		_, err = mysql.ParseOne(ctx, line, 1)
+		require.NoError(t, err, line)
+	}
+	require.Equal(t, "INSERT INTO `t1` VALUES (1,'first\\nsecond'),(2,'a;\\r\\nb');", lines[2])
+}
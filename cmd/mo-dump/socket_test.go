@@ -0,0 +1,61 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDSNSocket(t *testing.T) {
+	ctx := context.Background()
+	opt := Options{username: "dump", password: "111", host: "127.0.0.1:x", port: 6001, socket: "/tmp/mo.sock"}
+	dsn, err := opt.dsn(ctx, opt.host, "db1")
+	require.NoError(t, err)
+	require.Equal(t, "dump:111@unix(/tmp/mo.sock)/db1", dsn)
+	cfg, err := mysql.ParseDSN(dsn)
+	require.NoError(t, err)
+	require.Equal(t, "unix", cfg.Net)
+	require.Equal(t, "/tmp/mo.sock", cfg.Addr)
+}
+
+func TestOpenDBConnectionSocket(t *testing.T) {
+	ctx := context.Background()
+	db, _, err := sqlmock.NewWithDSN("dump:111@unix(/tmp/mo.sock)/db1")
+	require.NoError(t, err)
+	defer db.Close()
+
+	var opened []string
+	sqlOpen = func(_ string, dsn string) (*sql.DB, error) {
+		opened = append(opened, dsn)
+		return sql.Open("sqlmock", dsn)
+	}
+	defer func() { sqlOpen = sql.Open }()
+
+	// the connection is still pinged before it is used
+	opt := Options{username: "dump", password: "111", host: "h1", port: 6001, socket: "/tmp/mo.sock"}
+	c, err := opt.openDBConnection(ctx, "db1")
+	require.NoError(t, err)
+	require.NotNil(t, c)
+	require.Equal(t, []string{"dump:111@unix(/tmp/mo.sock)/db1"}, opened)
+
+	opt.socket = "/tmp/other.sock"
+	_, err = opt.openDBConnection(ctx, "db1")
+	require.Error(t, err)
+}
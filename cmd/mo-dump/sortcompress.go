@@ -0,0 +1,65 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/matrixorigin/matrixone/pkg/common/moerr"
+)
+
+// parseSortForCompression parses tbl:col1,col2;tbl:col into the columns
+// ordering the rows of each table, keyed by table name
+func parseSortForCompression(ctx context.Context, spec string) (map[string][]string, error) {
+	sorts := make(map[string][]string)
+	for _, item := range strings.Split(spec, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		tbl, list, ok := strings.Cut(item, ":")
+		tbl = strings.TrimSpace(tbl)
+		if !ok || tbl == "" {
+			return nil, moerr.NewInvalidInput(ctx, "sort-for-compression must be in the format tbl:col1,col2, got %s", item)
+		}
+		if _, ok := sorts[tbl]; ok {
+			return nil, moerr.NewInvalidInput(ctx, "table %s is given more than once in sort-for-compression", tbl)
+		}
+		var cols []string
+		for _, col := range strings.Split(list, ",") {
+			col = strings.TrimSpace(col)
+			if col == "" {
+				return nil, moerr.NewInvalidInput(ctx, "sort-for-compression must be in the format tbl:col1,col2, got %s", item)
+			}
+			cols = append(cols, col)
+		}
+		sorts[tbl] = cols
+	}
+	return sorts, nil
+}
+
+// orderBy returns the ORDER BY clause clustering the rows of the table for
+// group-by and sort-for-compression, or "" if the table is not sorted
+func (opt *Options) orderBy(tbl string) string {
+	cols := opt.sortForCompression[tbl]
+	if col, ok := opt.groupBy[tbl]; ok {
+		cols = append([]string{col}, cols...)
+	}
+	if len(cols) == 0 {
+		return ""
+	}
+	return " order by " + quoteIdents(cols)
+}
@@ -0,0 +1,148 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSortForCompression(t *testing.T) {
+	ctx := context.Background()
+	sorts, err := parseSortForCompression(ctx, "t1:status, country;t2:kind;")
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{
+		"t1": {"status", "country"},
+		"t2": {"kind"},
+	}, sorts)
+
+	for _, spec := range []string{"t1", ":a", "t1:", "t1:a,,b", "t1:a;t1:b"} {
+		_, err = parseSortForCompression(ctx, spec)
+		require.Error(t, err, spec)
+	}
+}
+
+func TestSelectQueriesSortForCompression(t *testing.T) {
+	ctx := context.Background()
+	opt := Options{
+		netBufferLength:    defaultNetBufferLength,
+		where:              "a > 1",
+		sortForCompression: map[string][]string{"t1": {"status", "country"}},
+	}
+	queries, err := opt.selectQueries(ctx, "db1", "t1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"select * from `db1`.`t1` where (a > 1) order by `status`,`country`"}, queries)
+
+	queries, err = opt.selectQueries(ctx, "db1", "t2")
+	require.NoError(t, err)
+	require.Equal(t, []string{"select * from `db1`.`t2` where (a > 1)"}, queries)
+
+	// every query of a where-in list is ordered
+	opt.where = ""
+	opt.whereIn = &whereIn{table: "t1", column: "id", values: []string{"1", "2", "3"}}
+	opt.netBufferLength = 75
+	queries, err = opt.selectQueries(ctx, "db1", "t1")
+	require.NoError(t, err)
+	require.Greater(t, len(queries), 1)
+	for _, q := range queries {
+		require.Regexp(t, " where `id` in \\(.*\\) order by `status`,`country`$", q)
+	}
+}
+
+func TestGenOutputSortForCompression(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	bufPool := &sync.Pool{
+		New: func() any {
+			return &bytes.Buffer{}
+		},
+	}
+	opt := Options{
+		netBufferLength:    defaultNetBufferLength,
+		format:             formatSQL,
+		sortForCompression: map[string][]string{"t1": {"kind"}},
+	}
+	mock.ExpectQuery("select \\* from `db1`.`t1` order by `kind`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "kind"}).AddRow(2, "a").AddRow(1, "b"))
+	queries, err := opt.selectQueries(context.Background(), "db1", "t1")
+	require.NoError(t, err)
+	out := captureStdout(t, func() {
+		require.NoError(t, opt.genOutput(context.Background(), queries, "db1", "t1", bufPool))
+	})
+	require.Contains(t, out, "INSERT INTO `t1` VALUES (2,'a'),(1,'b');")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// BenchmarkSortForCompression compares the gzip ratio of rows with low
+// cardinality columns in random order and clustered by those columns
+func BenchmarkSortForCompression(b *testing.B) {
+	statuses := []string{"active", "inactive", "pending", "deleted"}
+	countries := []string{"CN", "US", "DE", "JP", "FR", "BR", "IN", "GB"}
+	r := rand.New(rand.NewSource(1))
+	rows := make([][3]string, 100000)
+	for i := range rows {
+		rows[i] = [3]string{
+			statuses[r.Intn(len(statuses))],
+			countries[r.Intn(len(countries))],
+			fmt.Sprint(r.Intn(1000)),
+		}
+	}
+	encode := func(rows [][3]string) []byte {
+		var buf bytes.Buffer
+		for i, row := range rows {
+			fmt.Fprintf(&buf, "%d,%s,%s,%s\n", i, row[0], row[1], row[2])
+		}
+		return buf.Bytes()
+	}
+	sorted := append([][3]string(nil), rows...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i][0] != sorted[j][0] {
+			return sorted[i][0] < sorted[j][0]
+		}
+		return sorted[i][1] < sorted[j][1]
+	})
+
+	for _, c := range []struct {
+		name string
+		data []byte
+	}{
+		{"random", encode(rows)},
+		{"sorted", encode(sorted)},
+	} {
+		b.Run(c.name, func(b *testing.B) {
+			var size int
+			for i := 0; i < b.N; i++ {
+				var buf bytes.Buffer
+				w := gzip.NewWriter(&buf)
+				_, err := w.Write(c.data)
+				require.NoError(b, err)
+				require.NoError(b, w.Close())
+				size = buf.Len()
+			}
+			b.ReportMetric(float64(len(c.data))/float64(size), "ratio")
+		})
+	}
+}
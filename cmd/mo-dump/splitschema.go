@@ -0,0 +1,81 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+// the files -split-schema-data writes the dump to
+const (
+	schemaFileName = "schema.sql"
+	dataFileName   = "data.sql"
+)
+
+// schema returns where the DDL of the dump is written, the schema file if
+// the schema and the data are split, otherwise the same writer as the data
+func (opt *Options) schema() io.Writer {
+	if opt.schemaOut != nil {
+		return opt.schemaOut
+	}
+	return opt.stdout()
+}
+
+// splitSchema reports if the schema and the data are written to different
+// files. The data no longer follows the CREATE TABLE, which then ends the
+// statement group of the table itself.
+func (opt *Options) splitSchema() bool {
+	return opt.schemaOut != nil
+}
+
+// openSplitFiles creates the schema and the data file in dir
+func openSplitFiles(dir string) (schema *resultFile, data *resultFile, err error) {
+	schema, _, err = openResultFile(filepath.Join(dir, schemaFileName))
+	if err != nil {
+		return nil, nil, err
+	}
+	data, _, err = openResultFile(filepath.Join(dir, dataFileName))
+	if err != nil {
+		schema.Close()
+		return nil, nil, err
+	}
+	return schema, data, nil
+}
+
+// useDatabase switches the database of both files, since the data
+// statements name the tables only
+func (opt *Options) useDatabase(db string) {
+	if opt.splitSchema() {
+		fmt.Fprintf(opt.schema(), "USE %s;\n\n\n", quoteIdent(db))
+	}
+	fmt.Fprintf(opt.stdout(), "USE %s;\n\n\n", quoteIdent(db))
+}
+
+// foreignKeyChecks toggles the foreign key checks in both split files. The
+// tables are created and loaded in an order that ignores the references
+// between them. no-fk-toggle leaves out both the start and the end.
+func (opt *Options) foreignKeyChecks(on bool) {
+	if !opt.splitSchema() || opt.noFKToggle {
+		return
+	}
+	v := 0
+	if on {
+		v = 1
+	}
+	fmt.Fprintf(opt.schema(), "SET FOREIGN_KEY_CHECKS = %d;\n", v)
+	fmt.Fprintf(opt.stdout(), "SET FOREIGN_KEY_CHECKS = %d;\n", v)
+}
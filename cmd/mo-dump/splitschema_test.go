@@ -0,0 +1,91 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpDataSplitSchema(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	var schema, data bytes.Buffer
+	ctx := context.Background()
+	opt := Options{
+		dbs:             []string{"db1", "db2"},
+		emptyTables:     true,
+		netBufferLength: defaultNetBufferLength,
+		format:          formatSQL,
+		consistency:     consistencyNone,
+		schemaOut:       &schema,
+		out:             &data,
+	}
+	for _, name := range []string{"db1", "db2"} {
+		mock.ExpectQuery("show create database").
+			WillReturnRows(sqlmock.NewRows([]string{"Database", "Create"}).AddRow(name, "create database "+name))
+		mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables").
+			WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).AddRow("t1", "r").AddRow("v1", "v"))
+		mock.ExpectQuery("show create table").
+			WillReturnRows(sqlmock.NewRows([]string{"Table", "Create"}).AddRow("t1", "create table t1 (a int)"))
+		mock.ExpectQuery("show create table").
+			WillReturnRows(sqlmock.NewRows([]string{"View", "Create"}).AddRow("v1", "create view v1 as select a from t1"))
+		mock.ExpectQuery("select \\* from `" + name + "`.`t1`").
+			WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("1"))
+	}
+	err = opt.dumpData(ctx)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	require.Equal(t, "SET FOREIGN_KEY_CHECKS = 0;\n"+
+		"DROP DATABASE IF EXISTS `db1`;\ncreate database db1 ;\nUSE `db1`;\n\n\n"+
+		"DROP TABLE IF EXISTS `t1`;\ncreate table t1 (a int);\n\n\n"+
+		"DROP VIEW IF EXISTS `v1`;\ncreate view v1 as select a from t1;\n\n\n"+
+		"DROP DATABASE IF EXISTS `db2`;\ncreate database db2 ;\nUSE `db2`;\n\n\n"+
+		"DROP TABLE IF EXISTS `t1`;\ncreate table t1 (a int);\n\n\n"+
+		"DROP VIEW IF EXISTS `v1`;\ncreate view v1 as select a from t1;\n\n\n"+
+		"SET FOREIGN_KEY_CHECKS = 1;\n", schema.String())
+	require.NotContains(t, schema.String(), "INSERT")
+	require.Equal(t, "SET FOREIGN_KEY_CHECKS = 0;\n"+
+		"USE `db1`;\n\n\n"+
+		"INSERT INTO `t1` VALUES (1);\n\n\n\n"+
+		"USE `db2`;\n\n\n"+
+		"INSERT INTO `t1` VALUES (1);\n\n\n\n"+
+		"SET FOREIGN_KEY_CHECKS = 1;\n", data.String())
+	require.Equal(t, 4, opt.dumpedObjects)
+}
+
+func TestForeignKeyChecksNoFKToggle(t *testing.T) {
+	var schema, data bytes.Buffer
+	opt := Options{schemaOut: &schema, out: &data}
+	opt.foreignKeyChecks(false)
+	opt.foreignKeyChecks(true)
+	require.Equal(t, "SET FOREIGN_KEY_CHECKS = 0;\nSET FOREIGN_KEY_CHECKS = 1;\n", schema.String())
+	require.Equal(t, schema.String(), data.String())
+
+	schema.Reset()
+	data.Reset()
+	opt.noFKToggle = true
+	opt.foreignKeyChecks(false)
+	opt.foreignKeyChecks(true)
+	require.Empty(t, schema.String())
+	require.Empty(t, data.String())
+}
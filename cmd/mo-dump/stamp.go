@@ -0,0 +1,72 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/matrixorigin/matrixone/pkg/common/moerr"
+)
+
+// stamp records in a tracking table of the restore target which dump was
+// applied
+type stamp struct {
+	table   string
+	version string
+	create  bool
+}
+
+func (s *stamp) check(ctx context.Context) error {
+	if s.table == "" && s.version == "" && !s.create {
+		return nil
+	}
+	if s.table == "" || s.version == "" {
+		return moerr.NewInvalidInput(ctx, "stamp-table and stamp-version must be specified together")
+	}
+	return nil
+}
+
+func (s *stamp) enabled() bool {
+	return s.table != ""
+}
+
+// quotedTable quotes the stamp table, which may be qualified by a database
+func (s *stamp) quotedTable() string {
+	if db, tbl, ok := strings.Cut(s.table, "."); ok {
+		return quoteIdent(db) + "." + quoteIdent(tbl)
+	}
+	return quoteIdent(s.table)
+}
+
+// showStamp writes the statements recording the dump, the last ones of the
+// dump
+func (opt *Options) showStamp() {
+	s := &opt.stamp
+	if s.create {
+		fmt.Fprintf(opt.schema(), "CREATE TABLE IF NOT EXISTS %s (\n"+
+			"  `version` varchar(255) NOT NULL,\n"+
+			"  `dumped_at` datetime NOT NULL,\n"+
+			"  `source` varchar(255) NOT NULL\n"+
+			");\n", s.quotedTable())
+	}
+	source := fmt.Sprintf("%s:%d/%s", opt.host, opt.port, strings.Join(opt.dbs, ","))
+	if opt.socket != "" {
+		source = opt.socket + "/" + strings.Join(opt.dbs, ",")
+	}
+	fmt.Fprintf(opt.stdout(), "INSERT INTO %s (`version`, `dumped_at`, `source`) VALUES ('%s', '%s', '%s');\n",
+		s.quotedTable(), escapeString(s.version), opt.dumpStart.Format("2006-01-02 15:04:05"), escapeString(source))
+}
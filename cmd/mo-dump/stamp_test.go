@@ -0,0 +1,71 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStampCheck(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, (&stamp{}).check(ctx))
+	require.NoError(t, (&stamp{table: "schema_migrations", version: "v1"}).check(ctx))
+	require.Error(t, (&stamp{table: "schema_migrations"}).check(ctx))
+	require.Error(t, (&stamp{version: "v1"}).check(ctx))
+	require.Error(t, (&stamp{create: true}).check(ctx))
+}
+
+func TestDumpDataStamp(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	ctx := context.Background()
+	opt := Options{
+		host:            "127.0.0.1",
+		port:            6001,
+		dbs:             []string{"db1"},
+		tables:          Tables{{"t1", ""}},
+		netBufferLength: defaultNetBufferLength,
+		format:          formatSQL,
+		consistency:     consistencyNone,
+		stamp:           stamp{table: "ops.schema_migrations", version: "v2024.06", create: true},
+		dumpStart:       time.Date(2024, 6, 1, 8, 30, 0, 0, time.UTC),
+	}
+	mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables").
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).AddRow("t1", "r"))
+	mock.ExpectQuery("show create table").
+		WillReturnRows(sqlmock.NewRows([]string{"Table", "Create"}).AddRow("t1", "create table t1 (a int)"))
+	mock.ExpectQuery("select \\* from `db1`.`t1`").
+		WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("1"))
+	out := captureStdout(t, func() {
+		err = opt.dumpData(ctx)
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Less(t, strings.Index(out, "INSERT INTO `t1`"), strings.Index(out, "CREATE TABLE IF NOT EXISTS"))
+	require.True(t, strings.HasSuffix(out, "CREATE TABLE IF NOT EXISTS `ops`.`schema_migrations` (\n"+
+		"  `version` varchar(255) NOT NULL,\n"+
+		"  `dumped_at` datetime NOT NULL,\n"+
+		"  `source` varchar(255) NOT NULL\n"+
+		");\n"+
+		"INSERT INTO `ops`.`schema_migrations` (`version`, `dumped_at`, `source`) VALUES ('v2024.06', '2024-06-01 08:30:00', '127.0.0.1:6001/db1');\n"), out)
+}
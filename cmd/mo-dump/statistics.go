@@ -0,0 +1,140 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/matrixorigin/matrixone/pkg/catalog"
+)
+
+// tableStatistics holds the statistics MatrixOne keeps in the metadata of
+// a table: row count, size in bytes and min/max of every column
+type tableStatistics struct {
+	Table   string             `json:"table"`
+	Rows    int64              `json:"rows"`
+	Size    int64              `json:"size"`
+	Columns []columnStatistics `json:"columns"`
+}
+
+type columnStatistics struct {
+	Name string  `json:"name"`
+	Min  *string `json:"min"`
+	Max  *string `json:"max"`
+}
+
+// dumpStatistics writes the statistics of the ordinary tables to
+// db.statistics.json. MatrixOne derives statistics from the storage, they
+// can not be imported, so the file serves to compare the plans of the
+// source and the restored database. If the statistics can not be read,
+// a warning is printed and the dump goes on without them.
+func dumpStatistics(ctx context.Context, out io.Writer, dir string, db string, tables Tables) error {
+	stats := make([]*tableStatistics, 0, len(tables))
+	for _, tbl := range tables {
+		if tbl.Kind != catalog.SystemOrdinaryRel {
+			continue
+		}
+		s, err := getTableStatistics(ctx, db, tbl.Name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "can not read statistics of `%s`.`%s`, skip statistics of database %s: %v\n", db, tbl.Name, db, err)
+			return nil
+		}
+		stats = append(stats, s)
+	}
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return err
+	}
+	fname := dataFile(dir, fmt.Sprintf("%s.%s", db, "statistics.json"))
+	err = os.WriteFile(fname, data, 0644)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "/* STATISTICS '%s' */\n", refPath(fname))
+	return nil
+}
+
+func getTableStatistics(ctx context.Context, db, tbl string) (*tableStatistics, error) {
+	cols, err := getColumnNames(ctx, db, tbl)
+	if err != nil {
+		return nil, err
+	}
+	s := &tableStatistics{
+		Table:   tbl,
+		Columns: make([]columnStatistics, len(cols)),
+	}
+	minMax := make([]sql.NullString, 2*len(cols))
+	dest := make([]any, 0, 2+len(minMax))
+	dest = append(dest, &s.Rows, &s.Size)
+	for i := range minMax {
+		dest = append(dest, &minMax[i])
+	}
+	err = session(ctx).QueryRowContext(ctx, statisticsQuery(db, tbl, cols)).Scan(dest...)
+	if err != nil {
+		return nil, err
+	}
+	for i, col := range cols {
+		s.Columns[i].Name = col
+		if minMax[2*i].Valid {
+			s.Columns[i].Min = &minMax[2*i].String
+		}
+		if minMax[2*i+1].Valid {
+			s.Columns[i].Max = &minMax[2*i+1].String
+		}
+	}
+	return s, nil
+}
+
+// statisticsQuery reads all statistics of the table in one statement
+func statisticsQuery(db, tbl string, cols []string) string {
+	args := "'" + escapeString(db) + "', '" + escapeString(tbl) + "'"
+	exprs := make([]string, 0, 2+2*len(cols))
+	exprs = append(exprs, "mo_table_rows("+args+")", "mo_table_size("+args+")")
+	for _, col := range cols {
+		colArgs := args + ", '" + escapeString(col) + "'"
+		exprs = append(exprs, "mo_table_col_min("+colArgs+")", "mo_table_col_max("+colArgs+")")
+	}
+	return "select " + strings.Join(exprs, ", ")
+}
+
+// getColumnNames returns the visible columns of the table in definition order
+func getColumnNames(ctx context.Context, db, tbl string) ([]string, error) {
+	r, err := session(ctx).QueryContext(ctx, "select attname from mo_catalog.mo_columns where att_database = '"+escapeString(db)+
+		"' and att_relname = '"+escapeString(tbl)+"' and att_is_hidden = 0 order by attnum")
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var cols []string
+	for r.Next() {
+		var col string
+		err = r.Scan(&col)
+		if err != nil {
+			return nil, err
+		}
+		cols = append(cols, col)
+	}
+	if err = r.Err(); err != nil {
+		return nil, err
+	}
+	return cols, nil
+}
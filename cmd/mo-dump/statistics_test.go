@@ -0,0 +1,70 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatisticsQuery(t *testing.T) {
+	require.Equal(t, "select mo_table_rows('db1', 't1'), mo_table_size('db1', 't1')", statisticsQuery("db1", "t1", nil))
+	require.Equal(t,
+		"select mo_table_rows('db1', 't\\'1'), mo_table_size('db1', 't\\'1'), "+
+			"mo_table_col_min('db1', 't\\'1', 'a'), mo_table_col_max('db1', 't\\'1', 'a'), "+
+			"mo_table_col_min('db1', 't\\'1', 'b'), mo_table_col_max('db1', 't\\'1', 'b')",
+		statisticsQuery("db1", "t'1", []string{"a", "b"}))
+}
+
+func TestGetTableStatistics(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	mock.ExpectQuery("select attname from mo_catalog.mo_columns where att_database = 'db1' and att_relname = 't1' and att_is_hidden = 0 order by attnum").
+		WillReturnRows(sqlmock.NewRows([]string{"attname"}).AddRow("a").AddRow("b"))
+	mock.ExpectQuery(statisticsQuery("db1", "t1", []string{"a", "b"})).
+		WillReturnRows(sqlmock.NewRows([]string{"rows", "size", "min_a", "max_a", "min_b", "max_b"}).
+			AddRow(10, 4096, "1", "10", nil, nil))
+	s, err := getTableStatistics(context.Background(), "db1", "t1")
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Equal(t, int64(10), s.Rows)
+	require.Equal(t, int64(4096), s.Size)
+	require.Equal(t, 2, len(s.Columns))
+	require.Equal(t, "1", *s.Columns[0].Min)
+	require.Equal(t, "10", *s.Columns[0].Max)
+	require.Nil(t, s.Columns[1].Min)
+}
+
+func TestDumpStatisticsUnavailable(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	mock.ExpectQuery("mo_columns").WillReturnError(fmt.Errorf("access denied"))
+	out := captureStdout(t, func() {
+		err = dumpStatistics(context.Background(), os.Stdout, "", "db1", Tables{{"t1", "r"}, {"v1", "v"}})
+	})
+	require.NoError(t, err)
+	require.Equal(t, "", out)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,60 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/matrixorigin/matrixone/pkg/common/moerr"
+)
+
+// splitTablePatterns separates the shell style globs of -tbl, which are
+// matched against the tables of each database, from the plain names
+func splitTablePatterns(tables Tables) (names Tables, patterns []string) {
+	for _, tbl := range tables {
+		if isPattern(tbl.Name) {
+			patterns = append(patterns, tbl.Name)
+		} else {
+			names = append(names, tbl)
+		}
+	}
+	return names, patterns
+}
+
+// checkTablePatterns fails for the patterns of -tbl matching no table of
+// db, or warns about them when allowEmpty is set. The tables of a time
+// partitioned schema differ per database, so a pattern may match nothing.
+func checkTablePatterns(ctx context.Context, db string, patterns []string, tables Tables, allowEmpty bool) error {
+	for _, p := range patterns {
+		matched := false
+		for _, tbl := range tables {
+			if matchAny([]string{p}, tbl.Name) {
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+		if allowEmpty {
+			fmt.Fprintf(os.Stderr, "table pattern %s matches no table in database %s, skip it\n", p, db)
+			continue
+		}
+		return moerr.NewInvalidInput(ctx, "table pattern %s matches no table in database %s", p, db)
+	}
+	return nil
+}
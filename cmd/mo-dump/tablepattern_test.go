@@ -0,0 +1,98 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitTablePatterns(t *testing.T) {
+	names, patterns := splitTablePatterns(Tables{{"t1", ""}, {"log_2024_*", ""}, {"t?", ""}, {`t\[1\]`, ""}})
+	require.Equal(t, Tables{{"t1", ""}}, names)
+	require.Equal(t, []string{"log_2024_*", "t?", `t\[1\]`}, patterns)
+}
+
+func TestGetTablesPattern(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	ctx := context.Background()
+	query := "select relname,relkind from mo_catalog.mo_tables where reldatabase = 'db1'" + transientCond
+	newRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"relname", "relkind"}).
+			AddRow("log_2024_01", "r").AddRow("log_2023_12", "r").AddRow("users", "r").AddRow("log_2024_02", "r")
+	}
+
+	// a pattern lists all the tables and keeps the matching and the named ones
+	mock.ExpectQuery(query).WillReturnRows(newRows())
+	tables, err := getTables(ctx, "db1", Tables{{"users", ""}, {"log_2024_*", ""}}, nil, false, false, false)
+	require.NoError(t, err)
+	require.Equal(t, Tables{{"log_2024_01", "r"}, {"users", "r"}, {"log_2024_02", "r"}}, tables)
+
+	mock.ExpectQuery(query).WillReturnRows(newRows())
+	_, err = getTables(ctx, "db1", Tables{{"users", ""}, {"log_2025_*", ""}}, nil, false, false, false)
+	require.ErrorContains(t, err, "table pattern log_2025_* matches no table in database db1")
+
+	mock.ExpectQuery(query).WillReturnRows(newRows())
+	stderr := captureStderr(t, func() {
+		tables, err = getTables(ctx, "db1", Tables{{"users", ""}, {"log_2025_*", ""}}, nil, false, true, false)
+	})
+	require.NoError(t, err)
+	require.Equal(t, Tables{{"users", "r"}}, tables)
+	require.Contains(t, stderr, "table pattern log_2025_* matches no table in database db1, skip it")
+
+	// a named table missing still fails
+	mock.ExpectQuery(query).WillReturnRows(newRows())
+	_, err = getTables(ctx, "db1", Tables{{"missing", ""}, {"log_2024_*", ""}}, nil, false, false, false)
+	require.ErrorContains(t, err, "table missing not exists")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetTablesLiteralPattern(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	ctx := context.Background()
+	query := "select relname,relkind from mo_catalog.mo_tables where reldatabase = 'db1'" + transientCond
+	newRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"relname", "relkind"}).AddRow("t1", "r").AddRow("t[1]", "r").AddRow("t2", "r")
+	}
+
+	// the name of an existing table is not read as a glob
+	mock.ExpectQuery(query).WillReturnRows(newRows())
+	tables, err := getTables(ctx, "db1", Tables{{"t[1]", ""}}, nil, false, false, false)
+	require.NoError(t, err)
+	require.Equal(t, Tables{{"t[1]", "r"}}, tables)
+
+	// escaped metacharacters match themselves
+	mock.ExpectQuery(query).WillReturnRows(newRows())
+	tables, err = getTables(ctx, "db1", Tables{{`t\[1\]`, ""}}, nil, false, false, false)
+	require.NoError(t, err)
+	require.Equal(t, Tables{{"t[1]", "r"}}, tables)
+
+	// without such a table it is a glob
+	mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).AddRow("t1", "r").AddRow("t2", "r"))
+	tables, err = getTables(ctx, "db1", Tables{{"t[1]", ""}}, nil, false, false, false)
+	require.NoError(t, err)
+	require.Equal(t, Tables{{"t1", "r"}}, tables)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,90 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/matrixorigin/matrixone/pkg/catalog"
+)
+
+// transientCond leaves the temporary tables out of a query on mo_tables
+const transientCond = " and relpersistence <> '" + catalog.SystemTransientRel + "'"
+
+// persistenceColumn caches if mo_tables has relpersistence. The column is
+// probed once, by the first getTables which needs it.
+var persistenceColumn struct {
+	probed bool
+	exists bool
+}
+
+// persistenceCond is the condition of getTables on mo_tables. Temporary
+// tables are marked transient in relpersistence and are left out unless
+// -include-temporary is set. The condition is left out on a server whose
+// mo_tables has no relpersistence, which can not tell temporary tables
+// apart.
+func persistenceCond(ctx context.Context, temporary bool) (string, error) {
+	if temporary {
+		return "", nil
+	}
+	if !persistenceColumn.probed {
+		var cnt int
+		err := connRetry.queryRow(ctx, "read the relpersistence column of mo_catalog.mo_tables",
+			"select count(*) from mo_catalog.mo_columns where att_database = 'mo_catalog' and att_relname = 'mo_tables' and attname = 'relpersistence'", &cnt)
+		if err != nil {
+			return "", err
+		}
+		persistenceColumn.probed = true
+		persistenceColumn.exists = cnt > 0
+	}
+	if !persistenceColumn.exists {
+		return "", nil
+	}
+	return transientCond, nil
+}
+
+// getTemporaryTables returns the temporary tables of db which the session
+// can see. A temporary table belongs to the session that created it, so
+// mo-dump usually sees none of them.
+func getTemporaryTables(ctx context.Context, db string) (map[string]bool, error) {
+	qctx, cancel := queryContext(ctx)
+	defer cancel()
+	what := "list temporary tables of " + quoteIdent(db)
+	r, err := connRetry.query(qctx, what, "select relname from mo_catalog.mo_tables where reldatabase = '"+escapeString(db)+"' and relpersistence = '"+catalog.SystemTransientRel+"'")
+	if err != nil {
+		return nil, queryTimeoutError(ctx, qctx, err, what)
+	}
+	defer r.Close()
+	temporary := map[string]bool{}
+	for r.Next() {
+		var tbl string
+		if err = r.Scan(&tbl); err != nil {
+			return nil, err
+		}
+		temporary[tbl] = true
+	}
+	return temporary, queryTimeoutError(ctx, qctx, r.Err(), what)
+}
+
+// temporaryCreate turns the DDL of a temporary table into CREATE TEMPORARY
+// TABLE, which SHOW CREATE TABLE does not keep
+func temporaryCreate(create string) string {
+	const prefix = "create table"
+	if len(create) < len(prefix) || !strings.EqualFold(create[:len(prefix)], prefix) {
+		return create
+	}
+	return create[:len("create ")] + "TEMPORARY " + create[len("create "):]
+}
@@ -0,0 +1,123 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemporaryCreate(t *testing.T) {
+	require.Equal(t, "CREATE TEMPORARY TABLE `t` (`a` INT)", temporaryCreate("CREATE TABLE `t` (`a` INT)"))
+	require.Equal(t, "create TEMPORARY table t (a int)", temporaryCreate("create table t (a int)"))
+	require.Equal(t, "CREATE VIEW v AS SELECT 1", temporaryCreate("CREATE VIEW v AS SELECT 1"))
+}
+
+func TestGetTablesTemporary(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	ctx := context.Background()
+	mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables where reldatabase = 'db1' and relpersistence <> 't'").
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).AddRow("t1", "r"))
+	tables, err := getTables(ctx, "db1", nil, nil, false, false, false)
+	require.NoError(t, err)
+	require.Equal(t, Tables{{"t1", "r"}}, tables)
+
+	mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables where reldatabase = 'db1'").
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).AddRow("t1", "r").AddRow("tmp", "r"))
+	tables, err = getTables(ctx, "db1", nil, nil, false, false, true)
+	require.NoError(t, err)
+	require.Equal(t, Tables{{"t1", "r"}, {"tmp", "r"}}, tables)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetTablesProbePersistence(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	saved := persistenceColumn
+	defer func() { persistenceColumn = saved }()
+	persistenceColumn.probed = false
+
+	// a server without relpersistence gets the query without the condition,
+	// the column is probed only once
+	ctx := context.Background()
+	mock.ExpectQuery("select count(*) from mo_catalog.mo_columns where att_database = 'mo_catalog' and att_relname = 'mo_tables' and attname = 'relpersistence'").
+		WillReturnRows(sqlmock.NewRows([]string{"count(*)"}).AddRow(0))
+	for i := 0; i < 2; i++ {
+		mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables where reldatabase = 'db1'").
+			WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).AddRow("t1", "r"))
+		tables, err := getTables(ctx, "db1", nil, nil, false, false, false)
+		require.NoError(t, err)
+		require.Equal(t, Tables{{"t1", "r"}}, tables)
+	}
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// -include-temporary does not need the probe
+	persistenceColumn.probed = false
+	mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables where reldatabase = 'db1'").
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).AddRow("t1", "r"))
+	_, err = getTables(ctx, "db1", nil, nil, false, false, true)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	mock.ExpectQuery("select count(*) from mo_catalog.mo_columns where att_database = 'mo_catalog' and att_relname = 'mo_tables' and attname = 'relpersistence'").
+		WillReturnRows(sqlmock.NewRows([]string{"count(*)"}).AddRow(1))
+	mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables where reldatabase = 'db1'" + transientCond).
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).AddRow("t1", "r"))
+	_, err = getTables(ctx, "db1", nil, nil, false, false, false)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDumpDataTemporary(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	ctx := context.Background()
+	opt := Options{
+		dbs:              []string{"db1"},
+		netBufferLength:  defaultNetBufferLength,
+		format:           formatSQL,
+		consistency:      consistencyNone,
+		noData:           true,
+		includeTemporary: true,
+	}
+	mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables").
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).AddRow("t1", "r").AddRow("tmp", "r"))
+	mock.ExpectQuery("select relname from mo_catalog.mo_tables where reldatabase = 'db1' and relpersistence = 't'").
+		WillReturnRows(sqlmock.NewRows([]string{"relname"}).AddRow("tmp"))
+	for _, tbl := range []string{"t1", "tmp"} {
+		mock.ExpectQuery("show create table").
+			WillReturnRows(sqlmock.NewRows([]string{"Table", "Create"}).AddRow(tbl, "CREATE TABLE `"+tbl+"` (`a` INT)"))
+	}
+	out := captureStdout(t, func() {
+		err = opt.dumpData(ctx)
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Contains(t, out, "DROP TABLE IF EXISTS `t1`;\nCREATE TABLE `t1` (`a` INT);")
+	require.Contains(t, out, "DROP TABLE IF EXISTS `tmp`;\nCREATE TEMPORARY TABLE `tmp` (`a` INT);")
+	require.Equal(t, 2, opt.dumpedObjects)
+}
@@ -0,0 +1,145 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/matrixorigin/matrixone/pkg/common/moerr"
+)
+
+// timeWindow is the [from, to) range on timeColumn used to restrict
+// the rows dumped from append-only tables
+type timeWindow struct {
+	column string
+	from   string
+	to     string
+}
+
+// Partition describes one partition of a table as reported by
+// information_schema.partitions
+type Partition struct {
+	Name        string
+	Method      string
+	Expression  string
+	Description string
+}
+
+func (w *timeWindow) enabled() bool {
+	return w.column != ""
+}
+
+// check validates the combination of -time-column, -from and -to
+func (w *timeWindow) check(ctx context.Context) error {
+	if w.column == "" {
+		if w.from != "" || w.to != "" {
+			return moerr.NewInvalidInput(ctx, "-from and -to require -time-column")
+		}
+		return nil
+	}
+	if w.from == "" && w.to == "" {
+		return moerr.NewInvalidInput(ctx, "-time-column requires at least one of -from and -to")
+	}
+	if w.from != "" && w.to != "" && w.from >= w.to {
+		return moerr.NewInvalidInput(ctx, "-from must be earlier than -to")
+	}
+	return nil
+}
+
+// predicate generates an index-friendly range predicate on the time column.
+// The column is compared directly against constants so that the server can
+// use an index or the sort key on it.
+func (w *timeWindow) predicate() string {
+	var conds []string
+	if w.from != "" {
+		conds = append(conds, quoteIdent(w.column)+" >= '"+escapeString(w.from)+"'")
+	}
+	if w.to != "" {
+		conds = append(conds, quoteIdent(w.column)+" < '"+escapeString(w.to)+"'")
+	}
+	return strings.Join(conds, " AND ")
+}
+
+// prunePartitions returns the names of the range partitions which may hold
+// rows inside the window. nil is returned if the table is not partitioned by
+// RANGE COLUMNS on the time column, meaning no pruning can be done.
+// Bounds are compared as strings, which is correct for the canonical
+// 'YYYY-MM-DD[ hh:mm:ss]' formats.
+func (w *timeWindow) prunePartitions(parts []Partition) []string {
+	if len(parts) == 0 {
+		return nil
+	}
+	for _, p := range parts {
+		if p.Method != "RANGE COLUMNS" || unquoteIdent(p.Expression) != strings.ToLower(w.column) {
+			return nil
+		}
+	}
+	names := make([]string, 0, len(parts))
+	lower := ""
+	for i, p := range parts {
+		upper := unquoteLiteral(p.Description)
+		maxValue := strings.EqualFold(upper, "MAXVALUE")
+		// partition i holds [lower, upper)
+		overlap := (w.to == "" || i == 0 || lower < w.to) &&
+			(w.from == "" || maxValue || upper > w.from)
+		if overlap {
+			names = append(names, p.Name)
+		}
+		lower = upper
+	}
+	return names
+}
+
+// getPartitions returns the partitions of the table ordered by position
+func getPartitions(ctx context.Context, db, tbl string) ([]Partition, error) {
+	qctx, cancel := queryContext(ctx)
+	defer cancel()
+	what := "read partitions of " + quoteIdent(db) + "." + quoteIdent(tbl)
+	r, err := connRetry.query(qctx, what, "select partition_name, ifnull(partition_method, ''), ifnull(partition_expression, ''), ifnull(partition_description, '') "+
+		"from information_schema.partitions where table_schema = '"+escapeString(db)+"' and table_name = '"+escapeString(tbl)+"' "+
+		"and partition_name is not null order by partition_ordinal_position")
+	if err != nil {
+		return nil, queryTimeoutError(ctx, qctx, err, what)
+	}
+	defer r.Close()
+
+	var parts []Partition
+	for r.Next() {
+		var p Partition
+		err = r.Scan(&p.Name, &p.Method, &p.Expression, &p.Description)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, p)
+	}
+	if err = r.Err(); err != nil {
+		return nil, queryTimeoutError(ctx, qctx, err, what)
+	}
+	return parts, nil
+}
+
+func unquoteIdent(s string) string {
+	return strings.ToLower(strings.Trim(strings.TrimSpace(s), "`"))
+}
+
+func unquoteLiteral(s string) string {
+	return strings.Trim(strings.TrimSpace(s), "'\"")
+}
+
+func escapeString(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\\\")
+	return strings.ReplaceAll(s, "'", "\\'")
+}
@@ -0,0 +1,124 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeWindowCheck(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, (&timeWindow{}).check(ctx))
+	require.NoError(t, (&timeWindow{column: "ts", from: "2023-01-01"}).check(ctx))
+	require.Error(t, (&timeWindow{from: "2023-01-01"}).check(ctx))
+	require.Error(t, (&timeWindow{column: "ts"}).check(ctx))
+	require.Error(t, (&timeWindow{column: "ts", from: "2023-02-01", to: "2023-01-01"}).check(ctx))
+}
+
+func TestTimeWindowPredicate(t *testing.T) {
+	kases := []struct {
+		w    timeWindow
+		want string
+	}{
+		{timeWindow{"ts", "2023-01-01", "2023-02-01"}, "`ts` >= '2023-01-01' AND `ts` < '2023-02-01'"},
+		{timeWindow{"ts", "2023-01-01", ""}, "`ts` >= '2023-01-01'"},
+		{timeWindow{"ts", "", "2023-02-01"}, "`ts` < '2023-02-01'"},
+		{timeWindow{"ts", "2023'01", ""}, "`ts` >= '2023\\'01'"},
+	}
+	for _, k := range kases {
+		require.Equal(t, k.want, k.w.predicate())
+	}
+}
+
+func TestTimeWindowPrunePartitions(t *testing.T) {
+	parts := []Partition{
+		{"p0", "RANGE COLUMNS", "`ts`", "'2023-01-01'"},
+		{"p1", "RANGE COLUMNS", "`ts`", "'2023-02-01'"},
+		{"p2", "RANGE COLUMNS", "`ts`", "'2023-03-01'"},
+		{"p3", "RANGE COLUMNS", "`ts`", "MAXVALUE"},
+	}
+	kases := []struct {
+		w    timeWindow
+		want []string
+	}{
+		{timeWindow{"ts", "2023-01-15", "2023-02-15"}, []string{"p1", "p2"}},
+		{timeWindow{"ts", "2023-01-01", "2023-02-01"}, []string{"p1"}},
+		{timeWindow{"ts", "", "2023-01-01"}, []string{"p0"}},
+		{timeWindow{"ts", "2023-05-01", ""}, []string{"p3"}},
+		{timeWindow{"TS", "2022-01-01", "2024-01-01"}, []string{"p0", "p1", "p2", "p3"}},
+	}
+	for _, k := range kases {
+		require.Equal(t, k.want, k.w.prunePartitions(parts))
+	}
+
+	w := timeWindow{"other", "2023-01-01", ""}
+	require.Nil(t, w.prunePartitions(parts))
+	hash := []Partition{{"p0", "HASH", "`ts`", ""}}
+	require.Nil(t, w.prunePartitions(hash))
+	require.Nil(t, w.prunePartitions(nil))
+}
+
+func TestSelectQueryTimeWindow(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	ctx := context.Background()
+	opt := Options{window: timeWindow{"ts", "2023-01-15", "2023-02-15"}}
+
+	rows := sqlmock.NewRows([]string{"name", "method", "expression", "description"}).
+		AddRow("p0", "RANGE COLUMNS", "`ts`", "'2023-01-01'").
+		AddRow("p1", "RANGE COLUMNS", "`ts`", "'2023-02-01'").
+		AddRow("p2", "RANGE COLUMNS", "`ts`", "MAXVALUE")
+	mock.ExpectQuery("information_schema.partitions").WillReturnRows(rows)
+	queries, err := opt.selectQueries(ctx, "db1", "t1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"select * from `db1`.`t1` partition (`p1`,`p2`) where `ts` >= '2023-01-15' AND `ts` < '2023-02-15'"}, queries)
+
+	mock.ExpectQuery("information_schema.partitions").WillReturnRows(sqlmock.NewRows([]string{"name", "method", "expression", "description"}))
+	queries, err = opt.selectQueries(ctx, "db1", "t2")
+	require.NoError(t, err)
+	require.Equal(t, []string{"select * from `db1`.`t2` where `ts` >= '2023-01-15' AND `ts` < '2023-02-15'"}, queries)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	opt.window = timeWindow{}
+	queries, err = opt.selectQueries(ctx, "db1", "t1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"select * from `db1`.`t1`"}, queries)
+}
+
+func TestSelectQueryWhere(t *testing.T) {
+	ctx := context.Background()
+	opt := Options{where: "a > 1 or b < 2"}
+	queries, err := opt.selectQueries(ctx, "db1", "t1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"select * from `db1`.`t1` where (a > 1 or b < 2)"}, queries)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+	mock.ExpectQuery("information_schema.partitions").WillReturnRows(sqlmock.NewRows([]string{"name", "method", "expression", "description"}))
+	opt.window = timeWindow{"ts", "2023-01-01", ""}
+	queries, err = opt.selectQueries(ctx, "db1", "t1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"select * from `db1`.`t1` where `ts` >= '2023-01-01' AND (a > 1 or b < 2)"}, queries)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
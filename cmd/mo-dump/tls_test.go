@@ -0,0 +1,152 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestCA creates a CA and a server certificate for host signed by it.
+// It returns the path of the CA certificate and the server certificate.
+func newTestCA(t *testing.T, host string) (string, tls.Certificate) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "mo-dump test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: host},
+		DNSNames:     []string{host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}), 0644))
+	return path, tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// handshake connects a client with cfg to a server with cert
+func handshake(cfg *tls.Config, cert tls.Certificate) error {
+	c, s := net.Pipe()
+	defer c.Close()
+	defer s.Close()
+	server := tls.Server(s, &tls.Config{Certificates: []tls.Certificate{cert}})
+	go server.Handshake()
+	return tls.Client(c, cfg).Handshake()
+}
+
+func TestSSLOptions(t *testing.T) {
+	ctx := context.Background()
+	ca, cert := newTestCA(t, "db.example.com")
+	otherCA, _ := newTestCA(t, "db.example.com")
+
+	// verify-identity checks the host name against the certificate
+	s := sslOptions{mode: sslVerifyIdentity, ca: ca}
+	require.NoError(t, s.load(ctx))
+	require.NoError(t, handshake(s.configFor("db.example.com"), cert))
+	require.Error(t, handshake(s.configFor("127.0.0.1"), cert))
+	s = sslOptions{mode: sslVerifyIdentity, ca: otherCA}
+	require.NoError(t, s.load(ctx))
+	require.Error(t, handshake(s.configFor("db.example.com"), cert))
+
+	// verify-ca checks the CA only
+	s = sslOptions{mode: sslVerifyCA, ca: ca}
+	require.NoError(t, s.load(ctx))
+	require.NoError(t, handshake(s.configFor("127.0.0.1"), cert))
+	s = sslOptions{mode: sslVerifyCA, ca: otherCA}
+	require.NoError(t, s.load(ctx))
+	require.Error(t, handshake(s.configFor("127.0.0.1"), cert))
+
+	// required does not verify
+	s = sslOptions{mode: sslRequired}
+	require.NoError(t, s.load(ctx))
+	require.NoError(t, handshake(s.configFor("127.0.0.1"), cert))
+
+	for _, s := range []sslOptions{
+		{mode: "on"},
+		{mode: sslPreferred, ca: ca},
+		{mode: sslDisabled, cert: "client.pem", key: "client.key"},
+		{mode: sslRequired, cert: "client.pem"},
+		{mode: sslVerifyCA, ca: filepath.Join(t.TempDir(), "missing.pem")},
+	} {
+		require.Error(t, s.load(ctx), s)
+	}
+}
+
+func TestDSNSSL(t *testing.T) {
+	ctx := context.Background()
+	ca, _ := newTestCA(t, "db.example.com")
+	opt := Options{username: "dump", password: "111", host: "db.example.com", port: 6001}
+
+	opt.ssl = sslOptions{mode: sslDisabled}
+	dsn, err := opt.dsn(ctx, opt.host, "db1")
+	require.NoError(t, err)
+	require.Equal(t, "dump:111@tcp(db.example.com:6001)/db1", dsn)
+
+	opt.ssl = sslOptions{mode: sslPreferred}
+	dsn, err = opt.dsn(ctx, opt.host, "db1")
+	require.NoError(t, err)
+	require.Equal(t, "dump:111@tcp(db.example.com:6001)/db1?tls=preferred", dsn)
+
+	opt.ssl = sslOptions{mode: sslVerifyIdentity, ca: ca}
+	require.NoError(t, opt.ssl.load(ctx))
+	dsn, err = opt.dsn(ctx, opt.host, "db1")
+	require.NoError(t, err)
+	require.Equal(t, "dump:111@tcp(db.example.com:6001)/db1?tls=mo-dump-db.example.com", dsn)
+	cfg, err := mysql.ParseDSN(dsn)
+	require.NoError(t, err)
+	require.NotNil(t, cfg.TLS)
+	require.Equal(t, "db.example.com", cfg.TLS.ServerName)
+	require.False(t, cfg.TLS.InsecureSkipVerify)
+
+	// the tls parameter can not be overridden
+	opt.dsnParams = "tls=false"
+	_, err = opt.dsn(ctx, opt.host, "db1")
+	require.Error(t, err)
+}
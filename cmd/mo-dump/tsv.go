@@ -0,0 +1,71 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// tsvEscaper escapes the characters LOAD DATA would read as a separator,
+// with the backslash of its default ESCAPED BY
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// tsvWriter writes records as tab separated values for -format tsv. No
+// field is enclosed in quotes, so commas and quotes are written as they
+// are. NULL is written as \N, the backslash of a string \N is escaped so
+// it differs from NULL.
+type tsvWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func newTSVWriter(w io.Writer) *tsvWriter {
+	return &tsvWriter{w: bufio.NewWriter(w)}
+}
+
+func (w *tsvWriter) Write(record []string) error {
+	return w.writeRow(record, nil)
+}
+
+func (w *tsvWriter) writeRow(record []string, nulls []bool) error {
+	if w.err != nil {
+		return w.err
+	}
+	for i, field := range record {
+		if i > 0 {
+			w.w.WriteByte('\t')
+		}
+		if nulls != nil && nulls[i] {
+			w.w.Write(nullBytes)
+			continue
+		}
+		tsvEscaper.WriteString(w.w, field)
+	}
+	// bufio.Writer keeps the first error, the last write returns it
+	w.err = w.w.WriteByte('\n')
+	return w.err
+}
+
+func (w *tsvWriter) Flush() {
+	if w.err == nil {
+		w.err = w.w.Flush()
+	}
+}
+
+func (w *tsvWriter) Error() error {
+	return w.err
+}
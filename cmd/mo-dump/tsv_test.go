@@ -0,0 +1,73 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/matrixorigin/matrixone/pkg/sql/parsers/dialect/mysql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTSVWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := newTSVWriter(&buf)
+	require.NoError(t, w.writeRow([]string{"a,b", "\"q\"", "\\N", "x\ty", "1\n2\r", `c:\dir`}, []bool{false, false, true, false, false, false}))
+	require.NoError(t, w.writeRow([]string{"", "\\N"}, []bool{false, false}))
+	w.Flush()
+	require.NoError(t, w.Error())
+	require.Equal(t, "a,b\t\"q\"\t\\N\tx\\ty\t1\\n2\\r\tc:\\\\dir\n\t\\\\N\n", buf.String())
+}
+
+func TestToCsvTSV(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "name"}).
+		AddRow("1", "smith, john").
+		AddRow("2", nil).
+		AddRow("3", "\\N")
+	mock.ExpectQuery("select").WillReturnRows(rows)
+	r, err := db.Query("select")
+	require.NoError(t, err)
+	defer r.Close()
+
+	cols := []*Column{
+		{Name: "id", Type: "INT"},
+		{Name: "name", Type: "VARCHAR"},
+	}
+	rowResults := make([]any, 0, len(cols))
+	for range cols {
+		var v sql.RawBytes
+		rowResults = append(rowResults, &v)
+	}
+	var out bytes.Buffer
+	err = toCsv(r, &out, "t", rowResults, cols, &csvConfig{enable: true, fieldDelimiter: ',', tsv: true})
+	require.NoError(t, err)
+	// the string \N is escaped, it does not load as NULL
+	require.Equal(t, "1\tsmith, john\n2\t\\N\n3\t\\\\N\n", out.String())
+}
+
+func TestLoadDataStmtTSV(t *testing.T) {
+	conf := &csvConfig{enable: true, tsv: true}
+	stmt := loadDataStmt("/tmp/db1_t1.tsv", "t1", false, conf, nil)
+	require.Equal(t, "LOAD DATA INFILE '/tmp/db1_t1.tsv' INTO TABLE `t1` FIELDS TERMINATED BY '\\t' ESCAPED BY '\\\\' LINES TERMINATED BY '\\n' PARALLEL 'FALSE';", stmt)
+	_, err := mysql.ParseOne(context.Background(), stmt, 1)
+	require.NoError(t, err)
+}
@@ -0,0 +1,41 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/matrixorigin/matrixone/pkg/common/moerr"
+)
+
+// isTerminal reports if f is a character device other than the null
+// device, i.e. the dump would flood a terminal
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	null, err := os.Stat(os.DevNull)
+	return err != nil || !os.SameFile(fi, null)
+}
+
+// checkStdout refuses to write the dump to a terminal unless forced
+func checkStdout(ctx context.Context, stdout *os.File, force bool) error {
+	if !force && isTerminal(stdout) {
+		return moerr.NewInvalidInput(ctx, "refuse to write the dump to a terminal, redirect the output to a file, e.g. mo-dump ... > dump.sql, or set -force-stdout")
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckStdout(t *testing.T) {
+	ctx := context.Background()
+
+	// a character device other than the null device stands for a terminal
+	tty, err := os.OpenFile("/dev/zero", os.O_WRONLY, 0)
+	if err != nil {
+		t.Skip("no character device to stand for a terminal")
+	}
+	defer tty.Close()
+	require.True(t, isTerminal(tty))
+	err = checkStdout(ctx, tty, false)
+	require.ErrorContains(t, err, "-force-stdout")
+	require.NoError(t, checkStdout(ctx, tty, true))
+
+	null, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	require.NoError(t, err)
+	defer null.Close()
+	require.False(t, isTerminal(null))
+	require.NoError(t, checkStdout(ctx, null, false))
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "dump.sql"))
+	require.NoError(t, err)
+	defer f.Close()
+	require.False(t, isTerminal(f))
+	require.NoError(t, checkStdout(ctx, f, false))
+
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer r.Close()
+	defer w.Close()
+	require.False(t, isTerminal(w))
+}
@@ -0,0 +1,91 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/matrixorigin/matrixone/pkg/common/moerr"
+)
+
+// commitTSColumn is the hidden column holding the commit timestamp of a
+// row. It is MatrixOne specific and only present if the server exposes
+// row level commit metadata.
+const commitTSColumn = "__mo_commit_ts"
+
+// txnRange is the [lo, hi) range of commit timestamps of the rows to dump.
+// Either bound may be empty.
+type txnRange struct {
+	lo string
+	hi string
+}
+
+// parseTxnRange parses lo:hi, both are physical timestamps as returned by
+// -capture-position without the logical part
+func parseTxnRange(ctx context.Context, spec string) (txnRange, error) {
+	lo, hi, ok := strings.Cut(spec, ":")
+	if !ok || (lo == "" && hi == "") {
+		return txnRange{}, moerr.NewInvalidInput(ctx, "txn-range must be in the format lo:hi, got %s", spec)
+	}
+	var bounds [2]uint64
+	for i, b := range []string{lo, hi} {
+		if b == "" {
+			continue
+		}
+		v, err := strconv.ParseUint(b, 10, 64)
+		if err != nil {
+			return txnRange{}, moerr.NewInvalidInput(ctx, "invalid txn-range bound %s", b)
+		}
+		bounds[i] = v
+	}
+	if lo != "" && hi != "" && bounds[0] >= bounds[1] {
+		return txnRange{}, moerr.NewInvalidInput(ctx, "txn-range lower bound must be less than the upper bound")
+	}
+	return txnRange{lo: lo, hi: hi}, nil
+}
+
+func (r *txnRange) enabled() bool {
+	return r.lo != "" || r.hi != ""
+}
+
+// predicate restricts the rows to the ones committed inside the range
+func (r *txnRange) predicate() string {
+	var conds []string
+	if r.lo != "" {
+		conds = append(conds, quoteIdent(commitTSColumn)+" >= "+r.lo)
+	}
+	if r.hi != "" {
+		conds = append(conds, quoteIdent(commitTSColumn)+" < "+r.hi)
+	}
+	return strings.Join(conds, " AND ")
+}
+
+// checkCommitTSColumn fails if the table does not expose the commit
+// timestamp of its rows
+func checkCommitTSColumn(ctx context.Context, db, tbl string) error {
+	var cnt int
+	what := "read the commit timestamp column of " + quoteIdent(db) + "." + quoteIdent(tbl)
+	err := connRetry.queryRow(ctx, what, "select count(*) from mo_catalog.mo_columns where att_database = '"+escapeString(db)+
+		"' and att_relname = '"+escapeString(tbl)+"' and attname = '"+commitTSColumn+"'", &cnt)
+	if err != nil {
+		return err
+	}
+	if cnt == 0 {
+		return moerr.NewNotSupported(ctx, "txn-range: table `%s`.`%s` does not expose the commit timestamp column %s, the server does not provide row level commit metadata", db, tbl, commitTSColumn)
+	}
+	return nil
+}
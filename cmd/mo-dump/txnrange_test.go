@@ -0,0 +1,67 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxnRangePredicate(t *testing.T) {
+	ctx := context.Background()
+	kases := []struct {
+		spec string
+		want string
+	}{
+		{"100:200", "`__mo_commit_ts` >= 100 AND `__mo_commit_ts` < 200"},
+		{"100:", "`__mo_commit_ts` >= 100"},
+		{":200", "`__mo_commit_ts` < 200"},
+	}
+	for _, k := range kases {
+		r, err := parseTxnRange(ctx, k.spec)
+		require.NoError(t, err)
+		require.True(t, r.enabled())
+		require.Equal(t, k.want, r.predicate())
+	}
+
+	for _, spec := range []string{"100", ":", "a:200", "100:-1", "200:100", "100:100", "1697328000000000000-1:"} {
+		_, err := parseTxnRange(ctx, spec)
+		require.Error(t, err, spec)
+	}
+	require.False(t, (&txnRange{}).enabled())
+}
+
+func TestSelectQueriesTxnRange(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	ctx := context.Background()
+	opt := Options{where: "a > 1", txnRange: txnRange{lo: "100", hi: "200"}}
+	mock.ExpectQuery("attname = '__mo_commit_ts'").WillReturnRows(sqlmock.NewRows([]string{"cnt"}).AddRow(1))
+	queries, err := opt.selectQueries(ctx, "db1", "t1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"select * from `db1`.`t1` where `__mo_commit_ts` >= 100 AND `__mo_commit_ts` < 200 AND (a > 1)"}, queries)
+
+	// the server does not expose the commit timestamps
+	mock.ExpectQuery("attname = '__mo_commit_ts'").WillReturnRows(sqlmock.NewRows([]string{"cnt"}).AddRow(0))
+	_, err = opt.selectQueries(ctx, "db1", "t1")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "does not expose the commit timestamp column")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -22,21 +22,98 @@ import (
 )
 
 const (
-	defaultUsername        = "dump"
-	defaultPassword        = "111"
-	defaultHost            = "127.0.0.1"
-	defaultPort            = 6001
-	defaultNetBufferLength = mpool.MB
-	minNetBufferLength     = mpool.KB * 16
-	maxNetBufferLength     = mpool.MB * 16
-	defaultCsv             = false
-	defaultLocalInfile     = true
-	defaultNoData          = false
-	timeout                = 10 * time.Second
+	defaultUsername             = "dump"
+	defaultPassword             = "111"
+	defaultPasswordStdin        = false
+	defaultHost                 = "127.0.0.1"
+	defaultPort                 = 6001
+	defaultNetBufferLength      = mpool.MB
+	minNetBufferLength          = mpool.KB * 16
+	maxNetBufferLength          = mpool.MB * 16
+	defaultCsv                  = false
+	defaultLocalInfile          = true
+	defaultCsvQuoteAll          = false
+	defaultNoData               = false
+	defaultSkipEmptyTables      = false
+	defaultSkipEmptyData        = false
+	defaultAddLocks             = false
+	defaultInsertBatchRows      = 0
+	defaultMaxRowSize           = 64 * mpool.MB
+	defaultParallel             = 1
+	defaultParallelSchemaFetch  = 1
+	defaultProgress             = false
+	defaultConsistencyFallback  = true
+	defaultCapturePosition      = false
+	defaultIgnoreErrors         = false
+	defaultRetryFailed          = 0
+	defaultRetries              = 0
+	defaultRetryInterval        = time.Second
+	defaultQueryTimeout         = time.Duration(0)
+	defaultFailOnLossy          = false
+	defaultSafeColumns          = false
+	defaultCompleteInsert       = false
+	defaultForceStdout          = false
+	defaultNormalizeDDL         = false
+	defaultSequences            = true
+	defaultNoSequences          = false
+	defaultRowChecksums         = false
+	defaultSkipMissingTables    = false
+	defaultAllowEmptyMatch      = false
+	defaultTruncate             = false
+	defaultSafeRestore          = false
+	defaultCompress             = false
+	defaultSplitSchemaData      = false
+	defaultSignFiles            = false
+	defaultIncludeTemporary     = false
+	defaultFullAccount          = false
+	defaultSingleTransaction    = false
+	defaultOrderByPrimaryKey    = false
+	defaultLockTables           = false
+	defaultNoLock               = false
+	defaultFKOrder              = false
+	defaultNoFKToggle           = false
+	defaultReportOnly           = false
+	defaultListKinds            = false
+	defaultProbeTypes           = false
+	defaultEstimateSize         = false
+	defaultSingleLineStatements = false
+	defaultRoutines             = false
+	defaultVerifyConn           = false
+	defaultMaterializeViews     = false
+	defaultFailOnEmpty          = false
+	defaultDumpStatistics       = false
+	defaultKeepAliveInterval    = 0
+	defaultPostFileConcurrency  = 4
+	defaultIgnoreHookErrors     = false
+	defaultCreateStampTable     = false
+	timeout                     = 10 * time.Second
 	//default Field delimiter (set to ',')
 	defaultFieldDelimiter rune = ','
+	// exitCodeEmpty is the exit code of -fail-on-empty
+	exitCodeEmpty = 2
+	// exitCodeTruncated is the exit code of a dump stopped by -deadline
+	exitCodeTruncated = 3
 )
 
+// authentication plugins supported by -auth-plugin
+const (
+	authNativePassword      = "mysql_native_password"
+	authCachingSha2Password = "caching_sha2_password"
+	authClearPassword       = "mysql_clear_password"
+)
+
+const (
+	formatSQL       = "sql"
+	formatMongoJSON = "mongo-json"
+	formatNDJSON    = "ndjson"
+	formatPrepared  = "prepared"
+	formatFramed    = "framed"
+	formatTSV       = "tsv"
+)
+
+// csvCompressGzip compresses the csv files with gzip
+const csvCompressGzip = "gzip"
+
 const (
 	quoteFmt   = "%q"
 	defaultFmt = "%s"
@@ -60,8 +137,27 @@ type Table struct {
 
 type Tables []Table
 
+// rowIterator is the part of *sql.Rows used to write the data of a table
+type rowIterator interface {
+	Next() bool
+	Scan(dest ...any) error
+	Err() error
+}
+
 // csvConfig is the configuration for csv output
 type csvConfig struct {
 	enable         bool
 	fieldDelimiter rune
+	// quoteAll encloses every field in double quotes
+	quoteAll bool
+	// tsv writes tab separated values with backslash escapes for -format tsv
+	tsv bool
+	// compress is the compression of the csv files, empty for none
+	compress string
+	// validateUTF8 is the mode of the utf8 check of string values
+	validateUTF8 string
+	// dir is the directory of the csv files, empty for the working directory
+	dir string
+	// chunk rolls the file of a table over to the next one, zero for one file
+	chunk csvChunk
 }
@@ -0,0 +1,78 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/matrixorigin/matrixone/pkg/common/moerr"
+)
+
+const (
+	// utf8Error fails the dump on a string value which is not valid utf8
+	utf8Error = "error"
+	// utf8Hex writes such a value as a hex literal with a warning
+	utf8Hex = "hex"
+)
+
+// checkValidateUTF8 checks the mode. Hex is only supported by INSERT
+// output: LOAD DATA would read a hex string in csv as the text itself.
+func checkValidateUTF8(ctx context.Context, mode string, csv bool) error {
+	switch mode {
+	case "", utf8Error:
+		return nil
+	case utf8Hex:
+		if csv {
+			return moerr.NewInvalidInput(ctx, "validate-utf8 hex does not support -csv and -format tsv")
+		}
+		return nil
+	default:
+		return moerr.NewInvalidInput(ctx, "unsupported validate-utf8 %s", mode)
+	}
+}
+
+// isStringType reports if values of the type are text in a character set
+func isStringType(typ string) bool {
+	switch strings.ToLower(typ) {
+	case "char", "varchar", "text", "tinytext", "mediumtext", "longtext":
+		return true
+	}
+	return false
+}
+
+// invalidUTF8 reports if the value of a string column is not valid utf8.
+// Nothing is checked if mode is empty.
+func invalidUTF8(mode string, v any, typ string) bool {
+	if mode == "" || !isStringType(typ) {
+		return false
+	}
+	ret := *(v.(*sql.RawBytes))
+	return ret != nil && !utf8.Valid(ret)
+}
+
+// reportInvalidUTF8 returns an error in mode error, otherwise warns that the
+// value is written hex encoded
+func reportInvalidUTF8(mode string, tbl string, col string, row int) error {
+	if mode == utf8Error {
+		return moerr.NewInvalidInputNoCtx("column `%s` of row %d of table `%s` is not valid utf8", col, row, tbl)
+	}
+	fmt.Fprintf(os.Stderr, "column `%s` of row %d of table `%s` is not valid utf8, write it hex encoded\n", col, row, tbl)
+	return nil
+}
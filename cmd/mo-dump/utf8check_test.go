@@ -0,0 +1,156 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/hex"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvalidUTF8(t *testing.T) {
+	require.NoError(t, checkValidateUTF8(context.Background(), "", true))
+	require.NoError(t, checkValidateUTF8(context.Background(), utf8Hex, false))
+	require.NoError(t, checkValidateUTF8(context.Background(), utf8Error, true))
+	require.Error(t, checkValidateUTF8(context.Background(), utf8Hex, true))
+	require.Error(t, checkValidateUTF8(context.Background(), "strict", false))
+
+	invalid := makeValue("a\xc3\x28")
+	require.True(t, invalidUTF8(utf8Error, invalid, "VARCHAR"))
+	require.False(t, invalidUTF8("", invalid, "VARCHAR"))
+	require.False(t, invalidUTF8(utf8Error, invalid, "BLOB"))
+	require.False(t, invalidUTF8(utf8Error, makeValue("中文"), "varchar"))
+	var null sql.RawBytes
+	require.False(t, invalidUTF8(utf8Error, &null, "varchar"))
+}
+
+func TestShowInsertValidateUTF8(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	bufPool := &sync.Pool{
+		New: func() any {
+			return &bytes.Buffer{}
+		},
+	}
+	cols := []*Column{{Name: "id", Type: "INT"}, {Name: "name", Type: "VARCHAR"}}
+	newRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "name"}).
+			AddRow("1", "ok").
+			AddRow("2", "bad\xc3\x28")
+	}
+
+	mock.ExpectQuery("select").WillReturnRows(newRows())
+	r, err := db.Query("select")
+	require.NoError(t, err)
+	args := []any{new(sql.RawBytes), new(sql.RawBytes)}
+	var out string
+	warn := captureStderr(t, func() {
+		out = captureStdout(t, func() {
+			err = showInsert(r, os.Stdout, args, cols, "t", bufPool, 1024, 0, 0, utf8Hex, false, "", -1)
+		})
+	})
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	require.Equal(t, "INSERT INTO `t` VALUES (1,'ok'),(2,x'626164c328');\n", out)
+	require.Equal(t, "column `name` of row 2 of table `t` is not valid utf8, write it hex encoded\n", warn)
+
+	mock.ExpectQuery("select").WillReturnRows(newRows())
+	r, err = db.Query("select")
+	require.NoError(t, err)
+	_ = captureStdout(t, func() {
+		err = showInsert(r, os.Stdout, args, cols, "t", bufPool, 1024, 0, 0, utf8Error, false, "", -1)
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "column `name` of row 2 of table `t` is not valid utf8")
+	require.NoError(t, r.Close())
+
+	mock.ExpectQuery("select").WillReturnRows(newRows())
+	r, err = db.Query("select")
+	require.NoError(t, err)
+	var csvOut bytes.Buffer
+	err = toCsv(r, &csvOut, "t", args, cols, &csvConfig{enable: true, fieldDelimiter: ',', validateUTF8: utf8Error})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "column `name` of row 2 of table `t` is not valid utf8")
+	require.NoError(t, r.Close())
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestToCsvValidateUTF8Load(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	want := [][]any{
+		{"1", "ok", []byte("bad\xc3\x28")},
+		{"2", "中文 \"q\"", []byte{0x00, 0xff}},
+		{"3", nil, nil},
+	}
+	rows := sqlmock.NewRows([]string{"id", "name", "b"})
+	for _, row := range want {
+		rows.AddRow(row[0], row[1], row[2])
+	}
+	mock.ExpectQuery("select").WillReturnRows(rows)
+	r, err := db.Query("select")
+	require.NoError(t, err)
+	defer r.Close()
+
+	cols := []*Column{{Name: "id", Type: "INT"}, {Name: "name", Type: "VARCHAR"}, {Name: "b", Type: "VARBINARY"}}
+	conf := &csvConfig{enable: true, fieldDelimiter: '\t', validateUTF8: utf8Error}
+	var out bytes.Buffer
+	require.NoError(t, toCsv(r, &out, "t", []any{new(sql.RawBytes), new(sql.RawBytes), new(sql.RawBytes)}, cols, conf))
+
+	// load the file the way the emitted statement tells LOAD DATA to: the
+	// columns read into a variable are set by the SET clause
+	stmt := loadDataStmt("/tmp/db1_t.csv", "t", false, conf, cols)
+	m := regexp.MustCompile(` \((.*)\) SET (.*) PARALLEL`).FindStringSubmatch(stmt)
+	require.NotNil(t, m, stmt)
+	targets := strings.Split(m[1], ",")
+	sets := map[string]bool{}
+	for _, set := range strings.Split(m[2], ",") {
+		col, expr, ok := strings.Cut(set, "=")
+		require.True(t, ok)
+		require.Equal(t, "unhex(@"+col+")", expr)
+		sets[col] = true
+	}
+	cr := csv.NewReader(&out)
+	cr.Comma = '\t'
+	records, err := cr.ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, len(want))
+	for i, record := range records {
+		for j, field := range record {
+			var got any = field
+			if field == "\\N" {
+				got = nil
+			} else if col := strings.TrimPrefix(targets[j], "@"); col != targets[j] {
+				require.True(t, sets[col], col)
+				got, err = hex.DecodeString(field)
+				require.NoError(t, err)
+			}
+			require.Equal(t, want[i][j], got, "row %d column %d", i, j)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/matrixorigin/matrixone/pkg/common/moerr"
+)
+
+// verifyConnection pings the connection before the dump of a database and
+// reconnects if the ping fails, so that a connection gone stale during a
+// long dump does not fail the database half way. The snapshot and the read
+// lock of the dump belong to the lost connection, so under those
+// consistencies the dump fails instead. It reports if it reconnected.
+func (opt *Options) verifyConnection(ctx context.Context, db string) (bool, error) {
+	pingCtx, cancel := context.WithTimeout(ctx, timeout)
+	err := conn.PingContext(pingCtx)
+	cancel()
+	if err == nil {
+		return false, nil
+	}
+	if opt.consistency == consistencySnapshot || opt.consistency == consistencyFlush {
+		return false, moerr.NewInternalError(ctx, "connection lost before database %s, can not reconnect with consistency %s: %v", db, opt.consistency, err)
+	}
+	fmt.Fprintf(os.Stderr, "connection lost before database %s, reconnecting: %v\n", db, err)
+	c, err := opt.openDBConnection(ctx, db)
+	if err != nil {
+		return false, err
+	}
+	conn.Close()
+	conn = c
+	return true, nil
+}
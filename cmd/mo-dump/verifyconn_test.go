@@ -0,0 +1,105 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyConnectionReconnect(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+	// the connection the dump gets after the first one drops
+	db2, mock2, err := sqlmock.NewWithDSN("dump:111@tcp(h1:6001)/db2")
+	require.NoError(t, err)
+	defer db2.Close()
+	var opened []string
+	sqlOpen = func(_ string, dsn string) (*sql.DB, error) {
+		opened = append(opened, dsn)
+		return sql.Open("sqlmock", dsn)
+	}
+	defer func() { sqlOpen = sql.Open }()
+
+	var buf bytes.Buffer
+	ctx := context.Background()
+	opt := Options{
+		username:        "dump",
+		password:        "111",
+		host:            "h1",
+		port:            6001,
+		dbs:             []string{"db1", "db2"},
+		tables:          Tables{{"t1", ""}},
+		netBufferLength: defaultNetBufferLength,
+		format:          formatSQL,
+		consistency:     consistencyNone,
+		verifyConn:      true,
+		out:             &buf,
+	}
+	mock.ExpectPing()
+	mock.ExpectQuery("select relname,relkind from mo_catalog.mo_tables where reldatabase = 'db1'").
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).AddRow("t1", "r"))
+	mock.ExpectQuery("show create table `db1`.`t1`").
+		WillReturnRows(sqlmock.NewRows([]string{"Table", "Create"}).AddRow("t1", "create table t1 (a int)"))
+	mock.ExpectQuery("select \\* from `db1`.`t1`").
+		WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("1"))
+	mock.ExpectPing().WillReturnError(errors.New("invalid connection"))
+	mock.ExpectClose()
+	mock2.ExpectQuery("select relname,relkind from mo_catalog.mo_tables where reldatabase = 'db2'").
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "relkind"}).AddRow("t1", "r"))
+	mock2.ExpectQuery("show create table `db2`.`t1`").
+		WillReturnRows(sqlmock.NewRows([]string{"Table", "Create"}).AddRow("t1", "create table t1 (a int)"))
+	mock2.ExpectQuery("select \\* from `db2`.`t1`").
+		WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("2"))
+
+	stderr := captureStderr(t, func() {
+		err = opt.dumpData(ctx)
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.NoError(t, mock2.ExpectationsWereMet())
+	require.Equal(t, []string{"dump:111@tcp(h1:6001)/db2"}, opened)
+	require.Contains(t, stderr, "connection lost before database db2")
+	require.Contains(t, buf.String(), "INSERT INTO `t1` VALUES (1);")
+	require.Contains(t, buf.String(), "INSERT INTO `t1` VALUES (2);")
+}
+
+func TestVerifyConnectionConsistency(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	ctx := context.Background()
+	opt := Options{consistency: consistencySnapshot}
+	mock.ExpectPing()
+	reconnected, err := opt.verifyConnection(ctx, "db1")
+	require.NoError(t, err)
+	require.False(t, reconnected)
+
+	// the snapshot is lost with the connection
+	mock.ExpectPing().WillReturnError(errors.New("invalid connection"))
+	_, err = opt.verifyConnection(ctx, "db1")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "consistency snapshot")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,151 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/matrixorigin/matrixone/pkg/common/moerr"
+)
+
+const datetimeLayout = "2006-01-02 15:04:05"
+
+var (
+	whereVarRegexp = regexp.MustCompile(`\$\{([^}]*)\}`)
+	// nowRegexp matches now, now-Nd and now-Nh
+	nowRegexp = regexp.MustCompile(`^now(?:-([0-9]+)([dh]))?$`)
+	// tableWhereRegexp matches the tbl: or db.tbl: prefix of a condition of
+	// one table, a :: cast is not a prefix
+	tableWhereRegexp = regexp.MustCompile(`^\s*([A-Za-z_][\w$]*(?:\.[A-Za-z_][\w$]*)?)\s*:([^:]|$)`)
+)
+
+// parseWhere splits the -where option into the conditions of the tables,
+// tbl:cond,db.tbl:cond, or returns it as the condition of every table if it
+// does not start with a table. The conditions of the tables are separated by
+// the commas which are followed by the next table, outside of quotes and
+// parentheses, so a condition may hold commas itself.
+func parseWhere(ctx context.Context, where string, now time.Time) (string, map[string]string, error) {
+	if !tableWhereRegexp.MatchString(where) {
+		expanded, err := expandWhere(ctx, where, now)
+		return expanded, nil, err
+	}
+	tables := make(map[string]string)
+	for _, item := range splitTableWhere(where) {
+		m := tableWhereRegexp.FindStringSubmatchIndex(item)
+		if m == nil {
+			return "", nil, moerr.NewInvalidInput(ctx, "where condition must be in the format tbl:cond, got %s", item)
+		}
+		tbl := item[m[2]:m[3]]
+		cond := strings.TrimSpace(item[m[4]:])
+		if cond == "" {
+			return "", nil, moerr.NewInvalidInput(ctx, "empty where condition of table %s", tbl)
+		}
+		if _, ok := tables[tbl]; ok {
+			return "", nil, moerr.NewInvalidInput(ctx, "duplicate where condition of table %s", tbl)
+		}
+		expanded, err := expandWhere(ctx, cond, now)
+		if err != nil {
+			return "", nil, err
+		}
+		tables[tbl] = expanded
+	}
+	return "", tables, nil
+}
+
+// splitTableWhere splits the conditions of the tables at the top level
+// commas followed by a table prefix
+func splitTableWhere(where string) []string {
+	var (
+		items []string
+		quote byte
+		depth int
+		start int
+	)
+	for i := 0; i < len(where); i++ {
+		c := where[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0 && tableWhereRegexp.MatchString(where[i+1:]):
+			items = append(items, where[start:i])
+			start = i + 1
+		}
+	}
+	return append(items, where[start:])
+}
+
+// whereCond returns the -where condition of the table, the one given for
+// db.tbl, then for tbl. A table without a condition is dumped in full.
+func (opt *Options) whereCond(db, tbl string) string {
+	if opt.tableWhere == nil {
+		return opt.where
+	}
+	if cond, ok := opt.tableWhere[db+"."+tbl]; ok {
+		return cond
+	}
+	return opt.tableWhere[tbl]
+}
+
+// expandWhere replaces the template variables in the where condition.
+// All variables are evaluated against the same now, so every table is
+// filtered with the same bounds.
+func expandWhere(ctx context.Context, where string, now time.Time) (string, error) {
+	var err error
+	expanded := whereVarRegexp.ReplaceAllStringFunc(where, func(v string) string {
+		if err != nil {
+			return v
+		}
+		var val string
+		val, err = whereVar(ctx, whereVarRegexp.FindStringSubmatch(v)[1], now)
+		return val
+	})
+	if err != nil {
+		return "", err
+	}
+	return expanded, nil
+}
+
+func whereVar(ctx context.Context, name string, now time.Time) (string, error) {
+	m := nowRegexp.FindStringSubmatch(name)
+	if m == nil {
+		return "", moerr.NewInvalidInput(ctx, "unknown variable ${%s} in where", name)
+	}
+	if m[1] != "" {
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			return "", moerr.NewInvalidInput(ctx, "invalid variable ${%s} in where", name)
+		}
+		unit := time.Hour
+		if m[2] == "d" {
+			unit = 24 * time.Hour
+		}
+		now = now.Add(-time.Duration(n) * unit)
+	}
+	return now.Format(datetimeLayout), nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandWhere(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2023, 3, 2, 10, 30, 0, 0, time.Local)
+	kases := []struct {
+		where string
+		want  string
+	}{
+		{"", ""},
+		{"a = 1", "a = 1"},
+		{"updated_at > '${now}'", "updated_at > '2023-03-02 10:30:00'"},
+		{"updated_at > '${now-1d}'", "updated_at > '2023-03-01 10:30:00'"},
+		{"updated_at > '${now-2d}'", "updated_at > '2023-02-28 10:30:00'"},
+		{"updated_at > '${now-12h}'", "updated_at > '2023-03-01 22:30:00'"},
+		{"ts >= '${now-1d}' and ts < '${now}'", "ts >= '2023-03-01 10:30:00' and ts < '2023-03-02 10:30:00'"},
+	}
+	for _, k := range kases {
+		got, err := expandWhere(ctx, k.where, now)
+		require.NoError(t, err)
+		require.Equal(t, k.want, got)
+	}
+
+	for _, where := range []string{"a > '${today}'", "a > '${now-1m}'", "a > '${now+1d}'", "a > '${}'"} {
+		_, err := expandWhere(ctx, where, now)
+		require.Error(t, err, where)
+	}
+}
+
+func TestParseWhere(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2023, 3, 2, 10, 30, 0, 0, time.Local)
+
+	// a bare condition is the condition of every table
+	for _, where := range []string{"a = 1", "a in (1,2) and b = 'x:y'", "ts > '10:00:00'", "a::int = 1"} {
+		global, tables, err := parseWhere(ctx, where, now)
+		require.NoError(t, err)
+		require.Equal(t, where, global)
+		require.Nil(t, tables)
+	}
+
+	global, tables, err := parseWhere(ctx, "orders:created_at>'${now-1d}',users:active=1, db2.users : id in (1,2) and name = 'a,b:c'", now)
+	require.NoError(t, err)
+	require.Empty(t, global)
+	require.Equal(t, map[string]string{
+		"orders":    "created_at>'2023-03-01 10:30:00'",
+		"users":     "active=1",
+		"db2.users": "id in (1,2) and name = 'a,b:c'",
+	}, tables)
+
+	for _, where := range []string{"t1:", "t1:a=1,t1:a=2", "t1:a > '${today}'"} {
+		_, _, err = parseWhere(ctx, where, now)
+		require.Error(t, err, where)
+	}
+
+	opt := Options{tableWhere: tables}
+	require.Equal(t, "active=1", opt.whereCond("db1", "users"))
+	require.Equal(t, "id in (1,2) and name = 'a,b:c'", opt.whereCond("db2", "users"))
+	require.Empty(t, opt.whereCond("db1", "items"))
+	opt = Options{where: "a = 1"}
+	require.Equal(t, "a = 1", opt.whereCond("db1", "items"))
+}
+
+func TestSelectQueriesTableWhere(t *testing.T) {
+	ctx := context.Background()
+	opt := Options{tableWhere: map[string]string{"orders": "created_at > '2024-01-01'", "db2.users": "active = 1"}}
+	queries, err := opt.selectQueries(ctx, "db1", "orders")
+	require.NoError(t, err)
+	require.Equal(t, []string{"select * from `db1`.`orders` where (created_at > '2024-01-01')"}, queries)
+	queries, err = opt.selectQueries(ctx, "db2", "users")
+	require.NoError(t, err)
+	require.Equal(t, []string{"select * from `db2`.`users` where (active = 1)"}, queries)
+	queries, err = opt.selectQueries(ctx, "db1", "users")
+	require.NoError(t, err)
+	require.Equal(t, []string{"select * from `db1`.`users`"}, queries)
+}
@@ -0,0 +1,98 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"strings"
+
+	"github.com/matrixorigin/matrixone/pkg/common/moerr"
+)
+
+// maxInListValues limits the values in one IN list
+const maxInListValues = 1000
+
+// whereIn selects the rows of table whose column is one of values
+type whereIn struct {
+	table  string
+	column string
+	values []string
+}
+
+// parseWhereIn parses tbl.col:/path/to/values.txt and reads the values,
+// one per line. Blank lines are ignored.
+func parseWhereIn(ctx context.Context, spec string) (*whereIn, error) {
+	target, path, ok := strings.Cut(spec, ":")
+	dot := strings.LastIndex(target, ".")
+	if !ok || dot <= 0 || dot == len(target)-1 || path == "" {
+		return nil, moerr.NewInvalidInput(ctx, "where-in must be in the format tbl.col:/path/to/values.txt, got %s", spec)
+	}
+	w := &whereIn{
+		table:  target[:dot],
+		column: target[dot+1:],
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		v := strings.TrimSpace(scanner.Text())
+		if v != "" {
+			w.values = append(w.values, v)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(w.values) == 0 {
+		return nil, moerr.NewInvalidInput(ctx, "where-in file %s has no values", path)
+	}
+	return w, nil
+}
+
+// predicates splits the values into IN predicates, each one holds at most
+// maxInListValues values and is no longer than maxLen unless a single value
+// exceeds it
+func (w *whereIn) predicates(maxLen int) []string {
+	prefix := quoteIdent(w.column) + " in ("
+	var (
+		preds []string
+		sb    strings.Builder
+		count int
+	)
+	for _, v := range w.values {
+		lit := "'" + escapeString(v) + "'"
+		if count > 0 && (count >= maxInListValues || sb.Len()+1+len(lit)+1 > maxLen) {
+			sb.WriteString(")")
+			preds = append(preds, sb.String())
+			sb.Reset()
+			count = 0
+		}
+		if count == 0 {
+			sb.WriteString(prefix)
+		} else {
+			sb.WriteString(",")
+		}
+		sb.WriteString(lit)
+		count++
+	}
+	sb.WriteString(")")
+	return append(preds, sb.String())
+}
@@ -0,0 +1,134 @@
+// Copyright 2023 Matrix Origin
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWhereIn(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ids.txt")
+	require.NoError(t, os.WriteFile(path, []byte("1\n 2 \n\n3\n"), 0644))
+
+	w, err := parseWhereIn(ctx, "orders.customer_id:"+path)
+	require.NoError(t, err)
+	require.Equal(t, "orders", w.table)
+	require.Equal(t, "customer_id", w.column)
+	require.Equal(t, []string{"1", "2", "3"}, w.values)
+
+	empty := filepath.Join(dir, "empty.txt")
+	require.NoError(t, os.WriteFile(empty, []byte("\n\n"), 0644))
+	for _, spec := range []string{
+		"orders:" + path,
+		"orders.:" + path,
+		".id:" + path,
+		"orders.id",
+		"orders.id:",
+		"orders.id:" + filepath.Join(dir, "missing.txt"),
+		"orders.id:" + empty,
+	} {
+		_, err = parseWhereIn(ctx, spec)
+		require.Error(t, err, spec)
+	}
+}
+
+func TestWhereInPredicates(t *testing.T) {
+	w := &whereIn{table: "t", column: "id", values: []string{"1", "2", "o'k"}}
+	require.Equal(t, []string{"`id` in ('1','2','o\\'k')"}, w.predicates(1024))
+
+	// chunked by length, each predicate fits in 20 bytes
+	w.values = []string{"1", "2", "3", "4", "5"}
+	preds := w.predicates(20)
+	require.Equal(t, []string{"`id` in ('1','2')", "`id` in ('3','4')", "`id` in ('5')"}, preds)
+
+	// chunked by count
+	w.values = w.values[:0]
+	for i := 0; i < 2*maxInListValues+1; i++ {
+		w.values = append(w.values, fmt.Sprint(i))
+	}
+	preds = w.predicates(defaultNetBufferLength)
+	require.Equal(t, 3, len(preds))
+	total := 0
+	for _, pred := range preds {
+		n := strings.Count(pred, ",") + 1
+		require.LessOrEqual(t, n, maxInListValues)
+		total += n
+	}
+	require.Equal(t, len(w.values), total)
+	require.True(t, strings.HasSuffix(preds[2], "('2000')"))
+
+	// a value longer than the limit gets a predicate of its own
+	w.values = []string{"1", strings.Repeat("x", 64), "2"}
+	preds = w.predicates(20)
+	require.Equal(t, 3, len(preds))
+}
+
+func TestSelectQueriesWhereIn(t *testing.T) {
+	ctx := context.Background()
+	opt := Options{
+		netBufferLength: 64,
+		where:           "a > 1",
+		whereIn:         &whereIn{table: "t1", column: "id", values: []string{"1", "2", "3", "4"}},
+	}
+	queries, err := opt.selectQueries(ctx, "db1", "t1")
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"select * from `db1`.`t1` where (a > 1) AND `id` in ('1','2','3')",
+		"select * from `db1`.`t1` where (a > 1) AND `id` in ('4')",
+	}, queries)
+
+	opt.netBufferLength = defaultNetBufferLength
+	queries, err = opt.selectQueries(ctx, "db1", "t1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"select * from `db1`.`t1` where (a > 1) AND `id` in ('1','2','3','4')"}, queries)
+
+	queries, err = opt.selectQueries(ctx, "db1", "t2")
+	require.NoError(t, err)
+	require.Equal(t, []string{"select * from `db1`.`t2` where (a > 1)"}, queries)
+}
+
+func TestGenOutputMultipleQueries(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+	conn = db
+
+	bufPool := &sync.Pool{
+		New: func() any {
+			return &bytes.Buffer{}
+		},
+	}
+	opt := Options{netBufferLength: defaultNetBufferLength, format: formatSQL}
+	mock.ExpectQuery("q1").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("1").AddRow("2"))
+	mock.ExpectQuery("q2").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("q3").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("3"))
+	out := captureStdout(t, func() {
+		err = opt.genOutput(context.Background(), []string{"q1", "q2", "q3"}, "db1", "t1", bufPool)
+	})
+	require.NoError(t, err)
+	require.Equal(t, "INSERT INTO `t1` VALUES (1),(2),(3);\n", out)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -4,12 +4,14 @@ go 1.20
 
 require (
 	github.com/DATA-DOG/go-sqlmock v1.5.0
-	github.com/go-sql-driver/mysql v1.7.1
+	github.com/cespare/xxhash/v2 v2.2.0
+	github.com/go-sql-driver/mysql v1.8.1
 	github.com/matrixorigin/matrixone v0.7.1-0.20230906044843-ce0185d3a794
 	github.com/stretchr/testify v1.8.4
 )
 
 require (
+	filippo.io/edwards25519 v1.1.0 // indirect
 	github.com/FastFilter/xorfilter v0.1.3 // indirect
 	github.com/RoaringBitmap/roaring v1.2.3 // indirect
 	github.com/alibabacloud-go/debug v0.0.0-20190504072949-9472017b5c68 // indirect
@@ -36,7 +38,6 @@ require (
 	github.com/aws/smithy-go v1.13.5 // indirect
 	github.com/axiomhq/hyperloglog v0.0.0-20230201085229-3ddf4bad03dc // indirect
 	github.com/bits-and-blooms/bitset v1.2.0 // indirect
-	github.com/cespare/xxhash/v2 v2.2.0 // indirect
 	github.com/cockroachdb/errors v1.9.1 // indirect
 	github.com/cockroachdb/redact v1.1.3 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect